@@ -3,13 +3,17 @@ package config
 import "os"
 
 type Config struct {
-	Address       string
-	JaegerAddress string
+	Address           string
+	JaegerAddress     string
+	RetentionInterval string
+	TrashPurgeDelay   string
 }
 
 func GetConfig() Config {
 	return Config{
-		Address:       os.Getenv("SERVICE_ADDRESS"),
-		JaegerAddress: os.Getenv("JAEGER_ADDRESS"),
+		Address:           os.Getenv("SERVICE_ADDRESS"),
+		JaegerAddress:     os.Getenv("JAEGER_ADDRESS"),
+		RetentionInterval: os.Getenv("RETENTION_INTERVAL"),
+		TrashPurgeDelay:   os.Getenv("TRASH_PURGE_DELAY"),
 	}
 }
@@ -3,13 +3,19 @@ package handlers
 import (
 	"ars_projekat/model"
 	"ars_projekat/services"
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"mime"
 	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 	"go.opentelemetry.io/otel/codes"
@@ -33,23 +39,194 @@ func NewConfigurationHandler(service services.ConfigurationService, tracer trace
 //
 // responses:
 //
+//	400: ErrorResponse
 //	404: ErrorResponse
+//	410: ErrorResponse
+//	422: ErrorResponse
 //	200: Configuration
 func (c ConfigurationHandler) Get(w http.ResponseWriter, r *http.Request) {
 	ctx, span := c.Tracer.Start(r.Context(), "ConfigurationHandler.Get")
 	defer span.End()
 
-	name := mux.Vars(r)["name"]
-	version := mux.Vars(r)["version"]
+	config, nested, ok := c.read(w, r, ctx)
+	if !ok {
+		return
+	}
 
-	config, err := c.Service.Get(name, version, ctx)
+	if nested {
+		view, err := model.NestConfiguration(*config)
+		if err != nil {
+			span.SetStatus(codes.Error, err.Error())
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+		renderRead(ctx, w, r, view)
+		span.SetStatus(codes.Ok, "")
+		return
+	}
+
+	renderRead(ctx, w, r, config)
+	span.SetStatus(codes.Ok, "")
+}
+
+// swagger:route GET /configs/{name}/{version}/fingerprint configuration getConfigurationFingerprint
+// Get only the fingerprint of a configuration read, it accepts the same query as the read
+//
+// responses:
+//
+//	400: ErrorResponse
+//	404: ErrorResponse
+//	410: ErrorResponse
+//	422: ErrorResponse
+//	200: Fingerprint
+func (c ConfigurationHandler) Fingerprint(w http.ResponseWriter, r *http.Request) {
+	ctx, span := c.Tracer.Start(r.Context(), "ConfigurationHandler.Fingerprint")
+	defer span.End()
+
+	config, _, ok := c.read(w, r, ctx)
+	if !ok {
+		return
+	}
+
+	renderRead(ctx, w, r, model.Fingerprint{Fingerprint: config.Fingerprint})
+	span.SetStatus(codes.Ok, "")
+}
+
+// read loads the configuration a GET asks for and fingerprints it, it answers the request itself when that fails.
+func (c ConfigurationHandler) read(w http.ResponseWriter, r *http.Request, ctx context.Context) (*model.Configuration, bool, bool) {
+	span := trace.SpanFromContext(ctx)
+
+	name := pathVar(r, "name")
+	version := pathVar(r, "version")
+
+	point, err := parseReadPoint(r)
 	if err != nil {
 		span.SetStatus(codes.Error, err.Error())
-		http.Error(w, err.Error(), http.StatusNotFound)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false, false
+	}
+	nested, err := parseView(r)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false, false
+	}
+	raw, err := parseRaw(r)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false, false
+	}
+
+	var config *model.Configuration
+	switch {
+	case point.revision > 0:
+		config, err = c.Service.GetRevision(name, version, point.revision, ctx)
+	case !point.asOf.IsZero():
+		config, err = c.Service.GetAsOf(name, version, point.asOf, ctx)
+	case raw:
+		config, err = c.Service.Get(name, version, ctx)
+	default:
+		config, err = c.Service.GetEffective(name, version, ctx)
+	}
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		http.Error(w, err.Error(), errorStatus(err))
+		return nil, false, false
+	}
+
+	config.Fingerprint = config.ComputeFingerprint()
+	return config, nested, true
+}
+
+// swagger:route GET /configs/{name}/{version}/revisions configuration getConfigurationHistory
+// List every revision written for a configuration version
+//
+// responses:
+//
+//	404: ErrorResponse
+//	200: []ConfigurationRevision
+func (c ConfigurationHandler) History(w http.ResponseWriter, r *http.Request) {
+	ctx, span := c.Tracer.Start(r.Context(), "ConfigurationHandler.History")
+	defer span.End()
+
+	name := pathVar(r, "name")
+	version := pathVar(r, "version")
+
+	revisions, err := c.Service.History(name, version, ctx)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		http.Error(w, err.Error(), errorStatus(err))
 		return
 	}
 
-	renderJSON(ctx, w, config, http.StatusOK)
+	renderJSON(ctx, w, revisions, http.StatusOK)
+	span.SetStatus(codes.Ok, "")
+}
+
+// swagger:route GET /configs/by-id/{id} configuration getConfigurationById
+// Get a configuration by the ID the server assigned to it
+//
+// responses:
+//
+//	400: ErrorResponse
+//	404: ErrorResponse
+//	410: ErrorResponse
+//	200: Configuration
+func (c ConfigurationHandler) GetByObjectId(w http.ResponseWriter, r *http.Request) {
+	ctx, span := c.Tracer.Start(r.Context(), "ConfigurationHandler.GetByObjectId")
+	defer span.End()
+
+	id, err := parseObjectId(r)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	config, err := c.Service.GetByObjectId(id, ctx)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	config.Fingerprint = config.ComputeFingerprint()
+	renderRead(ctx, w, r, config)
+	span.SetStatus(codes.Ok, "")
+}
+
+// swagger:route GET /configs configuration listConfigurations
+// List a page of configurations, filtered by name prefix and label selector
+//
+// responses:
+//
+//	400: ErrorResponse
+//	422: ErrorResponse
+//	200: ConfigurationPage
+func (c ConfigurationHandler) List(w http.ResponseWriter, r *http.Request) {
+	ctx, span := c.Tracer.Start(r.Context(), "ConfigurationHandler.List")
+	defer span.End()
+
+	opts, err := parseListOptions(r)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	opts.Delimiter = r.URL.Query().Get("delimiter")
+
+	page, err := c.Service.List(opts, ctx)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		writeError(ctx, w, err)
+		return
+	}
+	for i := range page.Items {
+		page.Items[i].Fingerprint = page.Items[i].ComputeFingerprint()
+	}
+
+	renderJSON(ctx, w, page, http.StatusOK)
 	span.SetStatus(codes.Ok, "")
 }
 
@@ -61,6 +238,7 @@ func (c ConfigurationHandler) Get(w http.ResponseWriter, r *http.Request) {
 //	415: ErrorResponse
 //	400: ErrorResponse
 //	409: ErrorResponse
+//	422: ValidationError
 //	201: Configuration
 func (c ConfigurationHandler) Upsert(w http.ResponseWriter, r *http.Request) {
 	ctx, span := c.Tracer.Start(r.Context(), "ConfigurationHandler.Upsert")
@@ -90,7 +268,7 @@ func (c ConfigurationHandler) Upsert(w http.ResponseWriter, r *http.Request) {
 
 	ver := model.ToString(cfg.Version)
 	check, err := c.Service.Get(cfg.Name, ver, ctx)
-	if err != nil && !strings.Contains(err.Error(), "not found") {
+	if err != nil && !strings.Contains(err.Error(), "not found") && !errors.Is(err, model.ErrGone) {
 		span.SetStatus(codes.Error, err.Error())
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -106,38 +284,41 @@ func (c ConfigurationHandler) Upsert(w http.ResponseWriter, r *http.Request) {
 	err = c.Service.Add(cfg, ctx)
 	if err != nil {
 		span.SetStatus(codes.Error, err.Error())
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(ctx, w, err)
 		return
 	}
+	cfg.Fingerprint = cfg.ComputeFingerprint()
 	renderJSON(ctx, w, cfg, http.StatusCreated)
 	span.SetStatus(codes.Ok, "")
 }
 
 // swagger:route DELETE /configs/{name}/{version} configuration deleteConfiguration
-// Delete a configuration by name and version
+// Move a configuration to the trash by name and version
 //
 // responses:
 //
 //	404: ErrorResponse
+//	409: ErrorResponse
+//	410: ErrorResponse
 //	204: NoContent
 func (c ConfigurationHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	ctx, span := c.Tracer.Start(r.Context(), "ConfigurationHandler.Delete")
 	defer span.End()
 
-	name := mux.Vars(r)["name"]
-	version := mux.Vars(r)["version"]
+	name := pathVar(r, "name")
+	version := pathVar(r, "version")
 
 	config, err := c.Service.Get(name, version, ctx)
 	if err != nil {
 		span.SetStatus(codes.Error, err.Error())
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		http.Error(w, err.Error(), errorStatus(err))
 		return
 	}
 
 	ok := c.Service.Delete(*config, ctx)
 	if ok != nil {
 		span.SetStatus(codes.Error, ok.Error())
-		http.Error(w, ok.Error(), http.StatusInternalServerError)
+		http.Error(w, ok.Error(), errorStatus(ok))
 		return
 	}
 
@@ -145,17 +326,229 @@ func (c ConfigurationHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	span.SetStatus(codes.Ok, "")
 }
 
-func decodeBody(r io.Reader) (*model.Configuration, error) {
-	dec := json.NewDecoder(r)
-	dec.DisallowUnknownFields()
+// swagger:route POST /configs/{name}/{version}/clone configuration cloneConfiguration
+// Copy a configuration to a new version, optionally applying a patch
+//
+// responses:
+//
+//	415: ErrorResponse
+//	400: ErrorResponse
+//	404: ErrorResponse
+//	409: ErrorResponse
+//	422: ValidationError
+//	201: Configuration
+func (c ConfigurationHandler) Clone(w http.ResponseWriter, r *http.Request) {
+	ctx, span := c.Tracer.Start(r.Context(), "ConfigurationHandler.Clone")
+	defer span.End()
+
+	name := pathVar(r, "name")
+	version := pathVar(r, "version")
+
+	contentType := r.Header.Get("Content-Type")
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if mediaType != "application/json" {
+		err := errors.New("expect application/json Content-Type")
+		span.SetStatus(codes.Error, err.Error())
+		http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+		return
+	}
+
+	req, err := decodeCloneBody(r.Body)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
+	clone, err := c.Service.Clone(name, version, *req, ctx)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		writeError(ctx, w, err)
+		return
+	}
+
+	clone.Fingerprint = clone.ComputeFingerprint()
+	renderJSON(ctx, w, clone, http.StatusCreated)
+	span.SetStatus(codes.Ok, "")
+}
+
+// swagger:route GET /configs/{name}/diff configuration diffConfiguration
+// Compare two versions of a configuration, format=text returns a unified text view
+//
+// responses:
+//
+//	400: ErrorResponse
+//	404: ErrorResponse
+//	200: ConfigurationDiff
+func (c ConfigurationHandler) Diff(w http.ResponseWriter, r *http.Request) {
+	ctx, span := c.Tracer.Start(r.Context(), "ConfigurationHandler.Diff")
+	defer span.End()
+
+	name := pathVar(r, "name")
+	from := r.URL.Query().Get("from")
+	to := r.URL.Query().Get("to")
+	if from == "" || to == "" {
+		err := errors.New("from and to query parameters are required")
+		span.SetStatus(codes.Error, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	diff, err := c.Service.Diff(name, from, to, ctx)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	if r.URL.Query().Get("format") == "text" {
+		renderText(ctx, w, diff.Unified(), http.StatusOK)
+	} else {
+		renderJSON(ctx, w, diff, http.StatusOK)
+	}
+	span.SetStatus(codes.Ok, "")
+}
+
+// swagger:route POST /configs/{name}/{version}/rollback configuration rollbackConfiguration
+// Restore a configuration to an earlier revision, preview=true only returns the diff that would be applied
+//
+// responses:
+//
+//	400: ErrorResponse
+//	404: ErrorResponse
+//	422: ErrorResponse
+//	200: Configuration
+func (c ConfigurationHandler) Rollback(w http.ResponseWriter, r *http.Request) {
+	ctx, span := c.Tracer.Start(r.Context(), "ConfigurationHandler.Rollback")
+	defer span.End()
+
+	name := pathVar(r, "name")
+	version := pathVar(r, "version")
+
+	number, preview, err := parseRollbackQuery(r)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if preview {
+		diff, err := c.Service.PreviewRollback(name, version, number, ctx)
+		if err != nil {
+			span.SetStatus(codes.Error, err.Error())
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+		if r.URL.Query().Get("format") == "text" {
+			renderText(ctx, w, diff.Unified(), http.StatusOK)
+		} else {
+			renderJSON(ctx, w, diff, http.StatusOK)
+		}
+		span.SetStatus(codes.Ok, "")
+		return
+	}
+
+	config, err := c.Service.Rollback(name, version, number, ctx)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	config.Fingerprint = config.ComputeFingerprint()
+	renderJSON(ctx, w, config, http.StatusOK)
+	span.SetStatus(codes.Ok, "")
+}
+
+func decodeBody(r io.Reader) (*model.Configuration, error) {
 	var configuration model.Configuration
-	if err := dec.Decode(&configuration); err != nil {
+	if err := decodeStrict(r, &configuration); err != nil {
 		return nil, err
 	}
 	return &configuration, nil
 }
 
+// decodeStrict decodes a request body into v and rejects fields v does not have. Configuration decodes itself, so
+// the decoder cannot reject unknown fields in it or in the members of a group, the body is checked against the
+// fields of v before it is decoded.
+func decodeStrict(r io.Reader, v interface{}) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if err = unknownField(data, reflect.TypeOf(v)); err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
+
+// unknownField fails on the first object key in data that the fields of t do not have. Maps are not walked, their
+// keys are data, and values of the wrong JSON type are left for the decoder to reject.
+func unknownField(data []byte, t reflect.Type) error {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		var items []json.RawMessage
+		if json.Unmarshal(data, &items) != nil {
+			return nil
+		}
+		for _, item := range items {
+			if err := unknownField(item, t.Elem()); err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		var object map[string]json.RawMessage
+		if json.Unmarshal(data, &object) != nil {
+			return nil
+		}
+		fields := jsonFields(t)
+		for key, value := range object {
+			field, ok := fields[strings.ToLower(key)]
+			if !ok {
+				return fmt.Errorf("json: unknown field %q", key)
+			}
+			if err := unknownField(value, field.Type); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// jsonFields returns the fields of the struct type t by their lower-cased JSON name, the decoder matches names
+// without regard to case. Fields of embedded structs are promoted.
+func jsonFields(t reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		switch {
+		case name == "-":
+		case field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct:
+			for k, f := range jsonFields(field.Type) {
+				fields[k] = f
+			}
+		case field.IsExported():
+			if name == "" {
+				name = field.Name
+			}
+			fields[strings.ToLower(name)] = field
+		}
+	}
+	return fields
+}
+
 func renderJSON(ctx context.Context, w http.ResponseWriter, v interface{}, statusCode int) {
 	marshal, err := json.Marshal(v)
 	if err != nil {
@@ -173,3 +566,186 @@ func renderJSON(ctx context.Context, w http.ResponseWriter, v interface{}, statu
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 	}
 }
+
+func decodeCloneBody(r io.Reader) (*model.CloneRequest, error) {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+
+	var req model.CloneRequest
+	if err := dec.Decode(&req); err != nil {
+		return nil, err
+	}
+	return &req, nil
+}
+
+// readPoint is the ?revision= or ?asOf= query of a historical read, the zero value means the current state.
+type readPoint struct {
+	revision int64
+	asOf     time.Time
+}
+
+func parseReadPoint(r *http.Request) (readPoint, error) {
+	var point readPoint
+	query := r.URL.Query()
+
+	if rev := query.Get("revision"); rev != "" {
+		number, err := strconv.ParseInt(rev, 10, 64)
+		if err != nil || number < 1 {
+			return point, errors.New("revision must be a positive integer")
+		}
+		point.revision = number
+	}
+	if asOf := query.Get("asOf"); asOf != "" {
+		if point.revision > 0 {
+			return point, errors.New("revision and asOf cannot be combined")
+		}
+		t, err := time.Parse(time.RFC3339, asOf)
+		if err != nil {
+			return point, errors.New("asOf must be an RFC3339 timestamp")
+		}
+		point.asOf = t
+	}
+
+	return point, nil
+}
+
+// pathVar returns an unescaped route variable, routes match the encoded path so names may contain an escaped slash.
+func pathVar(r *http.Request, key string) string {
+	value := mux.Vars(r)[key]
+	if unescaped, err := url.PathUnescape(value); err == nil {
+		return unescaped
+	}
+	return value
+}
+
+func parseObjectId(r *http.Request) (int64, error) {
+	id, err := strconv.ParseInt(pathVar(r, "id"), 10, 64)
+	if err != nil || id < 1 {
+		return 0, errors.New("id must be a positive integer")
+	}
+	return id, nil
+}
+
+// parseSelector reads the ?selector= label query of a listing or read, it returns nil when there is none.
+func parseSelector(r *http.Request) (model.Selector, error) {
+	selector := r.URL.Query().Get("selector")
+	if selector == "" {
+		return nil, nil
+	}
+	return model.ParseSelector(selector)
+}
+
+// parseListOptions reads the prefix, selector, sort, order, cursor and limit of a listing.
+func parseListOptions(r *http.Request) (model.ListOptions, error) {
+	query := r.URL.Query()
+	opts := model.ListOptions{
+		Prefix: query.Get("prefix"),
+		Sort:   query.Get("sort"),
+		Order:  query.Get("order"),
+		Cursor: query.Get("cursor"),
+		Limit:  model.DefaultPageLimit,
+	}
+	if opts.Sort == "" {
+		opts.Sort = model.SortByName
+	}
+	if opts.Order == "" {
+		opts.Order = model.OrderAsc
+	}
+	if limit := query.Get("limit"); limit != "" {
+		parsed, err := strconv.Atoi(limit)
+		if err != nil {
+			return opts, errors.New("limit must be an integer")
+		}
+		opts.Limit = parsed
+	}
+	selector, err := parseSelector(r)
+	opts.Selector = selector
+	return opts, err
+}
+
+// parseRaw reads the ?raw= query of a read, raw documents are returned without merging the configs they extend.
+func parseRaw(r *http.Request) (bool, error) {
+	raw := r.URL.Query().Get("raw")
+	if raw == "" {
+		return false, nil
+	}
+	parsed, err := strconv.ParseBool(raw)
+	if err != nil {
+		return false, errors.New("raw must be true or false")
+	}
+	return parsed, nil
+}
+
+// parseView reads the ?view= query of a read, it reports whether dotted parameter keys should be nested.
+func parseView(r *http.Request) (bool, error) {
+	switch r.URL.Query().Get("view") {
+	case "", "flat":
+		return false, nil
+	case "nested":
+		return true, nil
+	default:
+		return false, errors.New("view must be flat or nested")
+	}
+}
+
+func parseRollbackQuery(r *http.Request) (int64, bool, error) {
+	query := r.URL.Query()
+
+	number, err := strconv.ParseInt(query.Get("revision"), 10, 64)
+	if err != nil || number < 1 {
+		return 0, false, errors.New("revision query parameter must be a positive integer")
+	}
+
+	preview, err := parsePreview(r)
+	if err != nil {
+		return 0, false, err
+	}
+	return number, preview, nil
+}
+
+// parsePreview reads the ?preview= query of a write, a preview reports what the write would change without doing it.
+func parsePreview(r *http.Request) (bool, error) {
+	preview := r.URL.Query().Get("preview")
+	if preview == "" {
+		return false, nil
+	}
+	parsed, err := strconv.ParseBool(preview)
+	if err != nil {
+		return false, errors.New("preview must be true or false")
+	}
+	return parsed, nil
+}
+
+func renderText(ctx context.Context, w http.ResponseWriter, text string, statusCode int) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(statusCode)
+	if _, err := io.WriteString(w, text); err != nil {
+		span := trace.SpanFromContext(ctx)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
+
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, model.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, model.ErrAlreadyExists), errors.Is(err, model.ErrInUse), errors.Is(err, model.ErrDangling):
+		return http.StatusConflict
+	case errors.Is(err, model.ErrInvalid):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, model.ErrGone):
+		return http.StatusGone
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// writeError answers with the status matching err, schema violations are rendered as a JSON list.
+func writeError(ctx context.Context, w http.ResponseWriter, err error) {
+	var validation *model.ValidationError
+	if errors.As(err, &validation) {
+		renderJSON(ctx, w, validation, http.StatusUnprocessableEntity)
+		return
+	}
+	http.Error(w, err.Error(), errorStatus(err))
+}
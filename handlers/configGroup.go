@@ -3,14 +3,13 @@ package handlers
 import (
 	"ars_projekat/model"
 	"ars_projekat/services"
-	"encoding/json"
+	"context"
 	"errors"
 	"io"
 	"mime"
 	"net/http"
 	"strings"
 
-	"github.com/gorilla/mux"
 	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/trace"
 )
@@ -27,54 +26,352 @@ func NewConfigurationGroupHandler(groupService services.ConfigurationGroupServic
 	}
 }
 
-// swagger:route GET /config-groups/{name}/{version}/{labels} configurationgroup getConfigurationGroup
-// Get configuration group by name, version, and labels
+// swagger:route GET /groups/{name}/{version}/{labels} configurationgroup getConfigurationGroup
+// Get configuration group by name, version, and labels, includes=tree returns the included groups as a tree
 //
 // responses:
 //
+//	400: ErrorResponse
 //	404: ErrorResponse
+//	410: ErrorResponse
+//	422: ErrorResponse
 //	200: ConfigurationGroup
 func (cg ConfigurationGroupHandler) Get(w http.ResponseWriter, r *http.Request) {
 	ctx, span := cg.Tracer.Start(r.Context(), "ConfigurationGroupHandler.Get")
 	defer span.End()
 
-	name := mux.Vars(r)["name"]
-	version := mux.Vars(r)["version"]
-	labels := strings.Split(mux.Vars(r)["labels"], ";")
+	tree, err := parseIncludes(r)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if tree {
+		cg.getTree(w, r, ctx)
+		return
+	}
 
-	var labelString string
-	for i, v := range labels {
-		if i == len(labels)-1 {
-			labelString += v
-		} else {
-			labelString += v
-			labelString += ";"
+	cGroup, nested, ok := cg.read(w, r, ctx)
+	if !ok {
+		return
+	}
+
+	if nested {
+		view, err := model.NestGroup(*cGroup)
+		if err != nil {
+			span.SetStatus(codes.Error, err.Error())
+			http.Error(w, err.Error(), errorStatus(err))
+			return
 		}
+		renderRead(ctx, w, r, view)
+		span.SetStatus(codes.Ok, "")
+		return
 	}
-	versionModel, err := model.ToVersion(version)
+
+	renderRead(ctx, w, r, cGroup)
+	span.SetStatus(codes.Ok, "")
+}
+
+// getTree renders a group with the groups it includes as a tree.
+func (cg ConfigurationGroupHandler) getTree(w http.ResponseWriter, r *http.Request, ctx context.Context) {
+	span := trace.SpanFromContext(ctx)
+
+	versionModel, err := model.ToVersion(pathVar(r, "version"))
 	if err != nil {
 		span.SetStatus(codes.Error, err.Error())
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	cGroup, err := cg.GroupService.Get(name, *versionModel, labelString, ctx)
+	tree, err := cg.GroupService.GetTree(pathVar(r, "name"), *versionModel, ctx)
 	if err != nil {
 		span.SetStatus(codes.Error, err.Error())
-		http.Error(w, err.Error(), http.StatusNotFound)
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	renderJSON(ctx, w, tree, http.StatusOK)
+	span.SetStatus(codes.Ok, "")
+}
+
+// swagger:route GET /groups/{name}/{version}/resolve configurationgroup resolveConfigurationGroup
+// Merge the members of a configuration group that apply to a client context into one parameter map, each value
+// names the member that supplied it
+//
+// responses:
+//
+//	400: ErrorResponse
+//	404: ErrorResponse
+//	410: ErrorResponse
+//	422: ErrorResponse
+//	200: ResolvedGroup
+func (cg ConfigurationGroupHandler) Resolve(w http.ResponseWriter, r *http.Request) {
+	ctx, span := cg.Tracer.Start(r.Context(), "ConfigurationGroupHandler.Resolve")
+	defer span.End()
+
+	versionModel, err := model.ToVersion(pathVar(r, "version"))
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	req := parseResolveRequest(r)
+	if err = req.Validate(); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resolved, err := cg.GroupService.Resolve(pathVar(r, "name"), *versionModel, req, ctx)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		writeError(ctx, w, err)
+		return
+	}
+
+	renderJSON(ctx, w, resolved, http.StatusOK)
+	span.SetStatus(codes.Ok, "")
+}
+
+// swagger:route GET /groups/{name}/{version}/export configurationgroup exportConfigurationGroup
+// Render the parameter map a client context resolves from a configuration group as a dotenv, properties, YAML or
+// TOML file or as a Kubernetes ConfigMap or Secret manifest
+//
+// responses:
+//
+//	400: ErrorResponse
+//	404: ErrorResponse
+//	410: ErrorResponse
+//	422: ErrorResponse
+//	200: ExportFile
+func (cg ConfigurationGroupHandler) Export(w http.ResponseWriter, r *http.Request) {
+	ctx, span := cg.Tracer.Start(r.Context(), "ConfigurationGroupHandler.Export")
+	defer span.End()
+
+	versionModel, err := model.ToVersion(pathVar(r, "version"))
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	req := parseResolveRequest(r)
+	export := parseExportRequest(r)
+	if err = req.Validate(); err == nil {
+		err = export.Validate()
+	}
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	file, err := cg.GroupService.Export(pathVar(r, "name"), *versionModel, req, export, ctx)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		writeError(ctx, w, err)
 		return
 	}
 
+	w.Header().Set("Content-Type", file.ContentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": file.FileName}))
+	w.WriteHeader(http.StatusOK)
+	if _, err = w.Write(file.Body); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return
+	}
+	span.SetStatus(codes.Ok, "")
+}
+
+// swagger:route GET /groups/{name}/{version}/fingerprint configurationgroup getConfigurationGroupFingerprint
+// Get only the fingerprint of a configuration group read, it accepts the same query as the read
+//
+// responses:
+//
+//	400: ErrorResponse
+//	404: ErrorResponse
+//	410: ErrorResponse
+//	422: ErrorResponse
+//	200: Fingerprint
+func (cg ConfigurationGroupHandler) Fingerprint(w http.ResponseWriter, r *http.Request) {
+	ctx, span := cg.Tracer.Start(r.Context(), "ConfigurationGroupHandler.Fingerprint")
+	defer span.End()
+
+	cGroup, _, ok := cg.read(w, r, ctx)
+	if !ok {
+		return
+	}
+
+	renderRead(ctx, w, r, model.Fingerprint{Fingerprint: cGroup.Fingerprint})
+	span.SetStatus(codes.Ok, "")
+}
+
+// read loads the group a GET asks for and fingerprints it, it answers the request itself when that fails.
+func (cg ConfigurationGroupHandler) read(w http.ResponseWriter, r *http.Request, ctx context.Context) (*model.ConfigurationGroup, bool, bool) {
+	span := trace.SpanFromContext(ctx)
+
+	name := pathVar(r, "name")
+	version := pathVar(r, "version")
+	labelString := labelPath(r)
+	versionModel, err := model.ToVersion(version)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false, false
+	}
+
+	point, err := parseReadPoint(r)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false, false
+	}
+	nested, err := parseView(r)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false, false
+	}
+	raw, err := parseRaw(r)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false, false
+	}
+	selector, err := parseSelector(r)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false, false
+	}
+
+	var cGroup *model.ConfigurationGroup
+	switch {
+	case point.revision > 0:
+		cGroup, err = cg.GroupService.GetRevision(name, *versionModel, labelString, point.revision, ctx)
+	case !point.asOf.IsZero():
+		cGroup, err = cg.GroupService.GetAsOf(name, *versionModel, labelString, point.asOf, ctx)
+	case raw:
+		cGroup, err = cg.GroupService.Get(name, *versionModel, labelString, ctx)
+	default:
+		cGroup, err = cg.GroupService.GetEffective(name, *versionModel, labelString, ctx)
+	}
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		http.Error(w, err.Error(), errorStatus(err))
+		return nil, false, false
+	}
+
 	if cGroup == nil {
 		http.Error(w, "no content", http.StatusNoContent)
+		return nil, false, false
+	}
+	if selector != nil {
+		cGroup = selector.SelectMembers(cGroup)
+	}
+
+	cGroup.Fingerprint = cGroup.ComputeFingerprint()
+	return cGroup, nested, true
+}
+
+// swagger:route GET /groups configurationgroup listConfigurationGroups
+// List a page of configuration groups, filtered by name prefix and by the labels of their members
+//
+// responses:
+//
+//	400: ErrorResponse
+//	422: ErrorResponse
+//	200: ConfigurationGroupPage
+func (cg ConfigurationGroupHandler) List(w http.ResponseWriter, r *http.Request) {
+	ctx, span := cg.Tracer.Start(r.Context(), "ConfigurationGroupHandler.List")
+	defer span.End()
+
+	opts, err := parseListOptions(r)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	renderJSON(ctx, w, cGroup, http.StatusOK)
+	page, err := cg.GroupService.List(opts, ctx)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		writeError(ctx, w, err)
+		return
+	}
+	for i := range page.Items {
+		page.Items[i].Fingerprint = page.Items[i].ComputeFingerprint()
+	}
+
+	renderJSON(ctx, w, page, http.StatusOK)
 	span.SetStatus(codes.Ok, "")
 }
 
-// swagger:route POST /config-groups/{name}/{version} configurationgroup addConfigurationToGroup
+// swagger:route GET /groups/{name}/{version}/revisions configurationgroup getConfigurationGroupHistory
+// List every revision written for a configuration group version
+//
+// responses:
+//
+//	400: ErrorResponse
+//	404: ErrorResponse
+//	200: []GroupRevision
+func (cg ConfigurationGroupHandler) History(w http.ResponseWriter, r *http.Request) {
+	ctx, span := cg.Tracer.Start(r.Context(), "ConfigurationGroupHandler.History")
+	defer span.End()
+
+	name := pathVar(r, "name")
+	versionModel, err := model.ToVersion(pathVar(r, "version"))
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	revisions, err := cg.GroupService.History(name, *versionModel, ctx)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	renderJSON(ctx, w, revisions, http.StatusOK)
+	span.SetStatus(codes.Ok, "")
+}
+
+// swagger:route GET /groups/by-id/{id} configurationgroup getConfigurationGroupById
+// Get a configuration group by the ID the server assigned to it
+//
+// responses:
+//
+//	400: ErrorResponse
+//	404: ErrorResponse
+//	410: ErrorResponse
+//	200: ConfigurationGroup
+func (cg ConfigurationGroupHandler) GetByObjectId(w http.ResponseWriter, r *http.Request) {
+	ctx, span := cg.Tracer.Start(r.Context(), "ConfigurationGroupHandler.GetByObjectId")
+	defer span.End()
+
+	id, err := parseObjectId(r)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	group, err := cg.GroupService.GetByObjectId(id, ctx)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	group.Fingerprint = group.ComputeFingerprint()
+	renderRead(ctx, w, r, group)
+	span.SetStatus(codes.Ok, "")
+}
+
+// swagger:route PUT /groups/{name}/{version} configurationgroup addConfigurationToGroup
 // Add configuration to a configuration group
 //
 // responses:
@@ -82,13 +379,14 @@ func (cg ConfigurationGroupHandler) Get(w http.ResponseWriter, r *http.Request)
 //	415: ErrorResponse
 //	400: ErrorResponse
 //	409: ErrorResponse
+//	422: ValidationError
 //	201: ConfigurationGroup
 func (cg ConfigurationGroupHandler) AddConfig(w http.ResponseWriter, r *http.Request) {
 	ctx, span := cg.Tracer.Start(r.Context(), "ConfigurationGroupHandler.AddConfig")
 	defer span.End()
 
-	name := mux.Vars(r)["name"]
-	version := mux.Vars(r)["version"]
+	name := pathVar(r, "name")
+	version := pathVar(r, "version")
 	versionModel, err := model.ToVersion(version)
 	if err != nil {
 		span.SetStatus(codes.Error, err.Error())
@@ -132,7 +430,7 @@ func (cg ConfigurationGroupHandler) AddConfig(w http.ResponseWriter, r *http.Req
 	}
 
 	for _, v := range cGroup.Configurations {
-		if v.Name == config.Name && v.Version == config.Version {
+		if v.Name == config.MemberName() && v.Version == config.Version {
 			err := errors.New("config is already added")
 			span.SetStatus(codes.Error, err.Error())
 			http.Error(w, err.Error(), http.StatusConflict)
@@ -144,21 +442,23 @@ func (cg ConfigurationGroupHandler) AddConfig(w http.ResponseWriter, r *http.Req
 	err = cg.GroupService.Save(cGroup, ctx)
 	if err != nil {
 		span.SetStatus(codes.Error, err.Error())
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(ctx, w, err)
 		return
 	}
 
+	cGroup.Fingerprint = cGroup.ComputeFingerprint()
 	renderJSON(ctx, w, cGroup, http.StatusCreated)
 	span.SetStatus(codes.Ok, "")
 }
 
-// swagger:route POST /config-groups configurationgroup upsertConfigurationGroup
+// swagger:route POST /groups/ configurationgroup upsertConfigurationGroup
 // Add or update a configuration group
 //
 // responses:
 //
 //	415: ErrorResponse
 //	400: ErrorResponse
+//	422: ValidationError
 //	201: ConfigurationGroup
 func (cg ConfigurationGroupHandler) Upsert(w http.ResponseWriter, r *http.Request) {
 	ctx, span := cg.Tracer.Start(r.Context(), "ConfigurationGroupHandler.Upsert")
@@ -189,46 +489,53 @@ func (cg ConfigurationGroupHandler) Upsert(w http.ResponseWriter, r *http.Reques
 	err = cg.GroupService.Add(*cfgGroup, ctx)
 	if err != nil {
 		span.SetStatus(codes.Error, err.Error())
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(ctx, w, err)
 		return
 	}
 
+	cfgGroup.Fingerprint = cfgGroup.ComputeFingerprint()
 	renderJSON(ctx, w, cfgGroup, http.StatusCreated)
 	span.SetStatus(codes.Ok, "")
 }
 
-// swagger:route DELETE /config-groups/{name}/{version}/{labels} configurationgroup deleteConfigurationGroup
-// Delete a configuration group by name, version, and labels
+// swagger:route DELETE /groups/{name}/{version}/{labels} configurationgroup deleteConfigurationGroup
+// Move the members of a configuration group whose labels include the given labels to the trash, preview=true only
+// returns the members that would be moved
 //
 // responses:
 //
+//	400: ErrorResponse
 //	404: ErrorResponse
+//	409: ErrorResponse
+//	410: ErrorResponse
+//	200: ConfigurationGroup
 //	204: NoContent
 func (cg ConfigurationGroupHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	ctx, span := cg.Tracer.Start(r.Context(), "ConfigurationGroupHandler.Delete")
 	defer span.End()
 
-	name := mux.Vars(r)["name"]
-	version := mux.Vars(r)["version"]
-	labels := strings.Split(mux.Vars(r)["labels"], ";")
-
-	var labelString string
-	for i, v := range labels {
-		if i == len(labels)-1 {
-			labelString += v
-		} else {
-			labelString += v
-			labelString += ";"
-		}
-	}
+	name := pathVar(r, "name")
+	version := pathVar(r, "version")
+	labelString := labelPath(r)
 	versionModel, err := model.ToVersion(version)
 	if err != nil {
 		span.SetStatus(codes.Error, err.Error())
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	preview, err := parsePreview(r)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	check, err := cg.GroupService.Get(name, *versionModel, labelString, ctx)
+	if errors.Is(err, model.ErrGone) {
+		span.SetStatus(codes.Error, err.Error())
+		http.Error(w, err.Error(), http.StatusGone)
+		return
+	}
 	if check == nil {
 		err = errors.New("config not found")
 		span.SetStatus(codes.Error, err.Error())
@@ -240,11 +547,17 @@ func (cg ConfigurationGroupHandler) Delete(w http.ResponseWriter, r *http.Reques
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	if preview {
+		check.Fingerprint = check.ComputeFingerprint()
+		renderJSON(ctx, w, check, http.StatusOK)
+		span.SetStatus(codes.Ok, "")
+		return
+	}
 
 	ok := cg.GroupService.Delete(name, version, labelString, ctx)
 	if ok != nil {
 		span.SetStatus(codes.Error, ok.Error())
-		http.Error(w, ok.Error(), http.StatusInternalServerError)
+		http.Error(w, ok.Error(), errorStatus(ok))
 		return
 	}
 
@@ -252,14 +565,309 @@ func (cg ConfigurationGroupHandler) Delete(w http.ResponseWriter, r *http.Reques
 	span.SetStatus(codes.Ok, "")
 }
 
-func decodeGroupBody(r io.Reader) (*model.ConfigurationGroup, error) {
-	dec := json.NewDecoder(r)
-	dec.DisallowUnknownFields()
+// swagger:route PUT /groups/{name}/{version}/members/{configName} configurationgroup replaceGroupMember
+// Replace one member of a configuration group, labels qualify the member when several share its name
+//
+// responses:
+//
+//	415: ErrorResponse
+//	400: ErrorResponse
+//	404: ErrorResponse
+//	409: ErrorResponse
+//	422: ValidationError
+//	200: Configuration
+func (cg ConfigurationGroupHandler) ReplaceMember(w http.ResponseWriter, r *http.Request) {
+	ctx, span := cg.Tracer.Start(r.Context(), "ConfigurationGroupHandler.ReplaceMember")
+	defer span.End()
+
+	name := pathVar(r, "name")
+	versionModel, err := model.ToVersion(pathVar(r, "version"))
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	cType := r.Header.Get("Content-Type")
+	mediaType, _, err := mime.ParseMediaType(cType)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if mediaType != "application/json" {
+		err := errors.New("expect application/json Content-Type")
+		span.SetStatus(codes.Error, err.Error())
+		http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+		return
+	}
+
+	config, err := decodeBody(r.Body)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	member, err := cg.GroupService.ReplaceMember(name, *versionModel, pathVar(r, "configName"), memberLabels(r), *config, ctx)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		writeError(ctx, w, err)
+		return
+	}
+
+	member.Fingerprint = member.ComputeFingerprint()
+	renderJSON(ctx, w, member, http.StatusOK)
+	span.SetStatus(codes.Ok, "")
+}
+
+// swagger:route DELETE /groups/{name}/{version}/members/{configName} configurationgroup removeGroupMember
+// Move one member of a configuration group to the trash, labels qualify the member when several share its name
+//
+// responses:
+//
+//	400: ErrorResponse
+//	404: ErrorResponse
+//	422: ErrorResponse
+//	204: NoContent
+func (cg ConfigurationGroupHandler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	ctx, span := cg.Tracer.Start(r.Context(), "ConfigurationGroupHandler.RemoveMember")
+	defer span.End()
+
+	name := pathVar(r, "name")
+	versionModel, err := model.ToVersion(pathVar(r, "version"))
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err = cg.GroupService.RemoveMember(name, *versionModel, pathVar(r, "configName"), memberLabels(r), ctx); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		writeError(ctx, w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	span.SetStatus(codes.Ok, "")
+}
+
+// swagger:route POST /groups/{name}/{version}/clone configurationgroup cloneConfigurationGroup
+// Copy a configuration group with all of its members to a new version, optionally applying patches
+//
+// responses:
+//
+//	415: ErrorResponse
+//	400: ErrorResponse
+//	404: ErrorResponse
+//	409: ErrorResponse
+//	422: ValidationError
+//	201: ConfigurationGroup
+func (cg ConfigurationGroupHandler) Clone(w http.ResponseWriter, r *http.Request) {
+	ctx, span := cg.Tracer.Start(r.Context(), "ConfigurationGroupHandler.Clone")
+	defer span.End()
+
+	name := pathVar(r, "name")
+	version := pathVar(r, "version")
+	versionModel, err := model.ToVersion(version)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	cType := r.Header.Get("Content-Type")
+	mediaType, _, err := mime.ParseMediaType(cType)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if mediaType != "application/json" {
+		err := errors.New("expect application/json Content-Type")
+		span.SetStatus(codes.Error, err.Error())
+		http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+		return
+	}
+
+	req, err := decodeCloneBody(r.Body)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	clone, err := cg.GroupService.Clone(name, *versionModel, *req, ctx)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		writeError(ctx, w, err)
+		return
+	}
+
+	clone.Fingerprint = clone.ComputeFingerprint()
+	renderJSON(ctx, w, clone, http.StatusCreated)
+	span.SetStatus(codes.Ok, "")
+}
+
+// swagger:route GET /groups/{name}/diff configurationgroup diffConfigurationGroup
+// Compare two versions of a configuration group, format=text returns a unified text view
+//
+// responses:
+//
+//	400: ErrorResponse
+//	404: ErrorResponse
+//	200: GroupDiff
+func (cg ConfigurationGroupHandler) Diff(w http.ResponseWriter, r *http.Request) {
+	ctx, span := cg.Tracer.Start(r.Context(), "ConfigurationGroupHandler.Diff")
+	defer span.End()
 
+	name := pathVar(r, "name")
+	from, err := model.ToVersion(r.URL.Query().Get("from"))
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		http.Error(w, "from: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	to, err := model.ToVersion(r.URL.Query().Get("to"))
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		http.Error(w, "to: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	diff, err := cg.GroupService.Diff(name, *from, *to, ctx)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	if r.URL.Query().Get("format") == "text" {
+		renderText(ctx, w, diff.Unified(), http.StatusOK)
+	} else {
+		renderJSON(ctx, w, diff, http.StatusOK)
+	}
+	span.SetStatus(codes.Ok, "")
+}
+
+// swagger:route POST /groups/{name}/{version}/rollback configurationgroup rollbackConfigurationGroup
+// Atomically restore a configuration group to an earlier revision, preview=true only returns the diff that would be applied
+//
+// responses:
+//
+//	400: ErrorResponse
+//	404: ErrorResponse
+//	422: ErrorResponse
+//	200: ConfigurationGroup
+func (cg ConfigurationGroupHandler) Rollback(w http.ResponseWriter, r *http.Request) {
+	ctx, span := cg.Tracer.Start(r.Context(), "ConfigurationGroupHandler.Rollback")
+	defer span.End()
+
+	name := pathVar(r, "name")
+	versionModel, err := model.ToVersion(pathVar(r, "version"))
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	number, preview, err := parseRollbackQuery(r)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if preview {
+		diff, err := cg.GroupService.PreviewRollback(name, *versionModel, number, ctx)
+		if err != nil {
+			span.SetStatus(codes.Error, err.Error())
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+		if r.URL.Query().Get("format") == "text" {
+			renderText(ctx, w, diff.Unified(), http.StatusOK)
+		} else {
+			renderJSON(ctx, w, diff, http.StatusOK)
+		}
+		span.SetStatus(codes.Ok, "")
+		return
+	}
+
+	cGroup, err := cg.GroupService.Rollback(name, *versionModel, number, ctx)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	cGroup.Fingerprint = cGroup.ComputeFingerprint()
+	renderJSON(ctx, w, cGroup, http.StatusOK)
+	span.SetStatus(codes.Ok, "")
+}
+
+func decodeGroupBody(r io.Reader) (*model.ConfigurationGroup, error) {
 	var cg model.ConfigurationGroup
-	if err := dec.Decode(&cg); err != nil {
+	if err := decodeStrict(r, &cg); err != nil {
 		return nil, err
 	}
 
 	return &cg, nil
 }
+
+// labelPath reads the labels of a group route in their canonical encoding, so pairs may be given in any order.
+func labelPath(r *http.Request) string {
+	return model.SortLabels(model.ParseLabels(pathVar(r, "labels")))
+}
+
+// parseIncludes reads the ?includes= query of a group read, it reports whether included groups are returned as a
+// tree rather than flattened into the members.
+func parseIncludes(r *http.Request) (bool, error) {
+	switch r.URL.Query().Get("includes") {
+	case "", "flat":
+		return false, nil
+	case "tree":
+		return true, nil
+	default:
+		return false, errors.New("includes must be flat or tree")
+	}
+}
+
+// parseResolveRequest reads the client context of a resolve from the query, one parameter per context key, with the
+// order, the comma separated precedence and the member to resolve.
+func parseResolveRequest(r *http.Request) model.ResolveRequest {
+	query := r.URL.Query()
+	req := model.ResolveRequest{
+		Context: model.Labels{},
+		Order:   query.Get("order"),
+		Member:  query.Get("member"),
+	}
+	for _, key := range model.ContextKeys {
+		if value := query.Get(key); value != "" {
+			req.Context[key] = value
+		}
+	}
+	if precedence := query.Get("precedence"); precedence != "" {
+		req.Precedence = strings.Split(precedence, ",")
+	}
+	return req
+}
+
+// parseExportRequest reads the format, key style, key prefix and manifest metadata of an export from the query.
+func parseExportRequest(r *http.Request) model.ExportRequest {
+	query := r.URL.Query()
+	return model.ExportRequest{
+		Format:    query.Get("format"),
+		Keys:      model.KeyStyle(query.Get("keys")),
+		Prefix:    query.Get("prefix"),
+		Name:      query.Get("name"),
+		Namespace: query.Get("namespace"),
+	}
+}
+
+// memberLabels reads the ?labels= qualifier of a member route, in the same k:v;k:v form as label paths.
+func memberLabels(r *http.Request) string {
+	return model.SortLabels(model.ParseLabels(r.URL.Query().Get("labels")))
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// renderRead renders a configuration or group read with the hash of the rendered body as the ETag, so every view
+// and every field of it, metadata included, changes the tag. A client that sends the tag it holds in If-None-Match
+// gets 304 without a body, HEAD requests get the headers only.
+func renderRead(ctx context.Context, w http.ResponseWriter, r *http.Request, v interface{}) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		span := trace.SpanFromContext(ctx)
+		span.SetStatus(codes.Error, err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	sum := sha256.Sum256(body)
+	etag := hex.EncodeToString(sum[:])
+
+	w.Header().Set("ETag", strconv.Quote(etag))
+	if matchesETag(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if r.Method == http.MethodHead {
+		return
+	}
+	if _, err = w.Write(body); err != nil {
+		span := trace.SpanFromContext(ctx)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
+
+func matchesETag(header string, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || strings.Trim(tag, `"`) == etag {
+			return true
+		}
+	}
+	return false
+}
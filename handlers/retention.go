@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"ars_projekat/model"
+	"ars_projekat/services"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type RetentionHandler struct {
+	Tracer  trace.Tracer
+	Service services.RetentionService
+}
+
+func NewRetentionHandler(service services.RetentionService, tracer trace.Tracer) RetentionHandler {
+	return RetentionHandler{
+		Service: service,
+		Tracer:  tracer,
+	}
+}
+
+// swagger:route GET /retention/policies retention getRetentionPolicies
+// List retention policies
+//
+// responses:
+//
+//	200: []RetentionPolicy
+func (h RetentionHandler) GetPolicies(w http.ResponseWriter, r *http.Request) {
+	ctx, span := h.Tracer.Start(r.Context(), "RetentionHandler.GetPolicies")
+	defer span.End()
+
+	policies, err := h.Service.GetPolicies(ctx)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if policies == nil {
+		policies = []model.RetentionPolicy{}
+	}
+
+	renderJSON(ctx, w, policies, http.StatusOK)
+	span.SetStatus(codes.Ok, "")
+}
+
+// swagger:route GET /retention/policies/{name} retention getRetentionPolicy
+// Get a retention policy by name
+//
+// responses:
+//
+//	404: ErrorResponse
+//	200: RetentionPolicy
+func (h RetentionHandler) GetPolicy(w http.ResponseWriter, r *http.Request) {
+	ctx, span := h.Tracer.Start(r.Context(), "RetentionHandler.GetPolicy")
+	defer span.End()
+
+	policy, err := h.Service.GetPolicy(pathVar(r, "name"), ctx)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	renderJSON(ctx, w, policy, http.StatusOK)
+	span.SetStatus(codes.Ok, "")
+}
+
+// swagger:route POST /retention/policies retention upsertRetentionPolicy
+// Add or update a retention policy
+//
+// responses:
+//
+//	415: ErrorResponse
+//	400: ErrorResponse
+//	201: RetentionPolicy
+func (h RetentionHandler) UpsertPolicy(w http.ResponseWriter, r *http.Request) {
+	ctx, span := h.Tracer.Start(r.Context(), "RetentionHandler.UpsertPolicy")
+	defer span.End()
+
+	contentType := r.Header.Get("Content-Type")
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if mediaType != "application/json" {
+		err := errors.New("expect application/json Content-Type")
+		span.SetStatus(codes.Error, err.Error())
+		http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+		return
+	}
+
+	policy, err := decodePolicyBody(r.Body)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err = h.Service.AddPolicy(policy, ctx); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	renderJSON(ctx, w, policy, http.StatusCreated)
+	span.SetStatus(codes.Ok, "")
+}
+
+// swagger:route DELETE /retention/policies/{name} retention deleteRetentionPolicy
+// Delete a retention policy
+//
+// responses:
+//
+//	404: ErrorResponse
+//	204: NoContent
+func (h RetentionHandler) DeletePolicy(w http.ResponseWriter, r *http.Request) {
+	ctx, span := h.Tracer.Start(r.Context(), "RetentionHandler.DeletePolicy")
+	defer span.End()
+
+	name := pathVar(r, "name")
+	if _, err := h.Service.GetPolicy(name, ctx); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	if err := h.Service.DeletePolicy(name, ctx); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	span.SetStatus(codes.Ok, "")
+}
+
+// swagger:route GET /retention/dry-run retention retentionDryRun
+// Report which versions the retention policies would prune, without removing anything
+//
+// responses:
+//
+//	200: RetentionReport
+func (h RetentionHandler) DryRun(w http.ResponseWriter, r *http.Request) {
+	h.run(w, r, true)
+}
+
+// swagger:route POST /retention/run retention retentionRun
+// Run the retention policies now and prune matching versions
+//
+// responses:
+//
+//	200: RetentionReport
+func (h RetentionHandler) Run(w http.ResponseWriter, r *http.Request) {
+	h.run(w, r, false)
+}
+
+func (h RetentionHandler) run(w http.ResponseWriter, r *http.Request, dryRun bool) {
+	ctx, span := h.Tracer.Start(r.Context(), "RetentionHandler.Run")
+	defer span.End()
+
+	report, err := h.Service.Run(dryRun, ctx)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	renderJSON(ctx, w, report, http.StatusOK)
+	span.SetStatus(codes.Ok, "")
+}
+
+func decodePolicyBody(r io.Reader) (*model.RetentionPolicy, error) {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+
+	var policy model.RetentionPolicy
+	if err := dec.Decode(&policy); err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"ars_projekat/model"
+	"ars_projekat/services"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type SchemaHandler struct {
+	Tracer  trace.Tracer
+	Service services.SchemaService
+}
+
+func NewSchemaHandler(service services.SchemaService, tracer trace.Tracer) SchemaHandler {
+	return SchemaHandler{
+		Service: service,
+		Tracer:  tracer,
+	}
+}
+
+// swagger:route GET /schemas schema getSchemas
+// List the latest version of every schema
+//
+// responses:
+//
+//	200: []Schema
+func (h SchemaHandler) GetSchemas(w http.ResponseWriter, r *http.Request) {
+	ctx, span := h.Tracer.Start(r.Context(), "SchemaHandler.GetSchemas")
+	defer span.End()
+
+	schemas, err := h.Service.List(ctx)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if schemas == nil {
+		schemas = []model.Schema{}
+	}
+
+	renderJSON(ctx, w, schemas, http.StatusOK)
+	span.SetStatus(codes.Ok, "")
+}
+
+// swagger:route GET /schemas/{name} schema getSchema
+// Get the latest version of a schema
+//
+// responses:
+//
+//	404: ErrorResponse
+//	200: Schema
+func (h SchemaHandler) GetSchema(w http.ResponseWriter, r *http.Request) {
+	ctx, span := h.Tracer.Start(r.Context(), "SchemaHandler.GetSchema")
+	defer span.End()
+
+	schema, err := h.Service.Get(pathVar(r, "name"), 0, ctx)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	renderJSON(ctx, w, schema, http.StatusOK)
+	span.SetStatus(codes.Ok, "")
+}
+
+// swagger:route GET /schemas/{name}/versions schema getSchemaVersions
+// List every version of a schema
+//
+// responses:
+//
+//	404: ErrorResponse
+//	200: []Schema
+func (h SchemaHandler) GetVersions(w http.ResponseWriter, r *http.Request) {
+	ctx, span := h.Tracer.Start(r.Context(), "SchemaHandler.GetVersions")
+	defer span.End()
+
+	schemas, err := h.Service.Versions(pathVar(r, "name"), ctx)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	renderJSON(ctx, w, schemas, http.StatusOK)
+	span.SetStatus(codes.Ok, "")
+}
+
+// swagger:route GET /schemas/{name}/versions/{version} schema getSchemaVersion
+// Get a single version of a schema
+//
+// responses:
+//
+//	400: ErrorResponse
+//	404: ErrorResponse
+//	200: Schema
+func (h SchemaHandler) GetVersion(w http.ResponseWriter, r *http.Request) {
+	ctx, span := h.Tracer.Start(r.Context(), "SchemaHandler.GetVersion")
+	defer span.End()
+
+	version, err := strconv.ParseInt(pathVar(r, "version"), 10, 64)
+	if err != nil || version < 1 {
+		err = errors.New("schema version must be a positive integer")
+		span.SetStatus(codes.Error, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	schema, err := h.Service.Get(pathVar(r, "name"), version, ctx)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	renderJSON(ctx, w, schema, http.StatusOK)
+	span.SetStatus(codes.Ok, "")
+}
+
+// swagger:route POST /schemas schema registerSchema
+// Register a new version of a schema
+//
+// responses:
+//
+//	415: ErrorResponse
+//	400: ErrorResponse
+//	422: ErrorResponse
+//	201: Schema
+func (h SchemaHandler) Register(w http.ResponseWriter, r *http.Request) {
+	ctx, span := h.Tracer.Start(r.Context(), "SchemaHandler.Register")
+	defer span.End()
+
+	contentType := r.Header.Get("Content-Type")
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if mediaType != "application/json" {
+		err := errors.New("expect application/json Content-Type")
+		span.SetStatus(codes.Error, err.Error())
+		http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+		return
+	}
+
+	schema, err := decodeSchemaBody(r.Body)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err = h.Service.Register(schema, ctx); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	renderJSON(ctx, w, schema, http.StatusCreated)
+	span.SetStatus(codes.Ok, "")
+}
+
+// swagger:route DELETE /schemas/{name} schema deleteSchema
+// Delete every version of a schema
+//
+// responses:
+//
+//	404: ErrorResponse
+//	204: NoContent
+func (h SchemaHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	ctx, span := h.Tracer.Start(r.Context(), "SchemaHandler.Delete")
+	defer span.End()
+
+	if err := h.Service.Delete(pathVar(r, "name"), ctx); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	span.SetStatus(codes.Ok, "")
+}
+
+func decodeSchemaBody(r io.Reader) (*model.Schema, error) {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+
+	var schema model.Schema
+	if err := dec.Decode(&schema); err != nil {
+		return nil, err
+	}
+	return &schema, nil
+}
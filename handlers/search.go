@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"ars_projekat/model"
+	"ars_projekat/services"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const defaultSearchLimit = 20
+
+type SearchHandler struct {
+	Tracer  trace.Tracer
+	Service services.SearchService
+}
+
+func NewSearchHandler(service services.SearchService, tracer trace.Tracer) SearchHandler {
+	return SearchHandler{
+		Service: service,
+		Tracer:  tracer,
+	}
+}
+
+// swagger:route GET /search search search
+// Search configurations and configuration groups by name, parameter, label and metadata
+//
+// responses:
+//
+//	400: ErrorResponse
+//	422: ErrorResponse
+//	200: SearchResult
+func (h SearchHandler) Search(w http.ResponseWriter, r *http.Request) {
+	ctx, span := h.Tracer.Start(r.Context(), "SearchHandler.Search")
+	defer span.End()
+
+	params := r.URL.Query()
+	query := model.SearchQuery{
+		Text:  params.Get("q"),
+		Mode:  model.SearchMode(params.Get("mode")),
+		Kind:  params.Get("kind"),
+		Limit: defaultSearchLimit,
+	}
+	if query.Mode == "" {
+		query.Mode = model.SearchSubstring
+	}
+	if fields := params.Get("fields"); fields != "" {
+		query.Fields = strings.Split(fields, ",")
+	}
+	if limit := params.Get("limit"); limit != "" {
+		parsed, err := strconv.Atoi(limit)
+		if err != nil {
+			span.SetStatus(codes.Error, err.Error())
+			http.Error(w, "limit must be an integer", http.StatusBadRequest)
+			return
+		}
+		query.Limit = parsed
+	}
+
+	result, err := h.Service.Search(query, ctx)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		writeError(ctx, w, err)
+		return
+	}
+
+	renderJSON(ctx, w, result, http.StatusOK)
+	span.SetStatus(codes.Ok, "")
+}
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"ars_projekat/model"
+	"ars_projekat/services"
+	"net/http"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type TrashHandler struct {
+	Tracer        trace.Tracer
+	Service       services.TrashService
+	ConfigService services.ConfigurationService
+	GroupService  services.ConfigurationGroupService
+}
+
+func NewTrashHandler(service services.TrashService, configService services.ConfigurationService, groupService services.ConfigurationGroupService, tracer trace.Tracer) TrashHandler {
+	return TrashHandler{
+		Service:       service,
+		ConfigService: configService,
+		GroupService:  groupService,
+		Tracer:        tracer,
+	}
+}
+
+// swagger:route GET /trash trash getTrash
+// List trashed configurations and configuration groups
+//
+// responses:
+//
+//	200: Trash
+func (h TrashHandler) List(w http.ResponseWriter, r *http.Request) {
+	ctx, span := h.Tracer.Start(r.Context(), "TrashHandler.List")
+	defer span.End()
+
+	trash, err := h.Service.List(ctx)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	renderJSON(ctx, w, trash, http.StatusOK)
+	span.SetStatus(codes.Ok, "")
+}
+
+// swagger:route POST /trash/configs/{name}/{version}/restore trash restoreConfiguration
+// Restore a trashed configuration
+//
+// responses:
+//
+//	404: ErrorResponse
+//	409: ErrorResponse
+//	200: Configuration
+func (h TrashHandler) RestoreConfig(w http.ResponseWriter, r *http.Request) {
+	ctx, span := h.Tracer.Start(r.Context(), "TrashHandler.RestoreConfig")
+	defer span.End()
+
+	config, err := h.ConfigService.Restore(pathVar(r, "name"), pathVar(r, "version"), ctx)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	renderJSON(ctx, w, config, http.StatusOK)
+	span.SetStatus(codes.Ok, "")
+}
+
+// swagger:route DELETE /trash/configs/{name}/{version} trash purgeConfiguration
+// Permanently delete a trashed configuration
+//
+// responses:
+//
+//	404: ErrorResponse
+//	204: NoContent
+func (h TrashHandler) PurgeConfig(w http.ResponseWriter, r *http.Request) {
+	ctx, span := h.Tracer.Start(r.Context(), "TrashHandler.PurgeConfig")
+	defer span.End()
+
+	if err := h.Service.PurgeConfig(pathVar(r, "name"), pathVar(r, "version"), ctx); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	span.SetStatus(codes.Ok, "")
+}
+
+// swagger:route POST /trash/groups/{name}/{version}/restore trash restoreConfigurationGroup
+// Restore every trashed member of a configuration group version
+//
+// responses:
+//
+//	400: ErrorResponse
+//	404: ErrorResponse
+//	409: ErrorResponse
+//	200: ConfigurationGroup
+func (h TrashHandler) RestoreGroup(w http.ResponseWriter, r *http.Request) {
+	ctx, span := h.Tracer.Start(r.Context(), "TrashHandler.RestoreGroup")
+	defer span.End()
+
+	versionModel, err := model.ToVersion(pathVar(r, "version"))
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	group, err := h.GroupService.Restore(pathVar(r, "name"), *versionModel, ctx)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	renderJSON(ctx, w, group, http.StatusOK)
+	span.SetStatus(codes.Ok, "")
+}
+
+// swagger:route DELETE /trash/groups/{name}/{version} trash purgeConfigurationGroup
+// Permanently delete every trashed member of a configuration group version
+//
+// responses:
+//
+//	404: ErrorResponse
+//	204: NoContent
+func (h TrashHandler) PurgeGroup(w http.ResponseWriter, r *http.Request) {
+	ctx, span := h.Tracer.Start(r.Context(), "TrashHandler.PurgeGroup")
+	defer span.End()
+
+	if err := h.Service.PurgeGroup(pathVar(r, "name"), pathVar(r, "version"), ctx); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	span.SetStatus(codes.Ok, "")
+}
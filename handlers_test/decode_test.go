@@ -0,0 +1,51 @@
+package handlers_test
+
+import (
+	"ars_projekat/handlers"
+	"ars_projekat/repositories"
+	"ars_projekat/services"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+func post(router *mux.Router, target string, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestUpsert_RejectsUnknownFields(t *testing.T) {
+	mockRepo := new(repositories.MockConfigRepository)
+	tracer := sdktrace.NewTracerProvider().Tracer("test-")
+	configHandler := handlers.NewConfigurationHandler(services.NewConfigurationService(mockRepo, tracer), tracer)
+	groupHandler := handlers.NewConfigurationGroupHandler(services.NewConfigurationGroupService(mockRepo, tracer), tracer)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/configs/", configHandler.Upsert).Methods("POST")
+	router.HandleFunc("/groups/", groupHandler.Upsert).Methods("POST")
+
+	tests := map[string]struct {
+		target string
+		body   string
+		field  string
+	}{
+		"config":          {target: "/configs/", body: `{"name":"db","version":{"major":1},"parameters":{"port":"8080"},"paramters":{}}`, field: "paramters"},
+		"config metadata": {target: "/configs/", body: `{"name":"db","version":{"major":1},"descripton":"x"}`, field: "descripton"},
+		"group member":    {target: "/groups/", body: `{"name":"backend","version":{"major":1},"configurations":[{"name":"db","lables":{}}]}`, field: "lables"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			rec := post(router, tt.target, tt.body)
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			assert.Contains(t, rec.Body.String(), tt.field)
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package handlers_test
+
+import (
+	"ars_projekat/handlers"
+	"ars_projekat/model"
+	"ars_projekat/repositories"
+	"ars_projekat/services"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+func newConfigRouter(t *testing.T, config *model.Configuration) *mux.Router {
+	mockRepo := new(repositories.MockConfigRepository)
+	mockRepo.On("GetById", "testConfig", "1.0.0", mock.Anything).Return(config, nil)
+	t.Cleanup(func() { mockRepo.AssertExpectations(t) })
+
+	tracer := sdktrace.NewTracerProvider().Tracer("test-")
+	handler := handlers.NewConfigurationHandler(services.NewConfigurationService(mockRepo, tracer), tracer)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/configs/{name}/{version}", handler.Get).Methods("GET", "HEAD")
+	return router
+}
+
+func testConfig() *model.Configuration {
+	return &model.Configuration{
+		Name:       "testConfig",
+		Version:    model.Version{Major: 1, Minor: 0, Patch: 0},
+		Parameters: map[string]string{"db.port": "8080"},
+	}
+}
+
+func get(router *mux.Router, method string, target string, etag string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, nil)
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestConfigurationHandler_GetSendsETag(t *testing.T) {
+	config := testConfig()
+	router := newConfigRouter(t, config)
+
+	rec := get(router, http.MethodGet, "/configs/testConfig/1.0.0?raw=true", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotEmpty(t, rec.Header().Get("ETag"))
+	assert.Contains(t, rec.Body.String(), config.ComputeFingerprint())
+}
+
+func TestConfigurationHandler_GetNotModified(t *testing.T) {
+	router := newConfigRouter(t, testConfig())
+	etag := get(router, http.MethodGet, "/configs/testConfig/1.0.0?raw=true", "").Header().Get("ETag")
+
+	rec := get(router, http.MethodGet, "/configs/testConfig/1.0.0?raw=true", `"stale", W/`+etag)
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+	assert.Empty(t, rec.Body.String())
+}
+
+func TestConfigurationHandler_GetModified(t *testing.T) {
+	router := newConfigRouter(t, testConfig())
+
+	rec := get(router, http.MethodGet, "/configs/testConfig/1.0.0?raw=true", `"stale"`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotEmpty(t, rec.Body.String())
+}
+
+func TestConfigurationHandler_ETagFollowsRepresentation(t *testing.T) {
+	config := testConfig()
+	router := newConfigRouter(t, config)
+	etag := get(router, http.MethodGet, "/configs/testConfig/1.0.0?raw=true", "").Header().Get("ETag")
+
+	// Another view of the same content is another representation.
+	nested := get(router, http.MethodGet, "/configs/testConfig/1.0.0?raw=true&view=nested", etag)
+	assert.Equal(t, http.StatusOK, nested.Code)
+	assert.NotEqual(t, etag, nested.Header().Get("ETag"))
+
+	// A metadata edit keeps the fingerprint but changes the body and so the ETag.
+	fingerprint := config.ComputeFingerprint()
+	config.Description = "edited"
+	assert.Equal(t, fingerprint, config.ComputeFingerprint())
+	rec := get(router, http.MethodGet, "/configs/testConfig/1.0.0?raw=true", etag)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "edited")
+}
+
+func TestConfigurationHandler_Head(t *testing.T) {
+	router := newConfigRouter(t, testConfig())
+	etag := get(router, http.MethodGet, "/configs/testConfig/1.0.0?raw=true", "").Header().Get("ETag")
+
+	rec := get(router, http.MethodHead, "/configs/testConfig/1.0.0?raw=true", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, etag, rec.Header().Get("ETag"))
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.Empty(t, rec.Body.String())
+}
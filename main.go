@@ -48,6 +48,65 @@ func main() {
 		logger.Fatal(err)
 	}
 
+	escaped, err := store.RekeyEscapedNames(ctx)
+	if err != nil {
+		logger.Fatalf("failed to re-key escaped names: %v", err)
+	}
+	if escaped > 0 {
+		logger.Printf("moved %d keys to the escaped keys of their names", escaped)
+	}
+	moved, err := store.RekeyGroupMembers(ctx)
+	if err != nil {
+		logger.Fatalf("failed to re-key group members: %v", err)
+	}
+	if moved > 0 {
+		logger.Printf("re-keyed %d group members to canonical label keys", moved)
+	}
+	documented, err := store.BuildGroupDocuments(ctx)
+	if err != nil {
+		logger.Fatalf("failed to build group documents: %v", err)
+	}
+	if documented > 0 {
+		logger.Printf("listed the members of %d groups in their documents", documented)
+	}
+	indexed, err := store.BuildLabelIndex(ctx)
+	if err != nil {
+		logger.Fatalf("failed to build label index: %v", err)
+	}
+	if indexed > 0 {
+		logger.Printf("indexed %d labels of existing configs and group members", indexed)
+	}
+	sourced, err := store.BuildSourceIndex(ctx)
+	if err != nil {
+		logger.Fatalf("failed to build source index: %v", err)
+	}
+	if sourced > 0 {
+		logger.Printf("indexed %d source references of existing group members", sourced)
+	}
+	included, err := store.BuildIncludeIndex(ctx)
+	if err != nil {
+		logger.Fatalf("failed to build include index: %v", err)
+	}
+	if included > 0 {
+		logger.Printf("indexed %d includes of existing groups", included)
+	}
+	updated, err := store.BuildUpdateIndex(ctx)
+	if err != nil {
+		logger.Fatalf("failed to build update time index: %v", err)
+	}
+	if updated > 0 {
+		logger.Printf("indexed the update times of %d existing objects", updated)
+	}
+	// Objects without a watch stamp look deleted to the search index, so stamping runs after every migration that
+	// moves them.
+	stamped, err := store.BuildWatchIndex(ctx)
+	if err != nil {
+		logger.Fatalf("failed to build watch index: %v", err)
+	}
+	if stamped > 0 {
+		logger.Printf("stamped %d existing objects for watches", stamped)
+	}
+
 	configService := services.NewConfigurationService(store, tracer)
 	configHandler := handlers.NewConfigurationHandler(configService, tracer)
 
@@ -60,9 +119,46 @@ func main() {
 	metricsService := services.NewMetricsService()
 	metricsMiddleware := middleware.NewMetrics(metricsService)
 
+	retentionService := services.NewRetentionService(store, configService, configGroupService, metricsService, tracer)
+	retentionHandler := handlers.NewRetentionHandler(retentionService, tracer)
+
+	retentionInterval := time.Hour
+	if cfg.RetentionInterval != "" {
+		retentionInterval, err = time.ParseDuration(cfg.RetentionInterval)
+		if err != nil {
+			logger.Fatalf("invalid RETENTION_INTERVAL: %v", err)
+		}
+	}
+	pruneCtx, stopPruning := context.WithCancel(ctx)
+	defer stopPruning()
+	if retentionInterval > 0 {
+		retentionService.Start(retentionInterval, pruneCtx)
+	}
+
+	schemaService := services.NewSchemaService(store, tracer)
+	schemaHandler := handlers.NewSchemaHandler(schemaService, tracer)
+
+	trashPurgeDelay := 72 * time.Hour
+	if cfg.TrashPurgeDelay != "" {
+		trashPurgeDelay, err = time.ParseDuration(cfg.TrashPurgeDelay)
+		if err != nil || trashPurgeDelay <= 0 {
+			logger.Fatalf("invalid TRASH_PURGE_DELAY: %q", cfg.TrashPurgeDelay)
+		}
+	}
+	trashService := services.NewTrashService(store, trashPurgeDelay, tracer)
+	trashHandler := handlers.NewTrashHandler(trashService, configService, configGroupService, tracer)
+	trashService.Start(min(trashPurgeDelay, time.Hour), pruneCtx)
+
+	searchService := services.NewSearchService(store, tracer)
+	searchHandler := handlers.NewSearchHandler(searchService, tracer)
+	if err = searchService.Refresh(ctx); err != nil {
+		logger.Printf("failed to load search index, it fills in once the watches catch up: %v", err)
+	}
+	searchService.Start(pruneCtx)
+
 	limiter := middleware.NewRateLimiter(time.Second, 3)
 
-	router := mux.NewRouter()
+	router := mux.NewRouter().UseEncodedPath()
 	router.Use(otelmux.Middleware("ars_projekat"))
 
 	router.Use(func(next http.Handler) http.Handler {
@@ -74,17 +170,62 @@ func main() {
 	router.Use(func(next http.Handler) http.Handler {
 		return middleware.AdaptPrometheusHandler(next, metricsMiddleware)
 	})
+	router.Use(middleware.AdaptAuthorHandler)
 
 	// Config routes
-	router.HandleFunc("/configs/{name}/{version}", configHandler.Get).Methods("GET")
+	router.HandleFunc("/configs", configHandler.List).Methods("GET")
+	router.HandleFunc("/configs/by-id/{id}", configHandler.GetByObjectId).Methods("GET", "HEAD")
+	router.HandleFunc("/configs/{name}/diff", configHandler.Diff).Methods("GET")
+	router.HandleFunc("/configs/{name}/{version}", configHandler.Get).Methods("GET", "HEAD")
+	router.HandleFunc("/configs/{name}/{version}/fingerprint", configHandler.Fingerprint).Methods("GET", "HEAD")
+	router.HandleFunc("/configs/{name}/{version}/revisions", configHandler.History).Methods("GET")
 	router.HandleFunc("/configs/", configHandler.Upsert).Methods("POST")
 	router.HandleFunc("/configs/{name}/{version}", configHandler.Delete).Methods("DELETE")
+	router.HandleFunc("/configs/{name}/{version}/clone", configHandler.Clone).Methods("POST")
+	router.HandleFunc("/configs/{name}/{version}/rollback", configHandler.Rollback).Methods("POST")
 
 	// Config group routes
-	router.HandleFunc("/groups/{name}/{version}/{labels: ?.*}", configGroupHandler.Get).Methods("GET")
+	router.HandleFunc("/groups", configGroupHandler.List).Methods("GET")
+	router.HandleFunc("/groups/by-id/{id}", configGroupHandler.GetByObjectId).Methods("GET", "HEAD")
+	router.HandleFunc("/groups/{name}/diff", configGroupHandler.Diff).Methods("GET")
+	router.HandleFunc("/groups/{name}/{version}/revisions", configGroupHandler.History).Methods("GET")
+	router.HandleFunc("/groups/{name}/{version}/fingerprint", configGroupHandler.Fingerprint).Methods("GET", "HEAD")
+	router.HandleFunc("/groups/{name}/{version}/resolve", configGroupHandler.Resolve).Methods("GET")
+	router.HandleFunc("/groups/{name}/{version}/export", configGroupHandler.Export).Methods("GET")
+	router.HandleFunc("/groups/{name}/{version}/members/{configName}", configGroupHandler.ReplaceMember).Methods("PUT")
+	router.HandleFunc("/groups/{name}/{version}/members/{configName}", configGroupHandler.RemoveMember).Methods("DELETE")
+	router.HandleFunc("/groups/{name}/{version}/{labels: ?.*}", configGroupHandler.Get).Methods("GET", "HEAD")
 	router.HandleFunc("/groups/", configGroupHandler.Upsert).Methods("POST")
 	router.HandleFunc("/groups/{name}/{version}/{labels: ?.*}", configGroupHandler.Delete).Methods("DELETE")
 	router.HandleFunc("/groups/{name}/{version}", configGroupHandler.AddConfig).Methods("PUT")
+	router.HandleFunc("/groups/{name}/{version}/clone", configGroupHandler.Clone).Methods("POST")
+	router.HandleFunc("/groups/{name}/{version}/rollback", configGroupHandler.Rollback).Methods("POST")
+
+	// Retention routes
+	router.HandleFunc("/retention/policies", retentionHandler.GetPolicies).Methods("GET")
+	router.HandleFunc("/retention/policies", retentionHandler.UpsertPolicy).Methods("POST")
+	router.HandleFunc("/retention/policies/{name}", retentionHandler.GetPolicy).Methods("GET")
+	router.HandleFunc("/retention/policies/{name}", retentionHandler.DeletePolicy).Methods("DELETE")
+	router.HandleFunc("/retention/dry-run", retentionHandler.DryRun).Methods("GET")
+	router.HandleFunc("/retention/run", retentionHandler.Run).Methods("POST")
+
+	// Trash routes
+	router.HandleFunc("/trash", trashHandler.List).Methods("GET")
+	router.HandleFunc("/trash/configs/{name}/{version}/restore", trashHandler.RestoreConfig).Methods("POST")
+	router.HandleFunc("/trash/configs/{name}/{version}", trashHandler.PurgeConfig).Methods("DELETE")
+	router.HandleFunc("/trash/groups/{name}/{version}/restore", trashHandler.RestoreGroup).Methods("POST")
+	router.HandleFunc("/trash/groups/{name}/{version}", trashHandler.PurgeGroup).Methods("DELETE")
+
+	// Schema routes
+	router.HandleFunc("/schemas", schemaHandler.GetSchemas).Methods("GET")
+	router.HandleFunc("/schemas", schemaHandler.Register).Methods("POST")
+	router.HandleFunc("/schemas/{name}", schemaHandler.GetSchema).Methods("GET")
+	router.HandleFunc("/schemas/{name}", schemaHandler.Delete).Methods("DELETE")
+	router.HandleFunc("/schemas/{name}/versions", schemaHandler.GetVersions).Methods("GET")
+	router.HandleFunc("/schemas/{name}/versions/{version}", schemaHandler.GetVersion).Methods("GET")
+
+	// Search routes
+	router.HandleFunc("/search", searchHandler.Search).Methods("GET")
 
 	// Serve the swagger.yaml file
 	router.HandleFunc("/swagger.yaml", func(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,14 @@
+package middleware
+
+import (
+	"ars_projekat/services"
+	"net/http"
+)
+
+// AdaptAuthorHandler stores the X-Author header in the request context so revisions can record who made a change.
+func AdaptAuthorHandler(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := services.WithAuthor(r.Context(), r.Header.Get("X-Author"))
+		handler.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
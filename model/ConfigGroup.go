@@ -1,5 +1,7 @@
 package model
 
+import "sort"
+
 // ConfigurationGroup TODO implement version as struct
 
 // swagger:model ConfigurationGroup
@@ -8,6 +10,66 @@ type ConfigurationGroup struct {
 	Id             int64           `json:"id"`
 	Version        Version         `json:"version"`
 	Configurations []Configuration `json:"configurations"`
+	Includes       []GroupInclude  `json:"includes,omitempty"`
+	Fingerprint    string          `json:"fingerprint,omitempty"`
+	Metadata
+}
+
+// GroupDocument is the record stored for a group next to its members. It holds the group's ID, metadata, member
+// list and included groups, a group exists exactly when its document does and only the members it lists belong to
+// the group.
+type GroupDocument struct {
+	Name     string         `json:"name"`
+	Id       int64          `json:"id"`
+	Version  Version        `json:"version"`
+	Members  []GroupMember  `json:"members"`
+	Includes []GroupInclude `json:"includes,omitempty"`
+	Metadata
+}
+
+// GroupMember names a member in the group document, Labels is the canonical encoding of the member's labels.
+
+// swagger:model GroupMember
+type GroupMember struct {
+	Name   string `json:"name"`
+	Labels string `json:"labels,omitempty"`
+}
+
+func MemberOf(config Configuration) GroupMember {
+	return GroupMember{Name: config.Name, Labels: SortLabels(config.Labels)}
+}
+
+func (d *GroupDocument) HasMember(member GroupMember) bool {
+	for _, m := range d.Members {
+		if m == member {
+			return true
+		}
+	}
+	return false
+}
+
+// AddMember adds member unless the document lists it already, members stay sorted by name and labels.
+func (d *GroupDocument) AddMember(member GroupMember) {
+	if d.HasMember(member) {
+		return
+	}
+	d.Members = append(d.Members, member)
+	sort.Slice(d.Members, func(i, j int) bool {
+		if d.Members[i].Name != d.Members[j].Name {
+			return d.Members[i].Name < d.Members[j].Name
+		}
+		return d.Members[i].Labels < d.Members[j].Labels
+	})
+}
+
+func (d *GroupDocument) RemoveMember(member GroupMember) {
+	kept := d.Members[:0]
+	for _, m := range d.Members {
+		if m != member {
+			kept = append(kept, m)
+		}
+	}
+	d.Members = kept
 }
 
 func (cg *ConfigurationGroup) SetName(name string) {
@@ -0,0 +1,30 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ValidateConfigName checks a hierarchical configuration name such as team/payments/db, segments are separated by
+// a single slash and may not be empty, . or ..
+func ValidateConfigName(name string) error {
+	if name == "" {
+		return fmt.Errorf("config name is required: %w", ErrInvalid)
+	}
+	for _, segment := range strings.Split(name, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return fmt.Errorf("config name %q has an empty or relative segment: %w", name, ErrInvalid)
+		}
+	}
+	return nil
+}
+
+// FolderPrefix normalizes the prefix of a listing rolled up into folders, an empty prefix is the root and any other
+// prefix ends with a slash, so team lists the children of team/.
+func FolderPrefix(prefix string) string {
+	prefix = strings.Trim(prefix, "/")
+	if prefix == "" {
+		return ""
+	}
+	return prefix + "/"
+}
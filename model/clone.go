@@ -0,0 +1,60 @@
+package model
+
+// Patch describes parameter and label changes applied on top of an existing configuration.
+// Patched values keep the declared type of the parameter they replace.
+
+// swagger:model Patch
+type Patch struct {
+	Parameters       map[string]string `json:"parameters,omitempty"`
+	RemoveParameters []string          `json:"removeParameters,omitempty"`
+	Labels           map[string]string `json:"labels,omitempty"`
+	RemoveLabels     []string          `json:"removeLabels,omitempty"`
+}
+
+// Apply returns a copy of config with the patch applied, the original is left untouched.
+func (p Patch) Apply(config Configuration) Configuration {
+	params := make(map[string]string, len(config.Parameters))
+	for k, v := range config.Parameters {
+		params[k] = v
+	}
+	types := make(map[string]ParameterType, len(config.Types))
+	for k, t := range config.Types {
+		types[k] = t
+	}
+	for _, k := range p.RemoveParameters {
+		delete(params, k)
+		delete(types, k)
+	}
+	for k, v := range p.Parameters {
+		params[k] = v
+	}
+
+	labels := make(map[string]string, len(config.Labels))
+	for k, v := range config.Labels {
+		labels[k] = v
+	}
+	for _, k := range p.RemoveLabels {
+		delete(labels, k)
+	}
+	for k, v := range p.Labels {
+		labels[k] = v
+	}
+
+	config.SetParameters(params)
+	config.Types = nil
+	if len(types) > 0 {
+		config.Types = types
+	}
+	config.SetLabels(labels)
+	return config
+}
+
+// CloneRequest copies a configuration or group to Version. Patch is applied to the configuration,
+// or to every member of a group, Members holds additional patches for single group members by config name.
+
+// swagger:model CloneRequest
+type CloneRequest struct {
+	Version Version          `json:"version"`
+	Patch   *Patch           `json:"patch,omitempty"`
+	Members map[string]Patch `json:"members,omitempty"`
+}
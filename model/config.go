@@ -1,19 +1,25 @@
 package model
 
 import (
-	"fmt"
-	"sort"
+	"net/url"
+	"strings"
 )
 
 // TODO add version as struct, add labels as field (used for filtering)
 
 // swagger:model Configuration
 type Configuration struct {
-	Name       string            `json:"name"`
-	Id         int64             `json:"id"`
-	Version    Version           `json:"version"`
-	Parameters map[string]string `json:"parameters"`
-	Labels     map[string]string `json:"labels"`
+	Name        string                   `json:"name"`
+	Id          int64                    `json:"id"`
+	Version     Version                  `json:"version"`
+	Parameters  map[string]string        `json:"parameters"`
+	Types       map[string]ParameterType `json:"types,omitempty"`
+	Labels      Labels                   `json:"labels"`
+	Extends     *ConfigReference         `json:"extends,omitempty"`
+	Source      *ConfigReference         `json:"source,omitempty"`
+	Binding     MemberBinding            `json:"binding,omitempty"`
+	Fingerprint string                   `json:"fingerprint,omitempty"`
+	Metadata
 }
 
 /*
@@ -44,38 +50,30 @@ func (c *Configuration) SetLabels(labels map[string]string) {
 	c.Labels = labels
 }
 
+// SortLabels returns the canonical encoding of labels, see Labels.Encode.
 func SortLabels(labels map[string]string) string {
-	keys := make([]string, 0, len(labels))
+	return Labels(labels).Encode()
+}
 
-	for k := range labels {
-		keys = append(keys, k)
+// ParseLabels is the inverse of SortLabels, it reads labels from the k:v;k:v form used in group routes. Unescaped
+// input is read as is, valid labels contain neither : nor ;
+func ParseLabels(labels string) map[string]string {
+	parsed := make(map[string]string)
+	if labels == "" {
+		return parsed
 	}
-	sort.Strings(keys)
-
-	reorderedMap := make(map[string]string)
-
-	for _, k := range keys {
-		reorderedMap[k] = labels[k]
-	}
-
-	var slice []string
-	var counter int
-	for k, v := range reorderedMap {
-		if counter != len(reorderedMap)-1 {
-			counter++
-			slice = append(slice, fmt.Sprintf("%s:%s", k, v))
-			slice = append(slice, ";")
-		} else {
-			slice = append(slice, fmt.Sprintf("%s:%s", k, v))
-		}
+	for _, pair := range strings.Split(labels, ";") {
+		k, v, _ := strings.Cut(pair, ":")
+		parsed[unescapeLabel(k)] = unescapeLabel(v)
 	}
+	return parsed
+}
 
-	var result string
-	for _, v := range slice {
-		result += v
+func unescapeLabel(s string) string {
+	if unescaped, err := url.QueryUnescape(s); err == nil {
+		return unescaped
 	}
-
-	return result
+	return s
 }
 
 /* Ovo nisam hteo vise nista dodavati, msm da je dovoljno za pocetak, samo osnovan CRUD
@@ -0,0 +1,142 @@
+package model
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type versionBound struct {
+	op      string
+	version Version
+}
+
+// VersionConstraint selects versions of a configuration. It is a space separated list of conditions that must
+// all hold, each one an exact version (1.2.3 or =1.2.3), a comparison (>, >=, <, <=), a caret range (^1.2),
+// a tilde range (~1.2.3) or a wildcard (1.x, 1.2.*, *). Missing minor and patch numbers default to 0.
+type VersionConstraint struct {
+	raw    string
+	bounds []versionBound
+}
+
+func ParseVersionConstraint(constraint string) (VersionConstraint, error) {
+	c := VersionConstraint{raw: constraint}
+	fields := strings.Fields(constraint)
+	if len(fields) == 0 {
+		return c, fmt.Errorf("empty version constraint")
+	}
+
+	for _, f := range fields {
+		op := ""
+		for _, prefix := range []string{">=", "<=", ">", "<", "=", "^", "~"} {
+			if strings.HasPrefix(f, prefix) {
+				op = prefix
+				break
+			}
+		}
+		bounds, err := parseBound(op, strings.TrimPrefix(f, op))
+		if err != nil {
+			return c, fmt.Errorf("invalid version constraint %q: %w", constraint, err)
+		}
+		c.bounds = append(c.bounds, bounds...)
+	}
+	return c, nil
+}
+
+func parseBound(op string, version string) ([]versionBound, error) {
+	parts := strings.Split(version, ".")
+	if len(parts) > 3 {
+		return nil, fmt.Errorf("%q has too many components", version)
+	}
+
+	numbers := make([]int, 0, 3)
+	for _, p := range parts {
+		if p == "x" || p == "X" || p == "*" {
+			break
+		}
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("%q is not a version", version)
+		}
+		numbers = append(numbers, n)
+	}
+	wildcard := len(numbers) < len(parts)
+	if wildcard && op != "" && op != "=" {
+		return nil, fmt.Errorf("wildcards cannot be combined with %s", op)
+	}
+
+	lower := Version{}
+	for i, n := range numbers {
+		switch i {
+		case 0:
+			lower.Major = n
+		case 1:
+			lower.Minor = n
+		case 2:
+			lower.Patch = n
+		}
+	}
+
+	switch op {
+	case ">", ">=", "<", "<=":
+		return []versionBound{{op: op, version: lower}}, nil
+	case "^":
+		upper := Version{Major: lower.Major + 1}
+		if lower.Major == 0 && len(numbers) > 1 {
+			upper = Version{Minor: lower.Minor + 1}
+		}
+		return []versionBound{{op: ">=", version: lower}, {op: "<", version: upper}}, nil
+	case "~":
+		upper := Version{Major: lower.Major, Minor: lower.Minor + 1}
+		if len(numbers) == 1 {
+			upper = Version{Major: lower.Major + 1}
+		}
+		return []versionBound{{op: ">=", version: lower}, {op: "<", version: upper}}, nil
+	}
+
+	// Exact versions and wildcards, a partial version such as 1.2 is treated as 1.2.x.
+	switch len(numbers) {
+	case 3:
+		return []versionBound{{op: "=", version: lower}}, nil
+	case 2:
+		return []versionBound{{op: ">=", version: lower}, {op: "<", version: Version{Major: lower.Major, Minor: lower.Minor + 1}}}, nil
+	case 1:
+		return []versionBound{{op: ">=", version: lower}, {op: "<", version: Version{Major: lower.Major + 1}}}, nil
+	}
+	return nil, nil
+}
+
+func (c VersionConstraint) Matches(v Version) bool {
+	for _, b := range c.bounds {
+		cmp := CompareVersions(v, b.version)
+		var ok bool
+		switch b.op {
+		case "=":
+			ok = cmp == 0
+		case ">":
+			ok = cmp > 0
+		case ">=":
+			ok = cmp >= 0
+		case "<":
+			ok = cmp < 0
+		case "<=":
+			ok = cmp <= 0
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Exact returns the only version the constraint allows, when it pins a single version.
+func (c VersionConstraint) Exact() (Version, bool) {
+	if len(c.bounds) == 1 && c.bounds[0].op == "=" {
+		return c.bounds[0].version, true
+	}
+	return Version{}, false
+}
+
+func (c VersionConstraint) String() string {
+	return c.raw
+}
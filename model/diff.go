@@ -0,0 +1,259 @@
+package model
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type ValueChange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// swagger:model MapDiff
+type MapDiff struct {
+	Added   map[string]string      `json:"added,omitempty"`
+	Removed map[string]string      `json:"removed,omitempty"`
+	Changed map[string]ValueChange `json:"changed,omitempty"`
+}
+
+func DiffMaps(from map[string]string, to map[string]string) MapDiff {
+	diff := MapDiff{}
+	for k, v := range from {
+		newValue, ok := to[k]
+		if !ok {
+			if diff.Removed == nil {
+				diff.Removed = make(map[string]string)
+			}
+			diff.Removed[k] = v
+			continue
+		}
+		if newValue != v {
+			if diff.Changed == nil {
+				diff.Changed = make(map[string]ValueChange)
+			}
+			diff.Changed[k] = ValueChange{From: v, To: newValue}
+		}
+	}
+	for k, v := range to {
+		if _, ok := from[k]; !ok {
+			if diff.Added == nil {
+				diff.Added = make(map[string]string)
+			}
+			diff.Added[k] = v
+		}
+	}
+	return diff
+}
+
+func (d MapDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// swagger:model ConfigurationDiff
+type ConfigurationDiff struct {
+	Name       string  `json:"name"`
+	From       Version `json:"from"`
+	To         Version `json:"to"`
+	Parameters MapDiff `json:"parameters"`
+	Labels     MapDiff `json:"labels"`
+}
+
+func DiffConfigurations(from Configuration, to Configuration) ConfigurationDiff {
+	return ConfigurationDiff{
+		Name:       to.Name,
+		From:       from.Version,
+		To:         to.Version,
+		Parameters: DiffMaps(from.Parameters, to.Parameters),
+		Labels:     DiffMaps(from.Labels, to.Labels),
+	}
+}
+
+func (d ConfigurationDiff) Empty() bool {
+	return d.From == d.To && d.Parameters.Empty() && d.Labels.Empty()
+}
+
+func (d ConfigurationDiff) Unified() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s %s\n", d.Name, ToString(d.From))
+	fmt.Fprintf(&sb, "+++ %s %s\n", d.Name, ToString(d.To))
+	writeMapDiff(&sb, "parameters", d.Parameters)
+	writeMapDiff(&sb, "labels", d.Labels)
+	return sb.String()
+}
+
+// MemberDiff describes a group member present in both versions of a group whose contents changed.
+type MemberDiff struct {
+	Name       string  `json:"name"`
+	From       Version `json:"from"`
+	To         Version `json:"to"`
+	Parameters MapDiff `json:"parameters"`
+	Labels     MapDiff `json:"labels"`
+}
+
+// swagger:model GroupDiff
+type GroupDiff struct {
+	Name     string          `json:"name"`
+	From     Version         `json:"from"`
+	To       Version         `json:"to"`
+	Added    []Configuration `json:"added,omitempty"`
+	Removed  []Configuration `json:"removed,omitempty"`
+	Modified []MemberDiff    `json:"modified,omitempty"`
+}
+
+// DiffGroups matches members by config name and labels. Members whose labels changed are still
+// reported as modified when their config name is unique among the unmatched members of both versions.
+func DiffGroups(from ConfigurationGroup, to ConfigurationGroup) GroupDiff {
+	diff := GroupDiff{Name: to.Name, From: from.Version, To: to.Version}
+
+	memberKey := func(c Configuration) string {
+		return c.Name + "/" + labelKey(c.Labels)
+	}
+
+	remaining := make(map[string]Configuration)
+	for _, c := range from.Configurations {
+		remaining[memberKey(c)] = c
+	}
+
+	var unmatched []Configuration
+	for _, c := range to.Configurations {
+		old, ok := remaining[memberKey(c)]
+		if !ok {
+			unmatched = append(unmatched, c)
+			continue
+		}
+		delete(remaining, memberKey(c))
+		diff.addModified(old, c)
+	}
+
+	byName := make(map[string][]string)
+	for k, c := range remaining {
+		byName[c.Name] = append(byName[c.Name], k)
+	}
+	newByName := make(map[string]int)
+	for _, c := range unmatched {
+		newByName[c.Name]++
+	}
+
+	for _, c := range unmatched {
+		keys := byName[c.Name]
+		if len(keys) == 1 && newByName[c.Name] == 1 {
+			diff.addModified(remaining[keys[0]], c)
+			delete(remaining, keys[0])
+			continue
+		}
+		diff.Added = append(diff.Added, c)
+	}
+	for _, c := range remaining {
+		diff.Removed = append(diff.Removed, c)
+	}
+
+	sortMembers(diff.Added)
+	sortMembers(diff.Removed)
+	sort.Slice(diff.Modified, func(i, j int) bool {
+		return diff.Modified[i].Name < diff.Modified[j].Name
+	})
+	return diff
+}
+
+func (d *GroupDiff) addModified(from Configuration, to Configuration) {
+	member := MemberDiff{
+		Name:       to.Name,
+		From:       from.Version,
+		To:         to.Version,
+		Parameters: DiffMaps(from.Parameters, to.Parameters),
+		Labels:     DiffMaps(from.Labels, to.Labels),
+	}
+	if member.From == member.To && member.Parameters.Empty() && member.Labels.Empty() {
+		return
+	}
+	d.Modified = append(d.Modified, member)
+}
+
+func (d GroupDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Modified) == 0
+}
+
+func (d GroupDiff) Unified() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s %s\n", d.Name, ToString(d.From))
+	fmt.Fprintf(&sb, "+++ %s %s\n", d.Name, ToString(d.To))
+	for _, c := range d.Removed {
+		fmt.Fprintf(&sb, "@@ member %s %s [%s] @@\n", c.Name, ToString(c.Version), labelKey(c.Labels))
+		for _, k := range sortedKeys(c.Parameters) {
+			fmt.Fprintf(&sb, "-%s=%s\n", k, c.Parameters[k])
+		}
+	}
+	for _, c := range d.Added {
+		fmt.Fprintf(&sb, "@@ member %s %s [%s] @@\n", c.Name, ToString(c.Version), labelKey(c.Labels))
+		for _, k := range sortedKeys(c.Parameters) {
+			fmt.Fprintf(&sb, "+%s=%s\n", k, c.Parameters[k])
+		}
+	}
+	for _, m := range d.Modified {
+		fmt.Fprintf(&sb, "@@ member %s %s -> %s @@\n", m.Name, ToString(m.From), ToString(m.To))
+		writeMapDiff(&sb, "parameters", m.Parameters)
+		writeMapDiff(&sb, "labels", m.Labels)
+	}
+	return sb.String()
+}
+
+func writeMapDiff(sb *strings.Builder, section string, d MapDiff) {
+	if d.Empty() {
+		return
+	}
+	fmt.Fprintf(sb, "@@ %s @@\n", section)
+
+	keys := make([]string, 0, len(d.Added)+len(d.Removed)+len(d.Changed))
+	for k := range d.Added {
+		keys = append(keys, k)
+	}
+	for k := range d.Removed {
+		keys = append(keys, k)
+	}
+	for k := range d.Changed {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if v, ok := d.Removed[k]; ok {
+			fmt.Fprintf(sb, "-%s=%s\n", k, v)
+		}
+		if v, ok := d.Changed[k]; ok {
+			fmt.Fprintf(sb, "-%s=%s\n", k, v.From)
+			fmt.Fprintf(sb, "+%s=%s\n", k, v.To)
+		}
+		if v, ok := d.Added[k]; ok {
+			fmt.Fprintf(sb, "+%s=%s\n", k, v)
+		}
+	}
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortMembers(configs []Configuration) {
+	sort.Slice(configs, func(i, j int) bool {
+		if configs[i].Name != configs[j].Name {
+			return configs[i].Name < configs[j].Name
+		}
+		return labelKey(configs[i].Labels) < labelKey(configs[j].Labels)
+	})
+}
+
+// labelKey renders labels in a stable k:v;k:v form for matching and display.
+func labelKey(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for _, k := range sortedKeys(labels) {
+		pairs = append(pairs, k+":"+labels[k])
+	}
+	return strings.Join(pairs, ";")
+}
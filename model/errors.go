@@ -0,0 +1,12 @@
+package model
+
+import "errors"
+
+var (
+	ErrNotFound      = errors.New("not found")
+	ErrAlreadyExists = errors.New("already exists")
+	ErrInvalid       = errors.New("invalid request")
+	ErrGone          = errors.New("moved to trash")
+	ErrInUse         = errors.New("still in use")
+	ErrDangling      = errors.New("refers to a missing object")
+)
@@ -0,0 +1,545 @@
+package model
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	ExportDotenv     = "dotenv"
+	ExportProperties = "properties"
+	ExportYAML       = "yaml"
+	ExportTOML       = "toml"
+	ExportConfigMap  = "configmap"
+	ExportSecret     = "secret"
+)
+
+// ExportFormats lists the formats a group can be exported in.
+var ExportFormats = []string{ExportDotenv, ExportProperties, ExportYAML, ExportTOML, ExportConfigMap, ExportSecret}
+
+// KeyStyle renames parameter keys on export, KeysAsIs keeps them as they are stored.
+type KeyStyle string
+
+const (
+	KeysAsIs       KeyStyle = "as-is"
+	KeysUpperSnake KeyStyle = "upper_snake"
+	KeysLowerSnake KeyStyle = "lower_snake"
+)
+
+var (
+	envKeyPattern       = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	manifestKeyPattern  = regexp.MustCompile(`^[-._a-zA-Z0-9]+$`)
+	manifestNamePattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`)
+	namespacePattern    = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+	bareTOMLKeyPattern  = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+	bareDotenvPattern   = regexp.MustCompile(`^[A-Za-z0-9_./:@%+,-]*$`)
+)
+
+// Apply renames a key, the snake styles split it on every character that is not a letter or a digit and
+// between a lower case letter or digit and the upper case letter that follows it, so db.host and dbHost are both
+// DB_HOST in upper snake case.
+func (s KeyStyle) Apply(key string) string {
+	if s == KeysAsIs {
+		return key
+	}
+
+	var b strings.Builder
+	runes := []rune(key)
+	for i, r := range runes {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if i > 0 && unicode.IsUpper(r) && (unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1])) {
+				b.WriteRune('_')
+			}
+			if s == KeysUpperSnake {
+				b.WriteRune(unicode.ToUpper(r))
+			} else {
+				b.WriteRune(unicode.ToLower(r))
+			}
+		default:
+			b.WriteRune('_')
+		}
+	}
+	return b.String()
+}
+
+// ExportRequest is the file a resolved group is rendered as. Keys defaults to upper snake case for dotenv and to
+// the stored keys for the other formats, Prefix is put in front of every key before it is renamed. Name and
+// Namespace are the metadata of the Kubernetes manifests, the name defaults to the group name.
+type ExportRequest struct {
+	Format    string
+	Keys      KeyStyle
+	Prefix    string
+	Name      string
+	Namespace string
+}
+
+// Validate checks the format, key style and manifest metadata and fills in the key style default.
+func (r *ExportRequest) Validate() error {
+	if !contains(ExportFormats, r.Format) {
+		return fmt.Errorf("format must be one of %v: %w", ExportFormats, ErrInvalid)
+	}
+
+	switch r.Keys {
+	case "":
+		r.Keys = KeysAsIs
+		if r.Format == ExportDotenv {
+			r.Keys = KeysUpperSnake
+		}
+	case KeysAsIs, KeysUpperSnake, KeysLowerSnake:
+	default:
+		return fmt.Errorf("keys must be %s, %s or %s: %w", KeysAsIs, KeysUpperSnake, KeysLowerSnake, ErrInvalid)
+	}
+
+	if r.Name != "" && (len(r.Name) > 253 || !manifestNamePattern.MatchString(r.Name)) {
+		return fmt.Errorf("name %q is not a valid Kubernetes object name: %w", r.Name, ErrInvalid)
+	}
+	if r.Namespace != "" && (len(r.Namespace) > 63 || !namespacePattern.MatchString(r.Namespace)) {
+		return fmt.Errorf("namespace %q is not a valid Kubernetes namespace: %w", r.Namespace, ErrInvalid)
+	}
+	return nil
+}
+
+// ExportFile is a rendered export with the media type and file name it is served with.
+type ExportFile struct {
+	FileName    string
+	ContentType string
+	Body        []byte
+}
+
+// Export renders the parameters of a resolved group in the format of req. req must be validated.
+func Export(resolved *ResolvedGroup, req ExportRequest) (*ExportFile, error) {
+	params, err := exportKeys(resolved.Parameters, req)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	file := &ExportFile{FileName: manifestName(resolved.Name)}
+	switch req.Format {
+	case ExportDotenv:
+		file.FileName += ".env"
+		file.ContentType = "text/plain; charset=utf-8"
+		file.Body, err = renderDotenv(params, keys)
+	case ExportProperties:
+		file.FileName += ".properties"
+		file.ContentType = "text/x-java-properties; charset=utf-8"
+		file.Body = renderProperties(params, keys)
+	case ExportYAML:
+		file.FileName += ".yaml"
+		file.ContentType = "application/yaml"
+		file.Body, err = renderYAML(params, keys)
+	case ExportTOML:
+		file.FileName += ".toml"
+		file.ContentType = "application/toml"
+		file.Body, err = renderTOML(params, keys)
+	case ExportConfigMap, ExportSecret:
+		file.FileName += "-" + req.Format + ".yaml"
+		file.ContentType = "application/yaml"
+		file.Body, err = renderManifest(resolved, params, keys, req)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// exportKeys renames the resolved parameters, two keys that end up with the same name are an error.
+func exportKeys(parameters map[string]ResolvedParameter, req ExportRequest) (map[string]ResolvedParameter, error) {
+	renamed := make(map[string]ResolvedParameter, len(parameters))
+	origins := make(map[string]string, len(parameters))
+	var clashes []string
+	for k, p := range parameters {
+		key := req.Keys.Apply(req.Prefix + k)
+		if key == "" {
+			return nil, fmt.Errorf("parameter %q exports as an empty key: %w", k, ErrInvalid)
+		}
+		if origin, ok := origins[key]; ok {
+			first, second := origin, k
+			if second < first {
+				first, second = second, first
+			}
+			clashes = append(clashes, fmt.Sprintf("%s and %s both export as %s", first, second, key))
+		}
+		origins[key] = k
+		renamed[key] = p
+	}
+	if len(clashes) > 0 {
+		sort.Strings(clashes)
+		return nil, fmt.Errorf("keys clash, %s: %w", strings.Join(clashes, "; "), ErrInvalid)
+	}
+	return renamed, nil
+}
+
+// manifestName turns a group name into a Kubernetes object name, lower case with every other character a dash.
+func manifestName(group string) string {
+	name := strings.Map(func(r rune) rune {
+		r = unicode.ToLower(r)
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '.' || r == '-' {
+			return r
+		}
+		return '-'
+	}, group)
+	name = strings.Trim(name, "-.")
+	if len(name) > 253 {
+		name = strings.Trim(name[:253], "-.")
+	}
+	if name == "" {
+		return "group"
+	}
+	return name
+}
+
+func renderDotenv(params map[string]ResolvedParameter, keys []string) ([]byte, error) {
+	var buf bytes.Buffer
+	for _, k := range keys {
+		if !envKeyPattern.MatchString(k) {
+			return nil, fmt.Errorf("%q is not a valid environment variable name, export with keys=%s: %w", k, KeysUpperSnake, ErrInvalid)
+		}
+		buf.WriteString(k)
+		buf.WriteByte('=')
+		buf.WriteString(dotenvValue(params[k].Value))
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
+
+// dotenvValue leaves plain values bare and double quotes the rest, escaping what a shell would expand.
+func dotenvValue(value string) string {
+	if bareDotenvPattern.MatchString(value) {
+		return value
+	}
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "$", `\$`, "`", "\\`")
+	return `"` + replacer.Replace(value) + `"`
+}
+
+func renderProperties(params map[string]ResolvedParameter, keys []string) []byte {
+	var buf bytes.Buffer
+	for _, k := range keys {
+		buf.WriteString(propertiesEscape(k, true))
+		buf.WriteByte('=')
+		buf.WriteString(propertiesEscape(params[k].Value, false))
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes()
+}
+
+// propertiesEscape escapes a key or value the way java.util.Properties stores it, non ASCII characters are written
+// as unicode escapes so the file reads the same in ISO 8859-1 and UTF-8.
+func propertiesEscape(s string, key bool) string {
+	var b strings.Builder
+	for i, r := range s {
+		switch {
+		case r == ' ':
+			if key || i == 0 {
+				b.WriteByte('\\')
+			}
+			b.WriteRune(r)
+		case r == '\\' || r == '=' || r == ':' || r == '#' || r == '!':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r == '\f':
+			b.WriteString(`\f`)
+		case r < 0x20 || r > 0x7e:
+			if r > 0xffff {
+				high, low := utf16.EncodeRune(r)
+				fmt.Fprintf(&b, `\u%04X\u%04X`, high, low)
+			} else {
+				fmt.Fprintf(&b, `\u%04X`, r)
+			}
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// exportValue decodes a canonical value into the value of its type, values that are still placeholders stay strings.
+func (t ParameterType) exportValue(value string) interface{} {
+	switch t {
+	case ParamInt:
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return n
+		}
+	case ParamFloat:
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	case ParamBool:
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	case ParamList, ParamJSON:
+		dec := json.NewDecoder(strings.NewReader(value))
+		dec.UseNumber()
+		var v interface{}
+		if err := dec.Decode(&v); err == nil {
+			return exportNumbers(v)
+		}
+	}
+	return value
+}
+
+// exportNumbers turns the numbers of a decoded JSON value into ints where they are integral and floats otherwise.
+func exportNumbers(v interface{}) interface{} {
+	switch v := v.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		f, _ := v.Float64()
+		return f
+	case []interface{}:
+		for i := range v {
+			v[i] = exportNumbers(v[i])
+		}
+	case map[string]interface{}:
+		for k := range v {
+			v[k] = exportNumbers(v[k])
+		}
+	}
+	return v
+}
+
+// nestExport expands dotted keys into nested tables whose leaves are typed values.
+func nestExport(params map[string]ResolvedParameter, keys []string) (map[string]interface{}, error) {
+	if conflicts := parameterConflicts(append([]string(nil), keys...)); len(conflicts) > 0 {
+		return nil, fmt.Errorf("parameters cannot be nested, %s: %w", strings.Join(conflicts, "; "), ErrInvalid)
+	}
+
+	nested := make(map[string]interface{})
+	for _, k := range keys {
+		parts := strings.Split(k, ".")
+		node := nested
+		for _, part := range parts[:len(parts)-1] {
+			child, ok := node[part].(map[string]interface{})
+			if !ok {
+				child = make(map[string]interface{})
+				node[part] = child
+			}
+			node = child
+		}
+		node[parts[len(parts)-1]] = params[k].Type.exportValue(params[k].Value)
+	}
+	return nested, nil
+}
+
+func renderYAML(params map[string]ResolvedParameter, keys []string) ([]byte, error) {
+	nested, err := nestExport(params, keys)
+	if err != nil {
+		return nil, err
+	}
+	return marshalYAML(nested)
+}
+
+func marshalYAML(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func renderTOML(params map[string]ResolvedParameter, keys []string) ([]byte, error) {
+	nested, err := nestExport(params, keys)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err = writeTOMLTable(&buf, nested, nil); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeTOMLTable writes the values of a table before its sub tables, each sub table under its own header.
+func writeTOMLTable(buf *bytes.Buffer, table map[string]interface{}, path []string) error {
+	keys := make([]string, 0, len(table))
+	for k := range table {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var tables []string
+	for _, k := range keys {
+		if _, ok := table[k].(map[string]interface{}); ok {
+			tables = append(tables, k)
+			continue
+		}
+		value, err := tomlValue(table[k], append(path, k))
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(buf, "%s = %s\n", tomlKey(k), value)
+	}
+
+	for _, k := range tables {
+		sub := append(append([]string(nil), path...), k)
+		if buf.Len() > 0 {
+			buf.WriteByte('\n')
+		}
+		header := make([]string, len(sub))
+		for i, part := range sub {
+			header[i] = tomlKey(part)
+		}
+		fmt.Fprintf(buf, "[%s]\n", strings.Join(header, "."))
+		if err := writeTOMLTable(buf, table[k].(map[string]interface{}), sub); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func tomlKey(key string) string {
+	if bareTOMLKeyPattern.MatchString(key) {
+		return key
+	}
+	return tomlString(key)
+}
+
+// tomlValue renders a value inline, nested objects inside arrays become inline tables. TOML has no null.
+func tomlValue(v interface{}, path []string) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return tomlString(v), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case float64:
+		s := strconv.FormatFloat(v, 'g', -1, 64)
+		if !strings.ContainsAny(s, ".eIN") {
+			s += ".0"
+		}
+		return s, nil
+	case []interface{}:
+		items := make([]string, len(v))
+		for i, item := range v {
+			rendered, err := tomlValue(item, path)
+			if err != nil {
+				return "", err
+			}
+			items[i] = rendered
+		}
+		return "[" + strings.Join(items, ", ") + "]", nil
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		items := make([]string, len(keys))
+		for i, k := range keys {
+			rendered, err := tomlValue(v[k], append(path, k))
+			if err != nil {
+				return "", err
+			}
+			items[i] = tomlKey(k) + " = " + rendered
+		}
+		if len(items) == 0 {
+			return "{}", nil
+		}
+		return "{ " + strings.Join(items, ", ") + " }", nil
+	}
+	return "", fmt.Errorf("%s is null, TOML has no null values: %w", strings.Join(path, "."), ErrInvalid)
+}
+
+// tomlString writes a basic string, escaping quotes, backslashes and control characters.
+func tomlString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '\r':
+			b.WriteString(`\r`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&b, `\u%04X`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+type manifestMetadata struct {
+	Name      string `yaml:"name"`
+	Namespace string `yaml:"namespace,omitempty"`
+}
+
+type manifest struct {
+	APIVersion string            `yaml:"apiVersion"`
+	Kind       string            `yaml:"kind"`
+	Metadata   manifestMetadata  `yaml:"metadata"`
+	Type       string            `yaml:"type,omitempty"`
+	Data       map[string]string `yaml:"data"`
+}
+
+// renderManifest writes a ConfigMap with the values as they are stored, or an Opaque Secret with them base64
+// encoded.
+func renderManifest(resolved *ResolvedGroup, params map[string]ResolvedParameter, keys []string, req ExportRequest) ([]byte, error) {
+	m := manifest{
+		APIVersion: "v1",
+		Kind:       "ConfigMap",
+		Metadata:   manifestMetadata{Name: req.Name, Namespace: req.Namespace},
+		Data:       make(map[string]string, len(keys)),
+	}
+	if m.Metadata.Name == "" {
+		m.Metadata.Name = manifestName(resolved.Name)
+	}
+	if req.Format == ExportSecret {
+		m.Kind = "Secret"
+		m.Type = "Opaque"
+	}
+
+	for _, k := range keys {
+		if len(k) > 253 || !manifestKeyPattern.MatchString(k) {
+			return nil, fmt.Errorf("%q is not a valid %s key, export with keys=%s: %w", k, m.Kind, KeysLowerSnake, ErrInvalid)
+		}
+		if req.Format == ExportSecret {
+			m.Data[k] = base64.StdEncoding.EncodeToString([]byte(params[k].Value))
+		} else {
+			m.Data[k] = params[k].Value
+		}
+	}
+	return marshalYAML(m)
+}
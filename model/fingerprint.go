@@ -0,0 +1,73 @@
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+)
+
+// fingerprintContent is the canonical form a fingerprint is computed over. encoding/json writes map keys sorted,
+// so equal content always serializes to the same bytes.
+type fingerprintContent struct {
+	Name       string                   `json:"name,omitempty"`
+	Version    string                   `json:"version"`
+	Parameters map[string]string        `json:"parameters"`
+	Types      map[string]ParameterType `json:"types,omitempty"`
+	Labels     Labels                   `json:"labels"`
+	Source     *ConfigReference         `json:"source,omitempty"`
+	Members    []fingerprintContent     `json:"members,omitempty"`
+	Includes   []string                 `json:"includes,omitempty"`
+}
+
+func configContent(c Configuration) fingerprintContent {
+	return fingerprintContent{
+		Version:    ToString(c.Version),
+		Parameters: c.Parameters,
+		Types:      c.Types,
+		Labels:     c.Labels,
+		Source:     c.Source,
+	}
+}
+
+func fingerprint(content fingerprintContent) string {
+	data, _ := json.Marshal(content)
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// ComputeFingerprint hashes the version, parameters, labels and source of the configuration. Fingerprints are computed on
+// reads and never stored, metadata is left out so editing a description does not change them.
+func (c Configuration) ComputeFingerprint() string {
+	return fingerprint(configContent(c))
+}
+
+// ComputeFingerprint hashes the name and version of the group, the content of its members, independent of their
+// order, and the groups it includes in order.
+func (cg ConfigurationGroup) ComputeFingerprint() string {
+	content := fingerprintContent{Name: cg.Name, Version: ToString(cg.Version)}
+	for _, include := range cg.Includes {
+		content.Includes = append(content.Includes, include.String())
+	}
+	for _, c := range cg.Configurations {
+		member := configContent(c)
+		member.Name = c.Name
+		content.Members = append(content.Members, member)
+	}
+	sort.Slice(content.Members, func(i, j int) bool {
+		a, b := content.Members[i], content.Members[j]
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		if a.Version != b.Version {
+			return a.Version < b.Version
+		}
+		return a.Labels.Encode() < b.Labels.Encode()
+	})
+	return fingerprint(content)
+}
+
+// swagger:model Fingerprint
+type Fingerprint struct {
+	Fingerprint string `json:"fingerprint"`
+}
@@ -0,0 +1,70 @@
+package model
+
+import "fmt"
+
+// MaxIncludeDepth bounds how deep groups may include groups that include further groups.
+const MaxIncludeDepth = 8
+
+// GroupInclude names a group version whose members another group takes in.
+
+// swagger:model GroupInclude
+type GroupInclude struct {
+	Name    string  `json:"name"`
+	Version Version `json:"version"`
+}
+
+func (i GroupInclude) String() string {
+	return i.Name + "@" + ToString(i.Version)
+}
+
+// ValidateIncludes checks that every include names a group and that no group is included twice.
+func ValidateIncludes(includes []GroupInclude) error {
+	seen := make(map[GroupInclude]bool, len(includes))
+	for _, include := range includes {
+		if include.Name == "" {
+			return fmt.Errorf("includes need the name of a group: %w", ErrInvalid)
+		}
+		if seen[include] {
+			return fmt.Errorf("group %s is included twice: %w", include, ErrInvalid)
+		}
+		seen[include] = true
+	}
+	return nil
+}
+
+// GroupTree is a group with the groups it includes, each holding only its own members. It is the
+// ?includes=tree form of a group read.
+
+// swagger:model GroupTree
+type GroupTree struct {
+	Name           string          `json:"name"`
+	Version        Version         `json:"version"`
+	Configurations []Configuration `json:"configurations"`
+	Includes       []GroupTree     `json:"includes,omitempty"`
+}
+
+// Flatten returns the members of the tree. Later includes override earlier ones and the group's own members override
+// every include, a member is overridden by one with the same name and labels and keeps its first position.
+func (t GroupTree) Flatten() []Configuration {
+	var members []Configuration
+	index := make(map[GroupMember]int)
+	add := func(configs []Configuration) {
+		for _, c := range configs {
+			member := MemberOf(c)
+			if i, ok := index[member]; ok {
+				members[i] = c
+				continue
+			}
+			index[member] = len(members)
+			members = append(members, c)
+		}
+	}
+	for _, include := range t.Includes {
+		add(include.Flatten())
+	}
+	add(t.Configurations)
+	if members == nil {
+		members = []Configuration{}
+	}
+	return members
+}
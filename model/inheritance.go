@@ -0,0 +1,58 @@
+package model
+
+import "fmt"
+
+// ConfigReference points at a base configuration by name and an exact version or a version constraint.
+// Resolved is filled in on reads with the version the constraint selected.
+type ConfigReference struct {
+	Name     string   `json:"name"`
+	Version  string   `json:"version"`
+	Resolved *Version `json:"resolved,omitempty"`
+}
+
+func (r ConfigReference) Validate() error {
+	return r.validate("extends")
+}
+
+// ValidateSource checks the reference of a group member to the configuration it reads.
+func (r ConfigReference) ValidateSource() error {
+	return r.validate("source")
+}
+
+func (r ConfigReference) validate(field string) error {
+	if r.Name == "" {
+		return fmt.Errorf("%s needs the name of a config: %w", field, ErrInvalid)
+	}
+	if _, err := ParseVersionConstraint(r.Version); err != nil {
+		return fmt.Errorf("%s %s: %s: %w", field, r.Name, err.Error(), ErrInvalid)
+	}
+	return nil
+}
+
+// MergeBase returns a copy of config with the parameters and types of base filled in where config does not set them.
+func MergeBase(config Configuration, base Configuration) Configuration {
+	params := make(map[string]string, len(base.Parameters)+len(config.Parameters))
+	for k, v := range base.Parameters {
+		params[k] = v
+	}
+	for k, v := range config.Parameters {
+		params[k] = v
+	}
+
+	types := make(map[string]ParameterType, len(base.Types)+len(config.Types))
+	for k, t := range base.Types {
+		if _, overridden := config.Parameters[k]; !overridden {
+			types[k] = t
+		}
+	}
+	for k, t := range config.Types {
+		types[k] = t
+	}
+
+	config.Parameters = params
+	config.Types = nil
+	if len(types) > 0 {
+		config.Types = types
+	}
+	return config
+}
@@ -0,0 +1,112 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	RefSelf   = "self"
+	RefConfig = "config"
+	RefGroup  = "group"
+)
+
+// ParameterReference is the target of a ${...} placeholder in a parameter value:
+// ${self:key}, ${config:name@version#key} or ${group:name@version#config.key}.
+type ParameterReference struct {
+	Kind    string
+	Name    string
+	Version string
+	Member  string
+	Key     string
+}
+
+func ParseParameterReference(expr string) (ParameterReference, error) {
+	ref := ParameterReference{}
+	kind, target, ok := strings.Cut(expr, ":")
+	if !ok {
+		return ref, fmt.Errorf("placeholder ${%s} has no kind, expected self, config or group", expr)
+	}
+	ref.Kind = kind
+
+	switch kind {
+	case RefSelf:
+		ref.Key = target
+	case RefConfig, RefGroup:
+		object, key, ok := strings.Cut(target, "#")
+		if !ok {
+			return ref, fmt.Errorf("placeholder ${%s} needs a #key", expr)
+		}
+		name, version, ok := strings.Cut(object, "@")
+		if !ok || name == "" || version == "" {
+			return ref, fmt.Errorf("placeholder ${%s} needs name@version", expr)
+		}
+		ref.Name, ref.Version, ref.Key = name, version, key
+		if kind == RefGroup {
+			member, memberKey, ok := strings.Cut(key, ".")
+			if !ok || member == "" {
+				return ref, fmt.Errorf("placeholder ${%s} needs #config.key", expr)
+			}
+			ref.Member, ref.Key = member, memberKey
+		}
+	default:
+		return ref, fmt.Errorf("placeholder ${%s} has unknown kind %q", expr, kind)
+	}
+
+	if ref.Key == "" {
+		return ref, fmt.Errorf("placeholder ${%s} has an empty key", expr)
+	}
+	return ref, nil
+}
+
+func (r ParameterReference) String() string {
+	switch r.Kind {
+	case RefConfig:
+		return fmt.Sprintf("${config:%s@%s#%s}", r.Name, r.Version, r.Key)
+	case RefGroup:
+		return fmt.Sprintf("${group:%s@%s#%s.%s}", r.Name, r.Version, r.Member, r.Key)
+	}
+	return fmt.Sprintf("${self:%s}", r.Key)
+}
+
+// Interpolate replaces every placeholder in value with the result of lookup. $${ is an escaped, literal ${.
+func Interpolate(value string, lookup func(ref ParameterReference) (string, error)) (string, error) {
+	var sb strings.Builder
+	for i := 0; i < len(value); {
+		if strings.HasPrefix(value[i:], "$${") {
+			sb.WriteString("${")
+			i += 3
+			continue
+		}
+		if strings.HasPrefix(value[i:], "${") {
+			end := strings.IndexByte(value[i+2:], '}')
+			if end < 0 {
+				return "", fmt.Errorf("unclosed placeholder in %q", value)
+			}
+			ref, err := ParseParameterReference(value[i+2 : i+2+end])
+			if err != nil {
+				return "", err
+			}
+			resolved, err := lookup(ref)
+			if err != nil {
+				return "", err
+			}
+			sb.WriteString(resolved)
+			i += end + 3
+			continue
+		}
+		sb.WriteByte(value[i])
+		i++
+	}
+	return sb.String(), nil
+}
+
+// HasPlaceholder reports whether value contains a placeholder, malformed ones included.
+func HasPlaceholder(value string) bool {
+	found := false
+	_, err := Interpolate(value, func(ParameterReference) (string, error) {
+		found = true
+		return "", nil
+	})
+	return found || err != nil
+}
@@ -0,0 +1,85 @@
+package model
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Labels follow the Kubernetes rules: a key is a name with an optional DNS subdomain prefix, as in
+// app.kubernetes.io/name, and a value is empty or a name. Names are at most 63 characters, start and end
+// with a letter or digit and may contain - _ and . in between.
+type Labels map[string]string
+
+const (
+	maxLabelName   = 63
+	maxLabelPrefix = 253
+)
+
+var (
+	labelName   = regexp.MustCompile(`^[A-Za-z0-9]([-A-Za-z0-9_.]*[A-Za-z0-9])?$`)
+	labelPrefix = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`)
+)
+
+func (l Labels) Validate() error {
+	for _, k := range l.keys() {
+		if err := validateLabelKey(k); err != nil {
+			return fmt.Errorf("label %q: %s: %w", k, err.Error(), ErrInvalid)
+		}
+		if v := l[k]; v != "" && !validLabelName(v) {
+			return fmt.Errorf("label %q: value %q must be at most %d letters, digits, -, _ or . and start and end with a letter or digit: %w",
+				k, v, maxLabelName, ErrInvalid)
+		}
+	}
+	return nil
+}
+
+func validateLabelKey(key string) error {
+	name := key
+	if prefix, rest, ok := strings.Cut(key, "/"); ok {
+		if len(prefix) > maxLabelPrefix || !labelPrefix.MatchString(prefix) {
+			return fmt.Errorf("prefix %q must be a lowercase DNS subdomain of at most %d characters", prefix, maxLabelPrefix)
+		}
+		name = rest
+	}
+	if !validLabelName(name) {
+		return fmt.Errorf("name %q must be at most %d letters, digits, -, _ or . and start and end with a letter or digit", name, maxLabelName)
+	}
+	return nil
+}
+
+func validLabelName(name string) bool {
+	return len(name) <= maxLabelName && labelName.MatchString(name)
+}
+
+// Encode returns the canonical k:v;k:v form used in group keys and routes. Pairs are sorted by key, keys and
+// values are escaped so the encoding is a single key segment and decodes to the same labels.
+func (l Labels) Encode() string {
+	pairs := make([]string, 0, len(l))
+	for _, k := range l.keys() {
+		pairs = append(pairs, url.QueryEscape(k)+":"+url.QueryEscape(l[k]))
+	}
+	return strings.Join(pairs, ";")
+}
+
+// Includes reports whether l carries every label of subset with the same value, an empty subset is included in
+// any labels.
+func (l Labels) Includes(subset map[string]string) bool {
+	for k, v := range subset {
+		if value, ok := l[k]; !ok || value != v {
+			return false
+		}
+	}
+	return true
+}
+
+func (l Labels) keys() []string {
+	keys := make([]string, 0, len(l))
+	for k := range l {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,51 @@
+package model
+
+import "fmt"
+
+// MemberBinding tells whether a group member follows later changes to the configurations it reads.
+type MemberBinding string
+
+const (
+	// BindingPinned members always read the same content, an embedded copy or one exact configuration version.
+	BindingPinned MemberBinding = "pinned"
+	// BindingFloating members read the highest configuration version matching their version constraint.
+	BindingFloating MemberBinding = "floating"
+)
+
+// ComputeBinding returns the binding of the configuration as a group member. Members without a source embed
+// their content and are pinned.
+func (c Configuration) ComputeBinding() MemberBinding {
+	if c.Source == nil {
+		return BindingPinned
+	}
+	if constraint, err := ParseVersionConstraint(c.Source.Version); err == nil {
+		if _, exact := constraint.Exact(); exact {
+			return BindingPinned
+		}
+	}
+	return BindingFloating
+}
+
+// MemberName is the name of the configuration as a group member, a member referencing a configuration is named
+// after it unless it sets a name of its own.
+func (c Configuration) MemberName() string {
+	if c.Name == "" && c.Source != nil {
+		return c.Source.Name
+	}
+	return c.Name
+}
+
+// ValidateSource checks that a member referencing a configuration carries no content of its own, its parameters,
+// types and base all come from the configuration it reads. Its name and labels stay the member's.
+func (c Configuration) ValidateSource() error {
+	if c.Source == nil {
+		return nil
+	}
+	if err := c.Source.ValidateSource(); err != nil {
+		return err
+	}
+	if len(c.Parameters) > 0 || len(c.Types) > 0 || c.Extends != nil {
+		return fmt.Errorf("member %s reads config %s and cannot set parameters, types or extends: %w", c.Name, c.Source.Name, ErrInvalid)
+	}
+	return nil
+}
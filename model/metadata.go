@@ -0,0 +1,33 @@
+package model
+
+import "time"
+
+// Metadata is stored with configurations and groups. CreatedAt, UpdatedAt and CreatedBy are set by the server,
+// values sent by clients for them are ignored.
+type Metadata struct {
+	CreatedAt   time.Time         `json:"createdAt"`
+	UpdatedAt   time.Time         `json:"updatedAt"`
+	CreatedBy   string            `json:"createdBy,omitempty"`
+	Description string            `json:"description,omitempty"`
+	Owner       string            `json:"owner,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+const (
+	KindConfig = "config"
+	KindGroup  = "group"
+)
+
+// ObjectRef is the entry of the ID index, it names the object an ID was assigned to.
+type ObjectRef struct {
+	Kind    string  `json:"kind"`
+	Name    string  `json:"name"`
+	Version Version `json:"version"`
+}
+
+// IndexedObject is an ID index entry together with the update time of the object, so listings sort by update time
+// without reading the objects. Entries written before it was stored have a zero UpdatedAt.
+type IndexedObject struct {
+	ObjectRef
+	UpdatedAt time.Time `json:"updatedAt,omitempty"`
+}
@@ -0,0 +1,137 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// flattenParameters copies raw into out, expanding nested objects into dotted keys. Objects declared as
+// json are kept as a single value. It reports whether any object was expanded.
+func flattenParameters(prefix string, raw map[string]json.RawMessage, types map[string]ParameterType, out map[string]json.RawMessage) (bool, error) {
+	expanded := false
+	for k, v := range raw {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+
+		trimmed := bytes.TrimSpace(v)
+		if _, declared := types[key]; !declared && len(trimmed) > 0 && trimmed[0] == '{' {
+			var nested map[string]json.RawMessage
+			if err := json.Unmarshal(trimmed, &nested); err != nil {
+				return false, fmt.Errorf("parameter %s: %w", key, err)
+			}
+			if len(nested) == 0 {
+				return false, fmt.Errorf("parameter %s is an empty object", key)
+			}
+			if _, err := flattenParameters(key, nested, types, out); err != nil {
+				return false, err
+			}
+			expanded = true
+			continue
+		}
+
+		if _, ok := out[key]; ok {
+			return false, fmt.Errorf("parameter %s is set more than once", key)
+		}
+		out[key] = v
+	}
+	return expanded, nil
+}
+
+// parameterConflicts lists every key that is a value and at the same time the parent of other keys.
+func parameterConflicts(keys []string) []string {
+	sort.Strings(keys)
+
+	var conflicts []string
+	for i, k := range keys {
+		var children []string
+		for _, other := range keys[i+1:] {
+			if strings.HasPrefix(other, k+".") {
+				children = append(children, other)
+			}
+		}
+		if len(children) > 0 {
+			conflicts = append(conflicts, fmt.Sprintf("%s is both a value and the parent of %s", k, strings.Join(children, ", ")))
+		}
+	}
+	return conflicts
+}
+
+// NestParameters expands dotted parameter keys into nested objects whose leaves are typed JSON values.
+func (c Configuration) NestParameters() (map[string]interface{}, error) {
+	keys := make([]string, 0, len(c.Parameters))
+	for k := range c.Parameters {
+		keys = append(keys, k)
+	}
+	if conflicts := parameterConflicts(keys); len(conflicts) > 0 {
+		return nil, fmt.Errorf("config %s cannot be nested, %s: %w", c.Name, strings.Join(conflicts, "; "), ErrInvalid)
+	}
+
+	nested := make(map[string]interface{})
+	for k, v := range c.Parameters {
+		raw, err := c.TypeOf(k).jsonValue(v)
+		if err != nil {
+			return nil, err
+		}
+
+		parts := strings.Split(k, ".")
+		node := nested
+		for _, part := range parts[:len(parts)-1] {
+			child, ok := node[part].(map[string]interface{})
+			if !ok {
+				child = make(map[string]interface{})
+				node[part] = child
+			}
+			node = child
+		}
+		node[parts[len(parts)-1]] = raw
+	}
+	return nested, nil
+}
+
+// NestedConfiguration renders a configuration with nested parameters, it is the ?view=nested form of a read.
+type NestedConfiguration struct {
+	config     Configuration
+	parameters map[string]interface{}
+}
+
+func NestConfiguration(config Configuration) (*NestedConfiguration, error) {
+	params, err := config.NestParameters()
+	if err != nil {
+		return nil, err
+	}
+	return &NestedConfiguration{config: config, parameters: params}, nil
+}
+
+func (n NestedConfiguration) MarshalJSON() ([]byte, error) {
+	out := struct {
+		*configurationAlias
+		Parameters map[string]interface{} `json:"parameters"`
+	}{(*configurationAlias)(&n.config), n.parameters}
+	return json.Marshal(out)
+}
+
+// NestedGroup is the ?view=nested form of a configuration group read.
+type NestedGroup struct {
+	Name           string                `json:"name"`
+	Id             int64                 `json:"id"`
+	Version        Version               `json:"version"`
+	Configurations []NestedConfiguration `json:"configurations"`
+	Fingerprint    string                `json:"fingerprint,omitempty"`
+}
+
+func NestGroup(group ConfigurationGroup) (*NestedGroup, error) {
+	nested := &NestedGroup{Name: group.Name, Id: group.Id, Version: group.Version, Fingerprint: group.Fingerprint}
+	for _, c := range group.Configurations {
+		n, err := NestConfiguration(c)
+		if err != nil {
+			return nil, err
+		}
+		nested.Configurations = append(nested.Configurations, *n)
+	}
+	return nested, nil
+}
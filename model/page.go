@@ -0,0 +1,99 @@
+package model
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const (
+	SortByName      = "name"
+	SortByVersion   = "version"
+	SortByUpdatedAt = "updatedAt"
+
+	OrderAsc  = "asc"
+	OrderDesc = "desc"
+)
+
+const (
+	DefaultPageLimit = 50
+	maxPageLimit     = 500
+)
+
+// ListOptions pick a page of a listing. Prefix filters on the name, Selector on labels, and a Delimiter of / rolls
+// configs nested below the prefix up into folders. Cursor is the NextCursor of the previous page.
+type ListOptions struct {
+	Prefix    string
+	Selector  Selector
+	Delimiter string
+	Sort      string
+	Order     string
+	Cursor    string
+	Limit     int
+}
+
+func (o ListOptions) Validate() error {
+	switch o.Sort {
+	case SortByName, SortByVersion, SortByUpdatedAt:
+	default:
+		return fmt.Errorf("sort must be %s, %s or %s: %w", SortByName, SortByVersion, SortByUpdatedAt, ErrInvalid)
+	}
+	if o.Order != OrderAsc && o.Order != OrderDesc {
+		return fmt.Errorf("order must be %s or %s: %w", OrderAsc, OrderDesc, ErrInvalid)
+	}
+	if o.Delimiter != "" && o.Delimiter != "/" {
+		return fmt.Errorf("delimiter must be /: %w", ErrInvalid)
+	}
+	if o.Limit < 1 || o.Limit > maxPageLimit {
+		return fmt.Errorf("limit must be between 1 and %d: %w", maxPageLimit, ErrInvalid)
+	}
+	return nil
+}
+
+// PageCursor is the last entry of a page, the next page starts right after it. Pages are keyed on the sort values
+// rather than offsets, so writes between two requests neither repeat nor skip entries.
+type PageCursor struct {
+	Sort      string    `json:"s"`
+	Order     string    `json:"o"`
+	Name      string    `json:"n"`
+	Version   Version   `json:"v"`
+	UpdatedAt time.Time `json:"u,omitempty"`
+}
+
+func (c PageCursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor reads the cursor of a listing made with opts, a cursor of a listing sorted differently is rejected.
+func DecodeCursor(cursor string, opts ListOptions) (*PageCursor, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("malformed cursor: %w", ErrInvalid)
+	}
+	c := &PageCursor{}
+	if err = json.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("malformed cursor: %w", ErrInvalid)
+	}
+	if c.Sort != opts.Sort || c.Order != opts.Order {
+		return nil, fmt.Errorf("cursor belongs to a listing sorted by %s %s: %w", c.Sort, c.Order, ErrInvalid)
+	}
+	return c, nil
+}
+
+// swagger:model ConfigurationPage
+type ConfigurationPage struct {
+	Items      []Configuration `json:"items"`
+	Folders    []string        `json:"folders,omitempty"`
+	NextCursor string          `json:"nextCursor,omitempty"`
+}
+
+// swagger:model ConfigurationGroupPage
+type ConfigurationGroupPage struct {
+	Items      []ConfigurationGroup `json:"items"`
+	NextCursor string               `json:"nextCursor,omitempty"`
+}
@@ -0,0 +1,268 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParameterType declares how the value of a parameter is interpreted. Values are always kept in Parameters
+// in a canonical string form, the declared type decides how they are validated and rendered as JSON.
+type ParameterType string
+
+const (
+	ParamString   ParameterType = "string"
+	ParamInt      ParameterType = "int"
+	ParamFloat    ParameterType = "float"
+	ParamBool     ParameterType = "bool"
+	ParamDuration ParameterType = "duration"
+	ParamList     ParameterType = "list"
+	ParamJSON     ParameterType = "json"
+)
+
+func (t ParameterType) Valid() bool {
+	switch t {
+	case ParamString, ParamInt, ParamFloat, ParamBool, ParamDuration, ParamList, ParamJSON:
+		return true
+	}
+	return false
+}
+
+// Normalize checks that value is valid for the type and returns its canonical string form.
+func (t ParameterType) Normalize(value string) (string, error) {
+	switch t {
+	case ParamString:
+		return value, nil
+	case ParamInt:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("%q is not an int", value)
+		}
+		return strconv.FormatInt(n, 10), nil
+	case ParamFloat:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil || math.IsInf(f, 0) || math.IsNaN(f) {
+			return "", fmt.Errorf("%q is not a float", value)
+		}
+		return strconv.FormatFloat(f, 'g', -1, 64), nil
+	case ParamBool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return "", fmt.Errorf("%q is not a bool", value)
+		}
+		return strconv.FormatBool(b), nil
+	case ParamDuration:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return "", fmt.Errorf("%q is not a duration", value)
+		}
+		return d.String(), nil
+	case ParamList:
+		var list []interface{}
+		if err := json.Unmarshal([]byte(value), &list); err != nil || list == nil {
+			return "", fmt.Errorf("%q is not a JSON array", value)
+		}
+		return compactJSON(list)
+	case ParamJSON:
+		var object map[string]interface{}
+		if err := json.Unmarshal([]byte(value), &object); err != nil || object == nil {
+			return "", fmt.Errorf("%q is not a JSON object", value)
+		}
+		return compactJSON(object)
+	}
+	return "", fmt.Errorf("unknown parameter type %q", t)
+}
+
+// jsonValue renders a canonical value as the JSON value of its type. Values that are still placeholders
+// are rendered as strings.
+func (t ParameterType) jsonValue(value string) (json.RawMessage, error) {
+	switch t {
+	case ParamInt, ParamFloat, ParamBool, ParamList, ParamJSON:
+		if json.Valid([]byte(value)) {
+			return json.RawMessage(value), nil
+		}
+	}
+	return json.Marshal(value)
+}
+
+// inferParameterType picks the type of a JSON value sent without a declared type.
+func inferParameterType(raw json.RawMessage) ParameterType {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 {
+		return ParamString
+	}
+	switch raw[0] {
+	case 't', 'f':
+		return ParamBool
+	case '[':
+		return ParamList
+	case '{':
+		return ParamJSON
+	case '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		if _, err := strconv.ParseInt(string(raw), 10, 64); err == nil {
+			return ParamInt
+		}
+		return ParamFloat
+	}
+	return ParamString
+}
+
+// parseParameter turns a JSON value into the canonical string form of its type. Quoted values are accepted
+// for every type, so string-only data written before types existed stays readable.
+func parseParameter(t ParameterType, raw json.RawMessage) (string, error) {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		if HasPlaceholder(s) {
+			return s, nil
+		}
+		return t.Normalize(s)
+	}
+	if t == ParamString || t == ParamDuration {
+		return "", fmt.Errorf("%s is not a %s", raw, t)
+	}
+	return t.Normalize(string(raw))
+}
+
+func compactJSON(v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// ValidateParameters checks the placeholders in every value, every declared type and that the values in
+// Parameters are valid for it. Values with placeholders are only checked against their type once resolved.
+func (c Configuration) ValidateParameters() error {
+	params := make([]string, 0, len(c.Parameters))
+	for k := range c.Parameters {
+		params = append(params, k)
+	}
+	sort.Strings(params)
+	for _, k := range params {
+		if _, err := Interpolate(c.Parameters[k], func(ParameterReference) (string, error) { return "", nil }); err != nil {
+			return fmt.Errorf("parameter %s: %s: %w", k, err.Error(), ErrInvalid)
+		}
+	}
+
+	keys := make([]string, 0, len(c.Types))
+	for k := range c.Types {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		t := c.Types[k]
+		if !t.Valid() {
+			return fmt.Errorf("parameter %s has unknown type %q: %w", k, t, ErrInvalid)
+		}
+		value, ok := c.Parameters[k]
+		if !ok {
+			return fmt.Errorf("type declared for missing parameter %s: %w", k, ErrInvalid)
+		}
+		if HasPlaceholder(value) {
+			continue
+		}
+		if _, err := t.Normalize(value); err != nil {
+			return fmt.Errorf("parameter %s: %s: %w", k, err.Error(), ErrInvalid)
+		}
+	}
+	return nil
+}
+
+// TypeOf returns the declared type of a parameter, parameters without a declaration are strings.
+func (c Configuration) TypeOf(key string) ParameterType {
+	if t, ok := c.Types[key]; ok {
+		return t
+	}
+	return ParamString
+}
+
+// configurationAlias has the fields of Configuration without its JSON methods.
+type configurationAlias Configuration
+
+type configurationJSON struct {
+	*configurationAlias
+	Parameters map[string]json.RawMessage `json:"parameters"`
+}
+
+// MarshalJSON renders every parameter as a JSON value of its declared type.
+func (c Configuration) MarshalJSON() ([]byte, error) {
+	out := configurationJSON{configurationAlias: (*configurationAlias)(&c)}
+	if c.Parameters != nil {
+		out.Parameters = make(map[string]json.RawMessage, len(c.Parameters))
+	}
+	for k, v := range c.Parameters {
+		raw, err := c.TypeOf(k).jsonValue(v)
+		if err != nil {
+			return nil, err
+		}
+		out.Parameters[k] = raw
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON accepts typed JSON values, parameters without a declared type get the type of their JSON value.
+// Nested objects are flattened into dotted keys unless the parameter is declared as json.
+func (c *Configuration) UnmarshalJSON(data []byte) error {
+	in := configurationJSON{configurationAlias: &configurationAlias{}}
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+
+	config := Configuration(*in.configurationAlias)
+	config.Fingerprint = ""
+
+	flat := make(map[string]json.RawMessage, len(in.Parameters))
+	expanded, err := flattenParameters("", in.Parameters, config.Types, flat)
+	if err != nil {
+		return err
+	}
+	if expanded {
+		keys := make([]string, 0, len(flat))
+		for k := range flat {
+			keys = append(keys, k)
+		}
+		if conflicts := parameterConflicts(keys); len(conflicts) > 0 {
+			return fmt.Errorf("conflicting parameters: %s", strings.Join(conflicts, "; "))
+		}
+	}
+
+	config.Parameters = nil
+	if in.Parameters != nil {
+		config.Parameters = make(map[string]string, len(flat))
+	}
+	for k, raw := range flat {
+		t, declared := config.Types[k]
+		if !declared {
+			t = inferParameterType(raw)
+		}
+		if !t.Valid() {
+			return fmt.Errorf("parameter %s has unknown type %q", k, t)
+		}
+		value, err := parseParameter(t, raw)
+		if err != nil {
+			return fmt.Errorf("parameter %s: %w", k, err)
+		}
+		config.Parameters[k] = value
+		if !declared && t != ParamString {
+			if config.Types == nil {
+				config.Types = make(map[string]ParameterType)
+			}
+			config.Types[k] = t
+		}
+	}
+	for k := range config.Types {
+		if _, ok := flat[k]; !ok {
+			return fmt.Errorf("type declared for missing parameter %s", k)
+		}
+	}
+
+	*c = config
+	return nil
+}
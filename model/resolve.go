@@ -0,0 +1,178 @@
+package model
+
+import (
+	"fmt"
+	"sort"
+)
+
+const (
+	// ResolveBySpecificity layers members by how many labels they have, precedence breaks ties.
+	ResolveBySpecificity = "specificity"
+	// ResolveByPrecedence layers members by the most significant context key they are labelled with, the number of
+	// labels breaks ties.
+	ResolveByPrecedence = "precedence"
+)
+
+// ContextKeys are the label keys a client context may set, DefaultPrecedence orders them most significant first.
+var (
+	ContextKeys       = []string{"env", "region", "cluster", "instance"}
+	DefaultPrecedence = []string{"instance", "cluster", "region", "env"}
+)
+
+// ResolveRequest is the client context a group is resolved for. Member restricts the resolution to the members
+// with that name.
+type ResolveRequest struct {
+	Context    Labels
+	Order      string
+	Precedence []string
+	Member     string
+}
+
+// Validate checks the order and precedence and fills in their defaults.
+func (r *ResolveRequest) Validate() error {
+	switch r.Order {
+	case "":
+		r.Order = ResolveBySpecificity
+	case ResolveBySpecificity, ResolveByPrecedence:
+	default:
+		return fmt.Errorf("order must be %s or %s: %w", ResolveBySpecificity, ResolveByPrecedence, ErrInvalid)
+	}
+
+	if len(r.Precedence) == 0 {
+		r.Precedence = DefaultPrecedence
+	}
+	seen := make(map[string]bool, len(r.Precedence))
+	for _, key := range r.Precedence {
+		if !contains(ContextKeys, key) {
+			return fmt.Errorf("precedence key %q is not one of %v: %w", key, ContextKeys, ErrInvalid)
+		}
+		if seen[key] {
+			return fmt.Errorf("precedence key %q is listed twice: %w", key, ErrInvalid)
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+// Applies reports whether a member with the given labels is part of the context, every label it carries must be
+// set to the same value in the context. Members without labels always apply.
+func (r ResolveRequest) Applies(labels Labels) bool {
+	for k, v := range labels {
+		if value, ok := r.Context[k]; !ok || value != v {
+			return false
+		}
+	}
+	return true
+}
+
+// rank is compared position by position, a higher rank is a more specific layer.
+func (r ResolveRequest) rank(labels Labels) []int {
+	presence := make([]int, 0, len(r.Precedence))
+	for _, key := range r.Precedence {
+		if _, ok := labels[key]; ok {
+			presence = append(presence, 1)
+		} else {
+			presence = append(presence, 0)
+		}
+	}
+	if r.Order == ResolveByPrecedence {
+		return append(presence, len(labels))
+	}
+	return append([]int{len(labels)}, presence...)
+}
+
+// ParameterSource names the group member a resolved value came from.
+
+// swagger:model ParameterSource
+type ParameterSource struct {
+	Member  string  `json:"member"`
+	Labels  Labels  `json:"labels"`
+	Version Version `json:"version"`
+}
+
+// swagger:model ResolvedParameter
+type ResolvedParameter struct {
+	Value  string          `json:"value"`
+	Type   ParameterType   `json:"type"`
+	Source ParameterSource `json:"source"`
+}
+
+// ParameterCollision is a parameter that members with different names set. Sources lists them from the least to the
+// most specific layer, the value of the last one is used.
+
+// swagger:model ParameterCollision
+type ParameterCollision struct {
+	Parameter string            `json:"parameter"`
+	Sources   []ParameterSource `json:"sources"`
+}
+
+// ResolvedGroup is the parameter map a client context reads from a group. Layers lists the applied members from
+// the least to the most specific, each value comes from the last layer that sets it. Collisions lists the
+// parameters members with different names set, ordered by parameter.
+
+// swagger:model ResolvedGroup
+type ResolvedGroup struct {
+	Name       string                       `json:"name"`
+	Version    Version                      `json:"version"`
+	Context    Labels                       `json:"context"`
+	Order      string                       `json:"order"`
+	Precedence []string                     `json:"precedence"`
+	Layers     []ParameterSource            `json:"layers"`
+	Parameters map[string]ResolvedParameter `json:"parameters"`
+	Collisions []ParameterCollision         `json:"collisions"`
+}
+
+// Resolve merges the members of group that apply to the context of req, more specific layers override less
+// specific ones. Members of the same rank are applied in order of name and labels. req must be validated.
+func Resolve(group ConfigurationGroup, req ResolveRequest) *ResolvedGroup {
+	var layers []Configuration
+	for _, c := range group.Configurations {
+		if (req.Member == "" || c.Name == req.Member) && req.Applies(c.Labels) {
+			layers = append(layers, c)
+		}
+	}
+	sort.SliceStable(layers, func(i, j int) bool {
+		a, b := req.rank(layers[i].Labels), req.rank(layers[j].Labels)
+		for k := range a {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+		if layers[i].Name != layers[j].Name {
+			return layers[i].Name < layers[j].Name
+		}
+		return layers[i].Labels.Encode() < layers[j].Labels.Encode()
+	})
+
+	resolved := &ResolvedGroup{
+		Name:       group.Name,
+		Version:    group.Version,
+		Context:    req.Context,
+		Order:      req.Order,
+		Precedence: req.Precedence,
+		Layers:     []ParameterSource{},
+		Parameters: make(map[string]ResolvedParameter),
+		Collisions: []ParameterCollision{},
+	}
+	setBy := make(map[string][]ParameterSource)
+	for _, c := range layers {
+		source := ParameterSource{Member: c.Name, Labels: c.Labels, Version: c.Version}
+		resolved.Layers = append(resolved.Layers, source)
+		for k, v := range c.Parameters {
+			resolved.Parameters[k] = ResolvedParameter{Value: v, Type: c.TypeOf(k), Source: source}
+			setBy[k] = append(setBy[k], source)
+		}
+	}
+	for k, sources := range setBy {
+		for _, s := range sources[1:] {
+			if s.Member != sources[0].Member {
+				resolved.Collisions = append(resolved.Collisions, ParameterCollision{Parameter: k, Sources: sources})
+				break
+			}
+		}
+	}
+	sort.Slice(resolved.Collisions, func(i, j int) bool {
+		return resolved.Collisions[i].Parameter < resolved.Collisions[j].Parameter
+	})
+	return resolved
+}
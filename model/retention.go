@@ -0,0 +1,107 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	RetentionConfigs = "configs"
+	RetentionGroups  = "groups"
+)
+
+// RetentionPolicy selects configs or groups by name prefix and labels and decides which of their versions are kept.
+// A version is pruned only when it is not among the KeepLast highest versions of its name, is older than
+// KeepYoungerThan and, with KeepReferenced, no group member reads it, through a source reference resolving to it or
+// as an embedded member with exactly its name and version.
+
+// swagger:model RetentionPolicy
+type RetentionPolicy struct {
+	Name            string            `json:"name"`
+	Kind            string            `json:"kind,omitempty"`
+	NamePrefix      string            `json:"namePrefix,omitempty"`
+	Labels          map[string]string `json:"labels,omitempty"`
+	KeepLast        int               `json:"keepLast"`
+	KeepYoungerThan string            `json:"keepYoungerThan,omitempty"`
+	KeepReferenced  bool              `json:"keepReferenced"`
+}
+
+func (p RetentionPolicy) Validate() error {
+	if p.Name == "" {
+		return errors.New("policy name is required")
+	}
+	if p.Kind != "" && p.Kind != RetentionConfigs && p.Kind != RetentionGroups {
+		return fmt.Errorf("kind must be %q, %q or empty", RetentionConfigs, RetentionGroups)
+	}
+	if p.KeepLast < 1 {
+		return errors.New("keepLast must be at least 1, the latest version is never pruned")
+	}
+	if _, err := p.MaxAge(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// MaxAge parses KeepYoungerThan, it accepts Go durations and whole days such as "90d".
+func (p RetentionPolicy) MaxAge() (time.Duration, error) {
+	if p.KeepYoungerThan == "" {
+		return 0, nil
+	}
+	if days, ok := strings.CutSuffix(p.KeepYoungerThan, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("keepYoungerThan %q is not a valid number of days", p.KeepYoungerThan)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(p.KeepYoungerThan)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("keepYoungerThan %q is not a valid duration", p.KeepYoungerThan)
+	}
+	return d, nil
+}
+
+// Matches reports whether the policy applies to an object of the given kind and name. Labels holds the labels
+// of a configuration, or of every member of a group, the policy labels must all be present on at least one of them.
+func (p RetentionPolicy) Matches(kind string, name string, labels []map[string]string) bool {
+	if p.Kind != "" && p.Kind != kind {
+		return false
+	}
+	if !strings.HasPrefix(name, p.NamePrefix) {
+		return false
+	}
+	if len(p.Labels) == 0 {
+		return true
+	}
+	for _, l := range labels {
+		matched := true
+		for k, v := range p.Labels {
+			if l[k] != v {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+type PrunedVersion struct {
+	Kind    string  `json:"kind"`
+	Name    string  `json:"name"`
+	Version Version `json:"version"`
+	Policy  string  `json:"policy"`
+}
+
+// swagger:model RetentionReport
+type RetentionReport struct {
+	DryRun    bool            `json:"dryRun"`
+	StartedAt time.Time       `json:"startedAt"`
+	Checked   int             `json:"checked"`
+	Pruned    []PrunedVersion `json:"pruned"`
+}
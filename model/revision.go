@@ -0,0 +1,43 @@
+package model
+
+import "time"
+
+// swagger:model ConfigurationRevision
+type ConfigurationRevision struct {
+	Number        int64          `json:"number"`
+	Timestamp     time.Time      `json:"timestamp"`
+	Author        string         `json:"author"`
+	Deleted       bool           `json:"deleted"`
+	RestoredFrom  int64          `json:"restoredFrom,omitempty"`
+	Configuration *Configuration `json:"configuration,omitempty"`
+}
+
+// swagger:model GroupRevision
+type GroupRevision struct {
+	Number       int64               `json:"number"`
+	Timestamp    time.Time           `json:"timestamp"`
+	Author       string              `json:"author"`
+	Deleted      bool                `json:"deleted"`
+	RestoredFrom int64               `json:"restoredFrom,omitempty"`
+	Group        *ConfigurationGroup `json:"group,omitempty"`
+}
+
+// ConfigurationRevisionAsOf returns the last revision written at or before t, revisions must be sorted by number.
+func ConfigurationRevisionAsOf(revisions []ConfigurationRevision, t time.Time) (*ConfigurationRevision, bool) {
+	for i := len(revisions) - 1; i >= 0; i-- {
+		if !revisions[i].Timestamp.After(t) {
+			return &revisions[i], true
+		}
+	}
+	return nil, false
+}
+
+// GroupRevisionAsOf returns the last revision written at or before t, revisions must be sorted by number.
+func GroupRevisionAsOf(revisions []GroupRevision, t time.Time) (*GroupRevision, bool) {
+	for i := len(revisions) - 1; i >= 0; i-- {
+		if !revisions[i].Timestamp.After(t) {
+			return &revisions[i], true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,206 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParameterSchema constrains a single parameter. Minimum and Maximum apply to numeric values,
+// Enum and Pattern to the canonical string form of the value.
+type ParameterSchema struct {
+	Type    ParameterType `json:"type,omitempty"`
+	Minimum *float64      `json:"minimum,omitempty"`
+	Maximum *float64      `json:"maximum,omitempty"`
+	Enum    []string      `json:"enum,omitempty"`
+	Pattern string        `json:"pattern,omitempty"`
+}
+
+// Schema applies to configurations with the name ConfigName, to configurations carrying every label in
+// Selector, or to those matching both when both are set. Registering a schema again stores a new version.
+
+// swagger:model Schema
+type Schema struct {
+	Name       string                     `json:"name"`
+	Version    int64                      `json:"version"`
+	ConfigName string                     `json:"configName,omitempty"`
+	Selector   map[string]string          `json:"selector,omitempty"`
+	Required   []string                   `json:"required,omitempty"`
+	Properties map[string]ParameterSchema `json:"properties,omitempty"`
+	CreatedAt  time.Time                  `json:"createdAt"`
+	CreatedBy  string                     `json:"createdBy"`
+}
+
+func (s Schema) Validate() error {
+	if s.Name == "" {
+		return errors.New("schema name is required")
+	}
+	if err := ValidateConfigName(s.Name); err != nil {
+		return err
+	}
+	if s.ConfigName == "" && len(s.Selector) == 0 {
+		return errors.New("schema needs a configName, a selector or both")
+	}
+	for k, p := range s.Properties {
+		if p.Type != "" && !p.Type.Valid() {
+			return fmt.Errorf("property %s has unknown type %q", k, p.Type)
+		}
+		if p.Minimum != nil && p.Maximum != nil && *p.Minimum > *p.Maximum {
+			return fmt.Errorf("property %s has minimum greater than maximum", k)
+		}
+		if p.Pattern != "" {
+			if _, err := regexp.Compile(p.Pattern); err != nil {
+				return fmt.Errorf("property %s has invalid pattern: %w", k, err)
+			}
+		}
+		if p.Type != "" {
+			for _, e := range p.Enum {
+				if _, err := p.Type.Normalize(e); err != nil {
+					return fmt.Errorf("property %s enum value %s", k, err.Error())
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// AppliesTo reports whether the schema constrains the given configuration.
+func (s Schema) AppliesTo(config Configuration) bool {
+	if s.ConfigName != "" && s.ConfigName != config.Name {
+		return false
+	}
+	for k, v := range s.Selector {
+		if config.Labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// Check returns every rule of the schema the configuration breaks, ordered by parameter.
+func (s Schema) Check(config Configuration) []Violation {
+	var violations []Violation
+	add := func(param string, rule string, format string, args ...interface{}) {
+		violations = append(violations, Violation{
+			Schema:    s.Name,
+			Version:   s.Version,
+			Parameter: param,
+			Rule:      rule,
+			Message:   fmt.Sprintf(format, args...),
+		})
+	}
+
+	for _, k := range s.Required {
+		if _, ok := config.Parameters[k]; !ok {
+			add(k, "required", "parameter is required")
+		}
+	}
+
+	for _, k := range sortedPropertyKeys(s.Properties) {
+		p := s.Properties[k]
+		value, ok := config.Parameters[k]
+		if !ok || HasPlaceholder(value) {
+			continue
+		}
+
+		if p.Type != "" {
+			if declared := config.TypeOf(k); declared != p.Type && declared != ParamString {
+				add(k, "type", "must be of type %s, declared as %s", p.Type, declared)
+				continue
+			}
+			normalized, err := p.Type.Normalize(value)
+			if err != nil {
+				add(k, "type", "must be of type %s: %s", p.Type, err.Error())
+				continue
+			}
+			value = normalized
+		}
+
+		if p.Minimum != nil || p.Maximum != nil {
+			n, err := strconv.ParseFloat(value, 64)
+			switch {
+			case err != nil:
+				add(k, "range", "%q is not a number", value)
+			case p.Minimum != nil && n < *p.Minimum:
+				add(k, "minimum", "%s is less than %s", value, formatNumber(*p.Minimum))
+			case p.Maximum != nil && n > *p.Maximum:
+				add(k, "maximum", "%s is greater than %s", value, formatNumber(*p.Maximum))
+			}
+		}
+
+		if len(p.Enum) > 0 && !containsEnum(p, value) {
+			add(k, "enum", "%q is not one of %s", value, strings.Join(p.Enum, ", "))
+		}
+
+		if p.Pattern != "" {
+			if re, err := regexp.Compile(p.Pattern); err == nil && !re.MatchString(value) {
+				add(k, "pattern", "%q does not match %s", value, p.Pattern)
+			}
+		}
+	}
+
+	sort.SliceStable(violations, func(i, j int) bool {
+		return violations[i].Parameter < violations[j].Parameter
+	})
+	return violations
+}
+
+func containsEnum(p ParameterSchema, value string) bool {
+	for _, e := range p.Enum {
+		if p.Type != "" {
+			if normalized, err := p.Type.Normalize(e); err == nil {
+				e = normalized
+			}
+		}
+		if e == value {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedPropertyKeys(properties map[string]ParameterSchema) []string {
+	keys := make([]string, 0, len(properties))
+	for k := range properties {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func formatNumber(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// Violation is a single broken schema rule.
+type Violation struct {
+	Config    string `json:"config,omitempty"`
+	Schema    string `json:"schema"`
+	Version   int64  `json:"version"`
+	Parameter string `json:"parameter"`
+	Rule      string `json:"rule"`
+	Message   string `json:"message"`
+}
+
+// ValidationError carries every violation found on a write, it matches ErrInvalid with errors.Is.
+
+// swagger:model ValidationError
+type ValidationError struct {
+	Violations []Violation `json:"violations"`
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		messages = append(messages, fmt.Sprintf("%s: %s", v.Parameter, v.Message))
+	}
+	return "schema validation failed: " + strings.Join(messages, "; ")
+}
+
+func (e *ValidationError) Unwrap() error {
+	return ErrInvalid
+}
@@ -0,0 +1,114 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+type SearchMode string
+
+const (
+	SearchExact     SearchMode = "exact"
+	SearchPrefix    SearchMode = "prefix"
+	SearchSubstring SearchMode = "substring"
+)
+
+// Fields a search can match, the value of a label match is k=v and metadata covers the description, owner,
+// creator and annotations.
+const (
+	FieldName           = "name"
+	FieldParameterKey   = "parameterKey"
+	FieldParameterValue = "parameterValue"
+	FieldLabel          = "label"
+	FieldMetadata       = "metadata"
+)
+
+const maxSearchLimit = 100
+
+// SearchQuery is matched case insensitively against whole field values. Empty Fields searches every field and an
+// empty Kind searches configs and groups.
+type SearchQuery struct {
+	Text   string
+	Mode   SearchMode
+	Fields []string
+	Kind   string
+	Limit  int
+}
+
+func (q SearchQuery) Validate() error {
+	if strings.TrimSpace(q.Text) == "" {
+		return fmt.Errorf("search text is required: %w", ErrInvalid)
+	}
+	switch q.Mode {
+	case SearchExact, SearchPrefix, SearchSubstring:
+	default:
+		return fmt.Errorf("search mode must be exact, prefix or substring: %w", ErrInvalid)
+	}
+	for _, f := range q.Fields {
+		switch f {
+		case FieldName, FieldParameterKey, FieldParameterValue, FieldLabel, FieldMetadata:
+		default:
+			return fmt.Errorf("unknown search field %q: %w", f, ErrInvalid)
+		}
+	}
+	if q.Kind != "" && q.Kind != KindConfig && q.Kind != KindGroup {
+		return fmt.Errorf("search kind must be %s or %s: %w", KindConfig, KindGroup, ErrInvalid)
+	}
+	if q.Limit < 1 || q.Limit > maxSearchLimit {
+		return fmt.Errorf("search limit must be between 1 and %d: %w", maxSearchLimit, ErrInvalid)
+	}
+	return nil
+}
+
+// SearchMatch is one matching field of a hit. Member names the group member the field belongs to and Key the
+// parameter, label or metadata entry. Highlight is Value with the matching part wrapped in <em> tags.
+
+// swagger:model SearchMatch
+type SearchMatch struct {
+	Field     string `json:"field"`
+	Member    string `json:"member,omitempty"`
+	Key       string `json:"key,omitempty"`
+	Value     string `json:"value"`
+	Highlight string `json:"highlight"`
+}
+
+// swagger:model SearchHit
+type SearchHit struct {
+	Kind    string        `json:"kind"`
+	Name    string        `json:"name"`
+	Version Version       `json:"version"`
+	Score   float64       `json:"score"`
+	Matches []SearchMatch `json:"matches"`
+}
+
+// swagger:model SearchResult
+type SearchResult struct {
+	Query string      `json:"query"`
+	Mode  SearchMode  `json:"mode"`
+	Total int         `json:"total"`
+	Hits  []SearchHit `json:"hits"`
+}
+
+// Highlight wraps the first case insensitive occurrence of text in value in <em> tags. It compares rune windows of
+// value itself, case folding may change the byte length of a rune so offsets into a lowered copy do not fit value.
+func Highlight(value string, text string) string {
+	n := utf8.RuneCountInString(text)
+	if n == 0 {
+		return value
+	}
+	for i := range value {
+		end := i
+		for j := 0; j < n && end < len(value); j++ {
+			_, size := utf8.DecodeRuneInString(value[end:])
+			end += size
+		}
+		if strings.EqualFold(value[i:end], text) {
+			return value[:i] + "<em>" + value[i:end] + "</em>" + value[end:]
+		}
+		if end == len(value) {
+			break
+		}
+	}
+	return value
+}
@@ -0,0 +1,189 @@
+package model
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+type SelectorOperator string
+
+const (
+	SelectEquals       SelectorOperator = "="
+	SelectNotEquals    SelectorOperator = "!="
+	SelectIn           SelectorOperator = "in"
+	SelectNotIn        SelectorOperator = "notin"
+	SelectExists       SelectorOperator = "exists"
+	SelectDoesNotExist SelectorOperator = "!"
+)
+
+// Requirement is a single term of a selector, Values holds the value of = and != and the set of in and notin.
+type Requirement struct {
+	Key      string
+	Operator SelectorOperator
+	Values   []string
+}
+
+// Selector is a label query, a comma separated list of requirements that must all hold, as in
+// env=prod,region in (eu,us),!canary. A bare key requires the label to exist.
+type Selector []Requirement
+
+var setRequirement = regexp.MustCompile(`^(\S+)\s+(in|notin)\s*\((.*)\)$`)
+
+func ParseSelector(selector string) (Selector, error) {
+	terms, err := splitSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed := make(Selector, 0, len(terms))
+	for _, term := range terms {
+		r, err := parseRequirement(term)
+		if err != nil {
+			return nil, fmt.Errorf("selector %q: %s: %w", selector, err.Error(), ErrInvalid)
+		}
+		parsed = append(parsed, r)
+	}
+	return parsed, nil
+}
+
+// splitSelector splits on the commas that are not inside a set.
+func splitSelector(selector string) ([]string, error) {
+	var terms []string
+	depth, start := 0, 0
+	for i, c := range selector {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				terms = append(terms, strings.TrimSpace(selector[start:i]))
+				start = i + 1
+			}
+		}
+		if depth < 0 || depth > 1 {
+			return nil, fmt.Errorf("selector %q has unbalanced parentheses: %w", selector, ErrInvalid)
+		}
+	}
+	if depth != 0 {
+		return nil, fmt.Errorf("selector %q has unbalanced parentheses: %w", selector, ErrInvalid)
+	}
+	terms = append(terms, strings.TrimSpace(selector[start:]))
+
+	for _, term := range terms {
+		if term == "" {
+			return nil, fmt.Errorf("selector %q has an empty requirement: %w", selector, ErrInvalid)
+		}
+	}
+	return terms, nil
+}
+
+func parseRequirement(term string) (Requirement, error) {
+	var r Requirement
+	switch {
+	case strings.HasPrefix(term, "!") && !strings.Contains(term, "="):
+		r = Requirement{Key: strings.TrimSpace(term[1:]), Operator: SelectDoesNotExist}
+	case setRequirement.MatchString(term):
+		m := setRequirement.FindStringSubmatch(term)
+		r = Requirement{Key: m[1], Operator: SelectorOperator(m[2])}
+		for _, v := range strings.Split(m[3], ",") {
+			r.Values = append(r.Values, strings.TrimSpace(v))
+		}
+	case strings.Contains(term, "!="):
+		k, v, _ := strings.Cut(term, "!=")
+		r = Requirement{Key: strings.TrimSpace(k), Operator: SelectNotEquals, Values: []string{strings.TrimSpace(v)}}
+	case strings.Contains(term, "="):
+		k, v, _ := strings.Cut(term, "=")
+		v = strings.TrimPrefix(v, "=")
+		r = Requirement{Key: strings.TrimSpace(k), Operator: SelectEquals, Values: []string{strings.TrimSpace(v)}}
+	default:
+		r = Requirement{Key: term, Operator: SelectExists}
+	}
+
+	if err := validateLabelKey(r.Key); err != nil {
+		return r, fmt.Errorf("key %q: %s", r.Key, err.Error())
+	}
+	for _, v := range r.Values {
+		if v != "" && !validLabelName(v) {
+			return r, fmt.Errorf("value %q of %s is not a valid label value", v, r.Key)
+		}
+	}
+	return r, nil
+}
+
+func (r Requirement) Matches(labels map[string]string) bool {
+	value, ok := labels[r.Key]
+	switch r.Operator {
+	case SelectEquals:
+		return ok && value == r.Values[0]
+	case SelectNotEquals:
+		return !ok || value != r.Values[0]
+	case SelectIn:
+		return ok && contains(r.Values, value)
+	case SelectNotIn:
+		return !ok || !contains(r.Values, value)
+	case SelectExists:
+		return ok
+	case SelectDoesNotExist:
+		return !ok
+	}
+	return false
+}
+
+// Positive reports whether the requirement can only hold for objects that have the label, those are the
+// requirements a label index can answer.
+func (r Requirement) Positive() bool {
+	return r.Operator == SelectEquals || r.Operator == SelectIn || r.Operator == SelectExists
+}
+
+func (s Selector) Matches(labels map[string]string) bool {
+	for _, r := range s {
+		if !r.Matches(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+// SelectMembers returns a copy of group holding only the members whose labels match.
+func (s Selector) SelectMembers(group *ConfigurationGroup) *ConfigurationGroup {
+	selected := *group
+	selected.Configurations = nil
+	for _, c := range group.Configurations {
+		if s.Matches(c.Labels) {
+			selected.Configurations = append(selected.Configurations, c)
+		}
+	}
+	return &selected
+}
+
+func (s Selector) String() string {
+	terms := make([]string, 0, len(s))
+	for _, r := range s {
+		switch r.Operator {
+		case SelectEquals, SelectNotEquals:
+			terms = append(terms, r.Key+string(r.Operator)+r.Values[0])
+		case SelectIn, SelectNotIn:
+			values := append([]string(nil), r.Values...)
+			sort.Strings(values)
+			terms = append(terms, fmt.Sprintf("%s %s (%s)", r.Key, r.Operator, strings.Join(values, ",")))
+		case SelectExists:
+			terms = append(terms, r.Key)
+		case SelectDoesNotExist:
+			terms = append(terms, "!"+r.Key)
+		}
+	}
+	return strings.Join(terms, ",")
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
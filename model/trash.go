@@ -0,0 +1,34 @@
+package model
+
+import "time"
+
+// swagger:model TrashedConfiguration
+type TrashedConfiguration struct {
+	Configuration Configuration `json:"configuration"`
+	DeletedAt     time.Time     `json:"deletedAt"`
+	DeletedBy     string        `json:"deletedBy"`
+	PurgeAt       time.Time     `json:"purgeAt"`
+}
+
+// TrashedGroup holds the members removed by a single group delete, Labels is the label filter the delete used.
+// Member is set when the delete removed that one member with exactly its labels, Document when it removed the whole
+// group.
+
+// swagger:model TrashedGroup
+type TrashedGroup struct {
+	Name           string          `json:"name"`
+	Version        Version         `json:"version"`
+	Labels         string          `json:"labels"`
+	Configurations []Configuration `json:"configurations"`
+	Member         *GroupMember    `json:"member,omitempty"`
+	DeletedAt      time.Time       `json:"deletedAt"`
+	DeletedBy      string          `json:"deletedBy"`
+	PurgeAt        time.Time       `json:"purgeAt"`
+	Document       *GroupDocument  `json:"document,omitempty"`
+}
+
+// swagger:model Trash
+type Trash struct {
+	Configurations []TrashedConfiguration `json:"configurations"`
+	Groups         []TrashedGroup         `json:"groups"`
+}
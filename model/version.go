@@ -56,6 +56,19 @@ func ToVersion(version string) (*Version, error) {
 	return &versionModel, nil
 }
 
+// CompareVersions returns -1 if a is lower than b, 1 if a is higher and 0 if they are equal.
+func CompareVersions(a Version, b Version) int {
+	for _, pair := range [][2]int{{a.Major, b.Major}, {a.Minor, b.Minor}, {a.Patch, b.Patch}} {
+		if pair[0] < pair[1] {
+			return -1
+		}
+		if pair[0] > pair[1] {
+			return 1
+		}
+	}
+	return 0
+}
+
 type VersionRepository interface {
 	Delete()
 	Update()
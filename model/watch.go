@@ -0,0 +1,15 @@
+package model
+
+// ConfigChanges is what a watch of the configs keyspace saw after the index it waited on. Changed holds the
+// configurations written since, Present names every stored configuration, indexed objects missing from it were
+// deleted.
+type ConfigChanges struct {
+	Changed []Configuration
+	Present []ObjectRef
+}
+
+// GroupChanges is ConfigChanges for groups, a group changed when its document or one of its members did.
+type GroupChanges struct {
+	Changed []ConfigurationGroup
+	Present []ObjectRef
+}
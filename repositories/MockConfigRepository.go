@@ -3,6 +3,7 @@ package repositories
 import (
 	"ars_projekat/model"
 	"context"
+	"time"
 
 	"github.com/stretchr/testify/mock"
 )
@@ -25,13 +26,8 @@ func (m *MockConfigRepository) GetById(name string, version string, ctx context.
 	return args.Get(0).(*model.Configuration), args.Error(1)
 }
 
-func (m *MockConfigRepository) Delete(name string, version string, ctx context.Context) error {
-	args := m.Called(name, version, ctx)
-	return args.Error(0)
-}
-
-func (m *MockConfigRepository) Add(config *model.Configuration, ctx context.Context) (*model.Configuration, error) {
-	args := m.Called(config, ctx)
+func (m *MockConfigRepository) Add(config *model.Configuration, rev *model.ConfigurationRevision, ctx context.Context) (*model.Configuration, error) {
+	args := m.Called(config, rev, ctx)
 	return args.Get(0).(*model.Configuration), args.Error(1)
 }
 
@@ -45,18 +41,13 @@ func (m *MockConfigRepository) GetGroupByParams(name string, version string, lab
 	return args.Get(0).(*model.ConfigurationGroup), args.Error(1)
 }
 
-func (m *MockConfigRepository) AddGroup(name string, version string, labels string, configs model.Configuration, ctx context.Context) error {
-	args := m.Called(name, version, labels, configs, ctx)
+func (m *MockConfigRepository) ReplaceGroup(name string, version string, configs []model.Configuration, includes []model.GroupInclude, rev *model.GroupRevision, ctx context.Context) error {
+	args := m.Called(name, version, configs, includes, rev, ctx)
 	return args.Error(0)
 }
 
-func (m *MockConfigRepository) DeleteGroupById(name string, version string, ctx context.Context) error {
-	args := m.Called(name, version, ctx)
-	return args.Error(0)
-}
-
-func (m *MockConfigRepository) DeleteGroupByParams(name string, version string, labels string, ctx context.Context) error {
-	args := m.Called(name, version, labels, ctx)
+func (m *MockConfigRepository) ReplaceGroupMember(name string, version string, previous model.Configuration, config model.Configuration, rev *model.GroupRevision, ctx context.Context) error {
+	args := m.Called(name, version, previous, config, rev, ctx)
 	return args.Error(0)
 }
 
@@ -69,3 +60,173 @@ func (m *MockConfigRepository) AddIdempotencyRequest(req *model.IdempotencyReque
 	args := m.Called(req, ctx)
 	return args.Get(0).(*model.IdempotencyRequest), args.Error(1)
 }
+
+func (m *MockConfigRepository) GetConfigRevisions(name string, version string, ctx context.Context) ([]model.ConfigurationRevision, error) {
+	args := m.Called(name, version, ctx)
+	return args.Get(0).([]model.ConfigurationRevision), args.Error(1)
+}
+
+func (m *MockConfigRepository) GetConfigRevision(name string, version string, number int64, ctx context.Context) (*model.ConfigurationRevision, error) {
+	args := m.Called(name, version, number, ctx)
+	return args.Get(0).(*model.ConfigurationRevision), args.Error(1)
+}
+
+func (m *MockConfigRepository) GetGroupRevisions(name string, version string, ctx context.Context) ([]model.GroupRevision, error) {
+	args := m.Called(name, version, ctx)
+	return args.Get(0).([]model.GroupRevision), args.Error(1)
+}
+
+func (m *MockConfigRepository) GetGroupRevision(name string, version string, number int64, ctx context.Context) (*model.GroupRevision, error) {
+	args := m.Called(name, version, number, ctx)
+	return args.Get(0).(*model.GroupRevision), args.Error(1)
+}
+
+func (m *MockConfigRepository) AddRetentionPolicy(policy *model.RetentionPolicy, ctx context.Context) error {
+	args := m.Called(policy, ctx)
+	return args.Error(0)
+}
+
+func (m *MockConfigRepository) GetRetentionPolicies(ctx context.Context) ([]model.RetentionPolicy, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]model.RetentionPolicy), args.Error(1)
+}
+
+func (m *MockConfigRepository) GetRetentionPolicy(name string, ctx context.Context) (*model.RetentionPolicy, error) {
+	args := m.Called(name, ctx)
+	return args.Get(0).(*model.RetentionPolicy), args.Error(1)
+}
+
+func (m *MockConfigRepository) DeleteRetentionPolicy(name string, ctx context.Context) error {
+	args := m.Called(name, ctx)
+	return args.Error(0)
+}
+
+func (m *MockConfigRepository) TrashConfig(trashed *model.TrashedConfiguration, rev *model.ConfigurationRevision, ctx context.Context) error {
+	args := m.Called(trashed, rev, ctx)
+	return args.Error(0)
+}
+
+func (m *MockConfigRepository) TrashGroup(trashed *model.TrashedGroup, rev *model.GroupRevision, ctx context.Context) error {
+	args := m.Called(trashed, rev, ctx)
+	return args.Error(0)
+}
+
+func (m *MockConfigRepository) GetTrashedConfig(name string, version string, ctx context.Context) (*model.TrashedConfiguration, error) {
+	args := m.Called(name, version, ctx)
+	return args.Get(0).(*model.TrashedConfiguration), args.Error(1)
+}
+
+func (m *MockConfigRepository) GetTrashedGroups(name string, version string, ctx context.Context) ([]model.TrashedGroup, error) {
+	args := m.Called(name, version, ctx)
+	return args.Get(0).([]model.TrashedGroup), args.Error(1)
+}
+
+func (m *MockConfigRepository) GetTrash(ctx context.Context) (*model.Trash, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(*model.Trash), args.Error(1)
+}
+
+func (m *MockConfigRepository) RestoreConfig(config *model.Configuration, rev *model.ConfigurationRevision, ctx context.Context) error {
+	args := m.Called(config, rev, ctx)
+	return args.Error(0)
+}
+
+func (m *MockConfigRepository) RestoreGroup(name string, version string, configs []model.Configuration, rev *model.GroupRevision, ctx context.Context) error {
+	args := m.Called(name, version, configs, rev, ctx)
+	return args.Error(0)
+}
+
+func (m *MockConfigRepository) PurgeConfig(name string, version string, ctx context.Context) error {
+	args := m.Called(name, version, ctx)
+	return args.Error(0)
+}
+
+func (m *MockConfigRepository) PurgeGroup(name string, version string, ctx context.Context) error {
+	args := m.Called(name, version, ctx)
+	return args.Error(0)
+}
+
+func (m *MockConfigRepository) AddSchema(schema *model.Schema, ctx context.Context) error {
+	args := m.Called(schema, ctx)
+	return args.Error(0)
+}
+
+func (m *MockConfigRepository) GetSchemas(ctx context.Context) ([]model.Schema, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]model.Schema), args.Error(1)
+}
+
+func (m *MockConfigRepository) GetSchemaVersions(name string, ctx context.Context) ([]model.Schema, error) {
+	args := m.Called(name, ctx)
+	return args.Get(0).([]model.Schema), args.Error(1)
+}
+
+func (m *MockConfigRepository) GetSchema(name string, version int64, ctx context.Context) (*model.Schema, error) {
+	args := m.Called(name, version, ctx)
+	return args.Get(0).(*model.Schema), args.Error(1)
+}
+
+func (m *MockConfigRepository) DeleteSchema(name string, ctx context.Context) error {
+	args := m.Called(name, ctx)
+	return args.Error(0)
+}
+
+func (m *MockConfigRepository) GetVersions(name string, ctx context.Context) ([]model.Configuration, error) {
+	args := m.Called(name, ctx)
+	return args.Get(0).([]model.Configuration), args.Error(1)
+}
+
+func (m *MockConfigRepository) ListConfigRefs(prefix string, ctx context.Context) ([]model.ObjectRef, error) {
+	args := m.Called(prefix, ctx)
+	return args.Get(0).([]model.ObjectRef), args.Error(1)
+}
+
+func (m *MockConfigRepository) ListGroupRefs(prefix string, ctx context.Context) ([]model.ObjectRef, error) {
+	args := m.Called(prefix, ctx)
+	return args.Get(0).([]model.ObjectRef), args.Error(1)
+}
+
+func (m *MockConfigRepository) ListIndexedObjects(kind string, prefix string, ctx context.Context) ([]model.IndexedObject, error) {
+	args := m.Called(kind, prefix, ctx)
+	return args.Get(0).([]model.IndexedObject), args.Error(1)
+}
+
+func (m *MockConfigRepository) FindByLabel(kind string, key string, values []string, ctx context.Context) ([]model.ObjectRef, error) {
+	args := m.Called(kind, key, values, ctx)
+	return args.Get(0).([]model.ObjectRef), args.Error(1)
+}
+
+func (m *MockConfigRepository) FindSourceMembers(source string, ctx context.Context) ([]model.ConfigurationGroup, error) {
+	args := m.Called(source, ctx)
+	return args.Get(0).([]model.ConfigurationGroup), args.Error(1)
+}
+
+func (m *MockConfigRepository) FindIncluders(name string, version string, ctx context.Context) ([]model.ConfigurationGroup, error) {
+	args := m.Called(name, version, ctx)
+	return args.Get(0).([]model.ConfigurationGroup), args.Error(1)
+}
+
+func (m *MockConfigRepository) WatchConfigs(waitIndex uint64, wait time.Duration, ctx context.Context) (*model.ConfigChanges, uint64, error) {
+	args := m.Called(waitIndex, wait, ctx)
+	return args.Get(0).(*model.ConfigChanges), args.Get(1).(uint64), args.Error(2)
+}
+
+func (m *MockConfigRepository) WatchGroups(waitIndex uint64, wait time.Duration, ctx context.Context) (*model.GroupChanges, uint64, error) {
+	args := m.Called(waitIndex, wait, ctx)
+	return args.Get(0).(*model.GroupChanges), args.Get(1).(uint64), args.Error(2)
+}
+
+func (m *MockConfigRepository) GetObjectRef(id int64, ctx context.Context) (*model.ObjectRef, error) {
+	args := m.Called(id, ctx)
+	return args.Get(0).(*model.ObjectRef), args.Error(1)
+}
+
+func (m *MockConfigRepository) GetGroupDocument(name string, version string, ctx context.Context) (*model.GroupDocument, error) {
+	args := m.Called(name, version, ctx)
+	return args.Get(0).(*model.GroupDocument), args.Error(1)
+}
+
+func (m *MockConfigRepository) SaveGroup(doc *model.GroupDocument, configs []model.Configuration, rev *model.GroupRevision, ctx context.Context) error {
+	args := m.Called(doc, configs, rev, ctx)
+	return args.Error(0)
+}
@@ -2,10 +2,12 @@ package repositories
 
 import (
 	"ars_projekat/model"
+	"bytes"
 	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"go.opentelemetry.io/otel/trace"
 	"log"
@@ -66,6 +68,32 @@ func (cr *ConfigRepository) GetAll(ctx context.Context) ([]model.Configuration,
 	return configurations, nil
 }
 
+// GetVersions returns every stored version of the configuration with the given name.
+func (cr *ConfigRepository) GetVersions(name string, ctx context.Context) ([]model.Configuration, error) {
+	_, span := cr.Tracer.Start(ctx, "ConfigRepository.GetVersions")
+	defer span.End()
+	kv := cr.cli.KV()
+	data, _, err := kv.List(ConstructConfigNamePrefix(name), nil)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	var configurations []model.Configuration
+	for _, pair := range data {
+		configuration := &model.Configuration{}
+		err = json.Unmarshal(pair.Value, configuration)
+		if err != nil {
+			span.SetStatus(codes.Error, err.Error())
+			return nil, err
+		}
+		configurations = append(configurations, *configuration)
+	}
+
+	span.SetStatus(codes.Ok, "Success fetching configuration versions")
+	return configurations, nil
+}
+
 func (cr *ConfigRepository) GetById(name string, version string, ctx context.Context) (*model.Configuration, error) {
 	_, span := cr.Tracer.Start(ctx, "ConfigRepository.GetById")
 	defer span.End()
@@ -73,7 +101,7 @@ func (cr *ConfigRepository) GetById(name string, version string, ctx context.Con
 	kv := cr.cli.KV()
 	data, _, err := kv.Get(ConstructConfigKey(name, version), nil)
 	if data == nil {
-		return nil, errors.New("not found")
+		return nil, model.ErrNotFound
 	}
 	if err != nil {
 		span.SetStatus(codes.Error, err.Error())
@@ -91,27 +119,11 @@ func (cr *ConfigRepository) GetById(name string, version string, ctx context.Con
 	return configuration, nil
 }
 
-func (cr *ConfigRepository) Delete(name string, version string, ctx context.Context) error {
-	_, span := cr.Tracer.Start(ctx, "ConfigRepository.Delete")
-	defer span.End()
-
-	kv := cr.cli.KV()
-
-	_, err := kv.Delete(ConstructConfigKey(name, version), nil)
-	if err != nil {
-		span.SetStatus(codes.Error, err.Error())
-		return err
-	}
-
-	span.SetStatus(codes.Ok, "Success deleting configuration")
-	return nil
-}
-
-func (cr *ConfigRepository) Add(config *model.Configuration, ctx context.Context) (*model.Configuration, error) {
+// Add stores config, rev is stored as its next revision in the same transaction when it is not nil.
+func (cr *ConfigRepository) Add(config *model.Configuration, rev *model.ConfigurationRevision, ctx context.Context) (*model.Configuration, error) {
 	_, span := cr.Tracer.Start(ctx, "ConfigRepository.Add")
 	defer span.End()
 
-	kv := cr.cli.KV()
 	version := model.ToString(config.Version)
 
 	data, err := json.Marshal(config)
@@ -120,12 +132,48 @@ func (cr *ConfigRepository) Add(config *model.Configuration, ctx context.Context
 		return nil, err
 	}
 
-	keyValue := &api.KVPair{Key: ConstructConfigKey(config.Name, version), Value: data}
-	_, err = kv.Put(keyValue, nil)
+	previous, _, err := cr.cli.KV().Get(ConstructConfigKey(config.Name, version), nil)
 	if err != nil {
 		span.SetStatus(codes.Error, err.Error())
 		return nil, err
 	}
+	var stale []string
+	if previous != nil {
+		old := model.Configuration{}
+		if err = json.Unmarshal(previous.Value, &old); err != nil {
+			span.SetStatus(codes.Error, err.Error())
+			return nil, err
+		}
+		stale = configIndexKeys(old)
+	}
+	indexed := configIndexKeys(*config)
+	if err = cr.addIndex(indexed); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	// The watch stamp, ID and update time index entries are written in the same transaction, configurations stored
+	// before IDs existed have none
+	ops := api.TxnOps{
+		setOp(ConstructConfigKey(config.Name, version), data),
+		setOp(ConstructWatchKey(model.KindConfig, config.Name, version), nil),
+	}
+	if config.Id != 0 {
+		idOps, err := indexOps(config.Id, model.ObjectRef{Kind: model.KindConfig, Name: config.Name, Version: config.Version}, config.UpdatedAt)
+		if err != nil {
+			span.SetStatus(codes.Error, err.Error())
+			return nil, err
+		}
+		ops = append(ops, idOps...)
+	}
+	if err = cr.commitConfigWrite(config.Name, version, ops, rev); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	if err = cr.removeIndex(stale, indexed); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
 
 	span.SetStatus(codes.Ok, "Successfully added Configuration")
 	return config, nil
@@ -137,57 +185,109 @@ func (cr *ConfigRepository) GetAllGroups(ctx context.Context) ([]model.Configura
 	defer span.End()
 
 	kv := cr.cli.KV()
-	data, _, err := kv.List(allGroups, nil)
+	data, _, err := kv.List(groupKeys, nil)
 	if err != nil {
 		span.SetStatus(codes.Error, err.Error())
 		return nil, err
 	}
 
+	groups, err := groupsFromPairs(data)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	span.SetStatus(codes.Ok, "Success fetching all config groups")
+	return groups, nil
+}
+
+// groupsFromPairs builds a group from every group document and adds the members the document lists, member keys
+// are groups/{escaped name}/{version}/[{labels}/]{config}. Other keys are skipped.
+func groupsFromPairs(pairs api.KVPairs) ([]model.ConfigurationGroup, error) {
 	var groups []model.ConfigurationGroup
-	for _, pair := range data {
-		cg := &model.ConfigurationGroup{}
-		err = json.Unmarshal(pair.Value, cg)
-		if err != nil {
-			span.SetStatus(codes.Error, err.Error())
+	docs := make(map[string]*model.GroupDocument)
+	index := make(map[string]int)
+	for _, pair := range pairs {
+		if !strings.HasPrefix(pair.Key, groupDocumentFolder) {
+			continue
+		}
+		doc := &model.GroupDocument{}
+		if err := json.Unmarshal(pair.Value, doc); err != nil {
 			return nil, err
 		}
-		groups = append(groups, *cg)
+		groupKey := escapeName(doc.Name) + "/" + model.ToString(doc.Version)
+		docs[groupKey] = doc
+		index[groupKey] = len(groups)
+		groups = append(groups, model.ConfigurationGroup{
+			Name:           doc.Name,
+			Id:             doc.Id,
+			Version:        doc.Version,
+			Configurations: []model.Configuration{},
+			Includes:       doc.Includes,
+			Metadata:       doc.Metadata,
+		})
 	}
 
-	span.SetStatus(codes.Ok, "Success fetching all config groups")
+	for _, pair := range pairs {
+		segments := strings.Split(pair.Key, "/")
+		if segments[0] != allGroups || len(segments) < 4 {
+			continue
+		}
+		groupKey := segments[1] + "/" + segments[2]
+		doc, ok := docs[groupKey]
+		if !ok {
+			continue
+		}
+		config := model.Configuration{}
+		if err := json.Unmarshal(pair.Value, &config); err != nil {
+			return nil, err
+		}
+		if doc.HasMember(model.MemberOf(config)) {
+			config.Binding = config.ComputeBinding()
+			i := index[groupKey]
+			groups[i].Configurations = append(groups[i].Configurations, config)
+		}
+	}
 	return groups, nil
 }
 
+// GetGroupByParams returns the group with the members whose labels include every label encoded in labels. It returns
+// nil when the group has no document, or when labels are given and no member matches them.
 func (cr *ConfigRepository) GetGroupByParams(name string, version string, labels string, ctx context.Context) (*model.ConfigurationGroup, error) {
 	_, span := cr.Tracer.Start(ctx, "ConfigGroupRepository.GetGroupByParams")
 	defer span.End()
 
 	kv := cr.cli.KV()
 
-	var key string
-	if len(labels) == 0 {
-		key = ConstructConfigGroupKey(name, version, "", "")
-	} else {
-		key = ConstructConfigGroupKey(name, version, labels, "")
-	}
-
-	data, _, err := kv.List(key, nil)
-	if data == nil {
+	docPair, _, err := kv.Get(ConstructGroupDocumentKey(name, version), nil)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
 		return nil, err
 	}
-	if err != nil {
+	if docPair == nil {
+		return nil, nil
+	}
+	doc := &model.GroupDocument{}
+	if err = json.Unmarshal(docPair.Value, doc); err != nil {
 		span.SetStatus(codes.Error, err.Error())
 		return nil, err
 	}
-	cg := &model.ConfigurationGroup{}
-	cg.Name = name
-	ver, err := model.ToVersion(version)
+
+	data, _, err := kv.List(ConstructConfigGroupKey(name, version, "", ""), nil)
 	if err != nil {
 		span.SetStatus(codes.Error, err.Error())
 		return nil, err
 	}
-	cg.Version = *ver
 
+	cg := &model.ConfigurationGroup{
+		Name:           name,
+		Id:             doc.Id,
+		Version:        doc.Version,
+		Configurations: []model.Configuration{},
+		Includes:       doc.Includes,
+		Metadata:       doc.Metadata,
+	}
+	wanted := model.ParseLabels(labels)
 	for _, pair := range data {
 		config := &model.Configuration{}
 		err = json.Unmarshal(pair.Value, config)
@@ -195,74 +295,169 @@ func (cr *ConfigRepository) GetGroupByParams(name string, version string, labels
 			span.SetStatus(codes.Error, err.Error())
 			return nil, err
 		}
-		cg.Configurations = append(cg.Configurations, *config)
+		if doc.HasMember(model.MemberOf(*config)) && config.Labels.Includes(wanted) {
+			config.Binding = config.ComputeBinding()
+			cg.Configurations = append(cg.Configurations, *config)
+		}
+	}
+	if labels != "" && len(cg.Configurations) == 0 {
+		return nil, nil
 	}
 
 	span.SetStatus(codes.Ok, "Success fetching group by parameters")
 	return cg, nil
 }
 
-func (cr *ConfigRepository) AddGroup(name string, version string, labels string, configs model.Configuration, ctx context.Context) error {
-	_, span := cr.Tracer.Start(ctx, "ConfigGroupRepository.AddGroup")
+// ReplaceGroup replaces all members and the includes of the group version with configs and includes, rev is
+// recorded in the same update. Members that keep their key and value are not rewritten, so the update splits around
+// the document like any other large group write.
+func (cr *ConfigRepository) ReplaceGroup(name string, version string, configs []model.Configuration, includes []model.GroupInclude, rev *model.GroupRevision, ctx context.Context) error {
+	_, span := cr.Tracer.Start(ctx, "ConfigGroupRepository.ReplaceGroup")
 	defer span.End()
 
-	kv := cr.cli.KV()
-
-	data, err := json.Marshal(configs)
+	previous, err := cr.listMembers(ConstructConfigGroupKey(name, version, "", ""))
 	if err != nil {
 		span.SetStatus(codes.Error, err.Error())
 		return err
 	}
-
-	keyValue := &api.KVPair{Key: ConstructConfigGroupKey(name, version, labels, configs.Name), Value: data}
-	_, err = kv.Put(keyValue, nil)
-	if err != nil {
+	indexed := membersIndexKeys(name, version, configs)
+	included := includeIndexKeys(name, version, includes)
+	if err = cr.addIndex(append(indexed, included...)); err != nil {
 		span.SetStatus(codes.Error, err.Error())
 		return err
 	}
 
-	span.SetStatus(codes.Ok, "Successfully added group")
-	return nil
-}
-
-func (cr *ConfigRepository) DeleteGroupById(name string, version string, ctx context.Context) error {
-	_, span := cr.Tracer.Start(ctx, "ConfigGroupRepository.DeleteGroupById")
-	defer span.End()
-
 	kv := cr.cli.KV()
-
-	_, err := kv.Delete(ConstructConfigKey(name, version), nil)
+	var replaced []model.GroupInclude
+	err = cr.updateGroupDocument(name, version, rev, func(doc *model.GroupDocument, _ bool) (api.TxnOps, bool, error) {
+		// Listed again on every attempt, every member write updates the document so a retry sees fresh values.
+		stored, _, err := kv.List(ConstructConfigGroupKey(name, version, "", ""), nil)
+		if err != nil {
+			return nil, false, err
+		}
+		ops, members, err := replaceMembersOps(name, version, stored, configs)
+		if err != nil {
+			return nil, false, err
+		}
+		replaced = doc.Includes
+		doc.Members = nil
+		doc.Includes = includes
+		for _, member := range members {
+			doc.AddMember(member)
+		}
+		return ops, true, nil
+	})
 	if err != nil {
+		cr.releaseIndexAfter(name, version, indexed)
+		cr.releaseIncludesAfter(name, version, included)
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	if err = cr.removeIndex(membersIndexKeys(name, version, previous), indexed); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	if err = cr.removeIndex(includeIndexKeys(name, version, replaced), included); err != nil {
 		span.SetStatus(codes.Error, err.Error())
 		return err
 	}
 
-	span.SetStatus(codes.Ok, "Successfully deleted configuration group")
+	span.SetStatus(codes.Ok, "Successfully replaced configuration group")
 	return nil
 }
 
-func (cr *ConfigRepository) DeleteGroupByParams(name string, version string, labels string, ctx context.Context) error {
-	_, span := cr.Tracer.Start(ctx, "ConfigGroupRepository.DeleteGroupByParams")
-	defer span.End()
+// replaceMembersOps returns the ops that turn the stored member keys of the group version into configs and the
+// members of configs. Members already stored with the same value get no op, stored keys configs does not have are
+// deleted one by one so member writes can be committed ahead of the document.
+func replaceMembersOps(name string, version string, stored api.KVPairs, configs []model.Configuration) (api.TxnOps, []model.GroupMember, error) {
+	values := make(map[string][]byte, len(stored))
+	for _, pair := range stored {
+		values[pair.Key] = pair.Value
+	}
+	ops := make(api.TxnOps, 0, len(configs))
+	members := make([]model.GroupMember, 0, len(configs))
+	kept := make(map[string]bool, len(configs))
+	for _, config := range configs {
+		data, err := json.Marshal(config)
+		if err != nil {
+			return nil, nil, err
+		}
+		member := model.MemberOf(config)
+		key := ConstructConfigGroupKey(name, version, member.Labels, config.Name)
+		if value, ok := values[key]; !ok || !bytes.Equal(value, data) {
+			ops = append(ops, setOp(key, data))
+		}
+		kept[key] = true
+		members = append(members, member)
+	}
+	for _, pair := range stored {
+		if !kept[pair.Key] {
+			ops = append(ops, deleteOp(pair.Key))
+		}
+	}
+	return ops, members, nil
+}
 
-	kv := cr.cli.KV()
+// ReplaceGroupMember swaps one member of a group version for config in one transaction, config may change the
+// member's labels. It fails with model.ErrNotFound when the group does not list previous, and with
+// model.ErrAlreadyExists when config would take the place of another member.
+func (cr *ConfigRepository) ReplaceGroupMember(name string, version string, previous model.Configuration, config model.Configuration, rev *model.GroupRevision, ctx context.Context) error {
+	_, span := cr.Tracer.Start(ctx, "ConfigGroupRepository.ReplaceGroupMember")
+	defer span.End()
 
-	var key string
-	if len(labels) == 0 {
-		key = ConstructConfigGroupKey(name, version, "", "")
-	} else {
-		key = ConstructConfigGroupKey(name, version, labels, "")
+	data, err := json.Marshal(config)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return err
 	}
-	_, err := kv.DeleteTree(key, nil)
+	indexed := memberIndexKeys(name, version, config)
+	if err = cr.addIndex(indexed); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+
+	old, member := model.MemberOf(previous), model.MemberOf(config)
+	err = cr.updateGroupDocument(name, version, rev, func(doc *model.GroupDocument, exists bool) (api.TxnOps, bool, error) {
+		if !exists || !doc.HasMember(old) {
+			return nil, false, fmt.Errorf("member %s of group %s %s %w", previous.Name, name, version, model.ErrNotFound)
+		}
+		if member != old && doc.HasMember(member) {
+			return nil, false, fmt.Errorf("member %s of group %s %s with labels %q %w", config.Name, name, version, member.Labels, model.ErrAlreadyExists)
+		}
+		doc.RemoveMember(old)
+		doc.AddMember(member)
+		ops := api.TxnOps{setOp(ConstructConfigGroupKey(name, version, member.Labels, config.Name), data)}
+		if member != old {
+			ops = append(ops, deleteOp(ConstructConfigGroupKey(name, version, old.Labels, previous.Name)))
+		}
+		return ops, true, nil
+	})
 	if err != nil {
+		cr.releaseIndexAfter(name, version, indexed)
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	if err = cr.removeIndex(memberIndexKeys(name, version, previous), indexed); err != nil {
 		span.SetStatus(codes.Error, err.Error())
 		return err
 	}
 
-	span.SetStatus(codes.Ok, "Successfully deleted configuration group")
+	span.SetStatus(codes.Ok, "Successfully replaced group member")
 	return nil
 }
 
+// deleteMembersOps deletes the given members, or every key of the group version when the whole group goes.
+func deleteMembersOps(name string, version string, whole bool, configs []model.Configuration) api.TxnOps {
+	if whole {
+		return api.TxnOps{deleteTreeOp(ConstructConfigGroupKey(name, version, "", ""))}
+	}
+	ops := make(api.TxnOps, 0, len(configs))
+	for _, config := range configs {
+		ops = append(ops, deleteOp(ConstructConfigGroupKey(name, version, model.SortLabels(config.Labels), config.Name)))
+	}
+	return ops
+}
+
 func (cr *ConfigRepository) GetIdempotencyRequestByKey(key string, ctx context.Context) (bool, error) {
 	_, span := cr.Tracer.Start(ctx, "Repository.IdempotencyRequest")
 	defer span.End()
@@ -308,13 +503,45 @@ func (cr *ConfigRepository) AddIdempotencyRequest(req *model.IdempotencyRequest,
 type IConfigRepository interface {
 	GetAll(ctx context.Context) ([]model.Configuration, error)
 	GetById(name string, version string, ctx context.Context) (*model.Configuration, error)
-	Delete(name string, version string, ctx context.Context) error
-	Add(config *model.Configuration, ctx context.Context) (*model.Configuration, error)
+	GetVersions(name string, ctx context.Context) ([]model.Configuration, error)
+	ListConfigRefs(prefix string, ctx context.Context) ([]model.ObjectRef, error)
+	ListGroupRefs(prefix string, ctx context.Context) ([]model.ObjectRef, error)
+	ListIndexedObjects(kind string, prefix string, ctx context.Context) ([]model.IndexedObject, error)
+	FindByLabel(kind string, key string, values []string, ctx context.Context) ([]model.ObjectRef, error)
+	FindSourceMembers(source string, ctx context.Context) ([]model.ConfigurationGroup, error)
+	FindIncluders(name string, version string, ctx context.Context) ([]model.ConfigurationGroup, error)
+	WatchConfigs(waitIndex uint64, wait time.Duration, ctx context.Context) (*model.ConfigChanges, uint64, error)
+	WatchGroups(waitIndex uint64, wait time.Duration, ctx context.Context) (*model.GroupChanges, uint64, error)
+	GetObjectRef(id int64, ctx context.Context) (*model.ObjectRef, error)
+	GetGroupDocument(name string, version string, ctx context.Context) (*model.GroupDocument, error)
+	SaveGroup(doc *model.GroupDocument, configs []model.Configuration, rev *model.GroupRevision, ctx context.Context) error
+	Add(config *model.Configuration, rev *model.ConfigurationRevision, ctx context.Context) (*model.Configuration, error)
 	GetAllGroups(ctx context.Context) ([]model.ConfigurationGroup, error)
 	GetGroupByParams(name string, version string, labels string, ctx context.Context) (*model.ConfigurationGroup, error)
-	AddGroup(name string, version string, labels string, configs model.Configuration, ctx context.Context) error
-	DeleteGroupById(name string, version string, ctx context.Context) error
-	DeleteGroupByParams(name string, version string, labels string, ctx context.Context) error
+	ReplaceGroup(name string, version string, configs []model.Configuration, includes []model.GroupInclude, rev *model.GroupRevision, ctx context.Context) error
+	ReplaceGroupMember(name string, version string, previous model.Configuration, config model.Configuration, rev *model.GroupRevision, ctx context.Context) error
 	GetIdempotencyRequestByKey(key string, ctx context.Context) (bool, error)
 	AddIdempotencyRequest(req *model.IdempotencyRequest, ctx context.Context) (*model.IdempotencyRequest, error)
+	GetConfigRevisions(name string, version string, ctx context.Context) ([]model.ConfigurationRevision, error)
+	GetConfigRevision(name string, version string, number int64, ctx context.Context) (*model.ConfigurationRevision, error)
+	GetGroupRevisions(name string, version string, ctx context.Context) ([]model.GroupRevision, error)
+	GetGroupRevision(name string, version string, number int64, ctx context.Context) (*model.GroupRevision, error)
+	AddRetentionPolicy(policy *model.RetentionPolicy, ctx context.Context) error
+	GetRetentionPolicies(ctx context.Context) ([]model.RetentionPolicy, error)
+	GetRetentionPolicy(name string, ctx context.Context) (*model.RetentionPolicy, error)
+	DeleteRetentionPolicy(name string, ctx context.Context) error
+	TrashConfig(trashed *model.TrashedConfiguration, rev *model.ConfigurationRevision, ctx context.Context) error
+	TrashGroup(trashed *model.TrashedGroup, rev *model.GroupRevision, ctx context.Context) error
+	GetTrashedConfig(name string, version string, ctx context.Context) (*model.TrashedConfiguration, error)
+	GetTrashedGroups(name string, version string, ctx context.Context) ([]model.TrashedGroup, error)
+	GetTrash(ctx context.Context) (*model.Trash, error)
+	RestoreConfig(config *model.Configuration, rev *model.ConfigurationRevision, ctx context.Context) error
+	RestoreGroup(name string, version string, configs []model.Configuration, rev *model.GroupRevision, ctx context.Context) error
+	PurgeConfig(name string, version string, ctx context.Context) error
+	PurgeGroup(name string, version string, ctx context.Context) error
+	AddSchema(schema *model.Schema, ctx context.Context) error
+	GetSchemas(ctx context.Context) ([]model.Schema, error)
+	GetSchemaVersions(name string, ctx context.Context) ([]model.Schema, error)
+	GetSchema(name string, version int64, ctx context.Context) (*model.Schema, error)
+	DeleteSchema(name string, ctx context.Context) error
 }
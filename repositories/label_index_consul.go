@@ -0,0 +1,233 @@
+package repositories
+
+import (
+	"ars_projekat/model"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// The label index maps every label to the configs and group members that carry it. Entries are added before the
+// objects they name are written and removed after, so the index may briefly name an object that no longer has a
+// label but never misses one. Readers load what the index names and check its labels.
+
+const labelIndexMigration = "label-index"
+
+func configIndexKeys(config model.Configuration) []string {
+	keys := make([]string, 0, len(config.Labels))
+	for k, v := range config.Labels {
+		keys = append(keys, ConstructConfigLabelKey(k, v, config.Name, model.ToString(config.Version)))
+	}
+	return keys
+}
+
+// memberIndexKeys returns the label index entries of a group member, and its source index entry when it reads a
+// config through a source reference.
+func memberIndexKeys(name string, version string, config model.Configuration) []string {
+	member := model.SortLabels(config.Labels) + "/" + config.Name
+	keys := make([]string, 0, len(config.Labels)+1)
+	for k, v := range config.Labels {
+		keys = append(keys, ConstructGroupLabelKey(k, v, name, version, member))
+	}
+	if config.Source != nil {
+		keys = append(keys, ConstructSourceIndexKey(config.Source.Name, name, version, member))
+	}
+	return keys
+}
+
+func membersIndexKeys(name string, version string, configs []model.Configuration) []string {
+	var keys []string
+	for _, c := range configs {
+		keys = append(keys, memberIndexKeys(name, version, c)...)
+	}
+	return keys
+}
+
+// addIndex writes index entries, in as many transactions as needed.
+func (cr *ConfigRepository) addIndex(keys []string) error {
+	ops := make(api.TxnOps, 0, len(keys))
+	for _, k := range keys {
+		ops = append(ops, setOp(k, nil))
+	}
+	return cr.commitChunks(ops)
+}
+
+// removeIndex deletes the index entries in keys that are not also in keep.
+func (cr *ConfigRepository) removeIndex(keys []string, keep []string) error {
+	kept := make(map[string]bool, len(keep))
+	for _, k := range keep {
+		kept[k] = true
+	}
+	var ops api.TxnOps
+	for _, k := range keys {
+		if !kept[k] {
+			ops = append(ops, deleteOp(k))
+		}
+	}
+	return cr.commitChunks(ops)
+}
+
+// releaseIndexAfter removes the entries in keys that no stored member of the group version carries, a write that
+// failed after indexing its members leaves them behind otherwise. The write already failed, so a failed cleanup is
+// only logged.
+func (cr *ConfigRepository) releaseIndexAfter(name string, version string, keys []string) {
+	members, err := cr.listMembers(ConstructConfigGroupKey(name, version, "", ""))
+	if err == nil {
+		err = cr.removeIndex(keys, membersIndexKeys(name, version, members))
+	}
+	if err != nil {
+		log.Printf("Removing the label index entries of unwritten members of group %s %s failed: %v", name, version, err)
+	}
+}
+
+func (cr *ConfigRepository) commitChunks(ops api.TxnOps) error {
+	for len(ops) > 0 {
+		n := min(len(ops), maxTxnOps)
+		if err := cr.commit(ops[:n]); err != nil {
+			return err
+		}
+		ops = ops[n:]
+	}
+	return nil
+}
+
+// listMembers returns the group members stored under prefix.
+func (cr *ConfigRepository) listMembers(prefix string) ([]model.Configuration, error) {
+	data, _, err := cr.cli.KV().List(prefix, nil)
+	if err != nil {
+		return nil, err
+	}
+	configs := make([]model.Configuration, 0, len(data))
+	for _, pair := range data {
+		config := model.Configuration{}
+		if err = json.Unmarshal(pair.Value, &config); err != nil {
+			return nil, err
+		}
+		configs = append(configs, config)
+	}
+	return configs, nil
+}
+
+// FindByLabel returns the configs, or the group versions with a member, that carry the label key with one of the
+// given values, or with any value when values is empty. kind is model.KindConfig or model.KindGroup.
+func (cr *ConfigRepository) FindByLabel(kind string, key string, values []string, ctx context.Context) ([]model.ObjectRef, error) {
+	_, span := cr.Tracer.Start(ctx, "ConfigRepository.FindByLabel")
+	defer span.End()
+
+	index := labelIndexConfigs
+	if kind == model.KindGroup {
+		index = labelIndexGroups
+	}
+
+	prefixes := []string{ConstructLabelIndexPrefix(index, key)}
+	if len(values) > 0 {
+		prefixes = prefixes[:0]
+		for _, v := range values {
+			prefixes = append(prefixes, ConstructLabelIndexPrefix(index, key)+escapeName(v)+"/")
+		}
+	}
+
+	// Keys are label-index/{kind}/{key}/{value}/{name}/{version}[/{member}]
+	kv := cr.cli.KV()
+	var refs []model.ObjectRef
+	seen := make(map[string]bool)
+	for _, prefix := range prefixes {
+		keys, _, err := kv.Keys(prefix, "", nil)
+		if err != nil {
+			span.SetStatus(codes.Error, err.Error())
+			return nil, err
+		}
+		for _, k := range keys {
+			segments := strings.Split(strings.TrimPrefix(k, labelIndex+index+"/"), "/")
+			if len(segments) < 4 || seen[segments[2]+"/"+segments[3]] {
+				continue
+			}
+			seen[segments[2]+"/"+segments[3]] = true
+
+			name, err := url.PathUnescape(segments[2])
+			if err != nil {
+				span.SetStatus(codes.Error, err.Error())
+				return nil, fmt.Errorf("malformed label index key %s: %w", k, err)
+			}
+			version, err := model.ToVersion(segments[3])
+			if err != nil {
+				span.SetStatus(codes.Error, err.Error())
+				return nil, fmt.Errorf("malformed label index key %s: %w", k, err)
+			}
+			refs = append(refs, model.ObjectRef{Kind: kind, Name: name, Version: *version})
+		}
+	}
+
+	span.SetStatus(codes.Ok, "Success fetching label index")
+	return refs, nil
+}
+
+// BuildLabelIndex indexes the configs and group members written before the label index existed. It runs once, a
+// marker key records that it completed. It returns the number of entries written.
+func (cr *ConfigRepository) BuildLabelIndex(ctx context.Context) (int, error) {
+	_, span := cr.Tracer.Start(ctx, "ConfigRepository.BuildLabelIndex")
+	defer span.End()
+
+	kv := cr.cli.KV()
+	marker, _, err := kv.Get(ConstructMigrationKey(labelIndexMigration), nil)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return 0, err
+	}
+	if marker != nil {
+		span.SetStatus(codes.Ok, "Migration already applied")
+		return 0, nil
+	}
+
+	configs, err := cr.GetAll(ctx)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return 0, err
+	}
+	var keys []string
+	for _, c := range configs {
+		keys = append(keys, configIndexKeys(c)...)
+	}
+
+	members, _, err := kv.List(allGroups+"/", nil)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return 0, err
+	}
+	for _, pair := range members {
+		segments := strings.Split(pair.Key, "/")
+		if len(segments) < 4 {
+			continue
+		}
+		config := model.Configuration{}
+		if err = json.Unmarshal(pair.Value, &config); err != nil {
+			span.SetStatus(codes.Error, err.Error())
+			return 0, err
+		}
+		name, err := unescapeName(segments[1])
+		if err != nil {
+			span.SetStatus(codes.Error, err.Error())
+			return 0, err
+		}
+		keys = append(keys, memberIndexKeys(name, segments[2], config)...)
+	}
+
+	if err = cr.addIndex(keys); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return 0, err
+	}
+	if _, err = kv.Put(&api.KVPair{Key: ConstructMigrationKey(labelIndexMigration), Value: []byte(time.Now().UTC().Format(time.RFC3339))}, nil); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return 0, err
+	}
+
+	span.SetStatus(codes.Ok, "Successfully built label index")
+	return len(keys), nil
+}
@@ -0,0 +1,108 @@
+package repositories
+
+import (
+	"ars_projekat/model"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"go.opentelemetry.io/otel/codes"
+)
+
+// parseConfigKey reads the name and version out of a configs/{escaped name}/{version}/ key.
+func parseConfigKey(key string) (*model.ObjectRef, error) {
+	segments := strings.Split(strings.TrimPrefix(key, configFolder), "/")
+	if len(segments) < 2 {
+		return nil, nil
+	}
+	name, err := unescapeName(segments[0])
+	if err != nil {
+		return nil, fmt.Errorf("malformed config key %s: %w", key, err)
+	}
+	version, err := model.ToVersion(segments[1])
+	if err != nil {
+		return nil, fmt.Errorf("malformed config key %s: %w", key, err)
+	}
+	return &model.ObjectRef{Kind: model.KindConfig, Name: name, Version: *version}, nil
+}
+
+// parseGroupDocumentKey reads the name and version out of a group-docs/{escaped name}/{version} key, one per group.
+func parseGroupDocumentKey(key string) (*model.ObjectRef, error) {
+	segments := strings.Split(key, "/")
+	if len(segments) != 3 {
+		return nil, nil
+	}
+	name, err := unescapeName(segments[1])
+	if err != nil {
+		return nil, fmt.Errorf("malformed group key %s: %w", key, err)
+	}
+	version, err := model.ToVersion(segments[2])
+	if err != nil {
+		return nil, fmt.Errorf("malformed group key %s: %w", key, err)
+	}
+	return &model.ObjectRef{Kind: model.KindGroup, Name: name, Version: *version}, nil
+}
+
+// ListConfigRefs names every configuration version whose name starts with prefix. Only keys are read, the
+// configurations themselves stay in Consul.
+func (cr *ConfigRepository) ListConfigRefs(prefix string, ctx context.Context) ([]model.ObjectRef, error) {
+	_, span := cr.Tracer.Start(ctx, "ConfigRepository.ListConfigRefs")
+	defer span.End()
+
+	kv := cr.cli.KV()
+	keys, _, err := kv.Keys(ConstructConfigFolderPrefix(prefix), "", nil)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	refs := make([]model.ObjectRef, 0, len(keys))
+	for _, key := range keys {
+		ref, err := parseConfigKey(key)
+		if err != nil {
+			span.SetStatus(codes.Error, err.Error())
+			return nil, err
+		}
+		if ref != nil {
+			refs = append(refs, *ref)
+		}
+	}
+
+	span.SetStatus(codes.Ok, "Success listing configuration keys")
+	return refs, nil
+}
+
+// ListGroupRefs names every group version whose name starts with prefix, read from the keys of group documents.
+func (cr *ConfigRepository) ListGroupRefs(prefix string, ctx context.Context) ([]model.ObjectRef, error) {
+	_, span := cr.Tracer.Start(ctx, "ConfigRepository.ListGroupRefs")
+	defer span.End()
+
+	kv := cr.cli.KV()
+	keys, _, err := kv.Keys(ConstructGroupDocumentFolderPrefix(prefix), "", nil)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	var refs []model.ObjectRef
+	for _, key := range keys {
+		ref, err := parseGroupDocumentKey(key)
+		if err != nil {
+			span.SetStatus(codes.Error, err.Error())
+			return nil, err
+		}
+		if ref != nil {
+			refs = append(refs, *ref)
+		}
+	}
+	sort.Slice(refs, func(i, j int) bool {
+		if refs[i].Name != refs[j].Name {
+			return refs[i].Name < refs[j].Name
+		}
+		return model.CompareVersions(refs[i].Version, refs[j].Version) < 0
+	})
+
+	span.SetStatus(codes.Ok, "Success listing configuration group keys")
+	return refs, nil
+}
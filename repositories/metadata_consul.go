@@ -0,0 +1,392 @@
+package repositories
+
+import (
+	"ars_projekat/model"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// indexOps write the ID index entry of an object and its update time index entry, they are committed together with
+// the object they name.
+func indexOps(id int64, ref model.ObjectRef, updatedAt time.Time) (api.TxnOps, error) {
+	data, err := json.Marshal(model.IndexedObject{ObjectRef: ref, UpdatedAt: updatedAt})
+	if err != nil {
+		return nil, err
+	}
+	return api.TxnOps{
+		setOp(ConstructObjectIdKey(id), data),
+		setOp(ConstructUpdateIndexKey(ref.Kind, ref.Name, model.ToString(ref.Version)), data),
+	}, nil
+}
+
+func (cr *ConfigRepository) GetObjectRef(id int64, ctx context.Context) (*model.ObjectRef, error) {
+	_, span := cr.Tracer.Start(ctx, "ConfigRepository.GetObjectRef")
+	defer span.End()
+
+	kv := cr.cli.KV()
+	data, _, err := kv.Get(ConstructObjectIdKey(id), nil)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	if data == nil {
+		return nil, model.ErrNotFound
+	}
+
+	ref := &model.ObjectRef{}
+	if err = json.Unmarshal(data.Value, ref); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	span.SetStatus(codes.Ok, "Success fetching object reference")
+	return ref, nil
+}
+
+// ListIndexedObjects returns the update time index entries of the objects of kind whose name starts with prefix,
+// without reading the entries of other kinds or names. Entries of trashed objects stay until the trash is purged,
+// callers match them against the stored objects.
+func (cr *ConfigRepository) ListIndexedObjects(kind string, prefix string, ctx context.Context) ([]model.IndexedObject, error) {
+	_, span := cr.Tracer.Start(ctx, "ConfigRepository.ListIndexedObjects")
+	defer span.End()
+
+	kv := cr.cli.KV()
+	data, _, err := kv.List(ConstructUpdateIndexPrefix(kind, prefix), nil)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	objects := make([]model.IndexedObject, 0, len(data))
+	for _, pair := range data {
+		object := model.IndexedObject{}
+		if err = json.Unmarshal(pair.Value, &object); err != nil {
+			span.SetStatus(codes.Error, err.Error())
+			return nil, err
+		}
+		if object.Kind == kind && strings.HasPrefix(object.Name, prefix) {
+			objects = append(objects, object)
+		}
+	}
+
+	span.SetStatus(codes.Ok, "Success listing indexed objects")
+	return objects, nil
+}
+
+func (cr *ConfigRepository) GetGroupDocument(name string, version string, ctx context.Context) (*model.GroupDocument, error) {
+	_, span := cr.Tracer.Start(ctx, "ConfigRepository.GetGroupDocument")
+	defer span.End()
+
+	kv := cr.cli.KV()
+	data, _, err := kv.Get(ConstructGroupDocumentKey(name, version), nil)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	if data == nil {
+		return nil, model.ErrNotFound
+	}
+
+	doc := &model.GroupDocument{}
+	if err = json.Unmarshal(data.Value, doc); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	span.SetStatus(codes.Ok, "Success fetching group document")
+	return doc, nil
+}
+
+// SaveGroup writes configs as members of the group version and lists them in its document together with the ID,
+// metadata and includes of doc, its ID index entry and rev, all in one update. Members the stored document lists
+// and configs does not are kept.
+func (cr *ConfigRepository) SaveGroup(doc *model.GroupDocument, configs []model.Configuration, rev *model.GroupRevision, ctx context.Context) error {
+	_, span := cr.Tracer.Start(ctx, "ConfigRepository.SaveGroup")
+	defer span.End()
+
+	name, version := doc.Name, model.ToString(doc.Version)
+	ops := make(api.TxnOps, 0, len(configs)+1)
+	members := make([]model.GroupMember, 0, len(configs))
+	for _, config := range configs {
+		data, err := json.Marshal(config)
+		if err != nil {
+			span.SetStatus(codes.Error, err.Error())
+			return err
+		}
+		member := model.MemberOf(config)
+		ops = append(ops, setOp(ConstructConfigGroupKey(name, version, member.Labels, config.Name), data))
+		members = append(members, member)
+	}
+	if doc.Id != 0 {
+		idOps, err := indexOps(doc.Id, model.ObjectRef{Kind: model.KindGroup, Name: doc.Name, Version: doc.Version}, doc.UpdatedAt)
+		if err != nil {
+			span.SetStatus(codes.Error, err.Error())
+			return err
+		}
+		ops = append(ops, idOps...)
+	}
+
+	indexed := membersIndexKeys(name, version, configs)
+	includes := includeIndexKeys(name, version, doc.Includes)
+	if err := cr.addIndex(append(indexed, includes...)); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	var previous []model.GroupInclude
+	err := cr.updateGroupDocument(name, version, rev, func(stored *model.GroupDocument, _ bool) (api.TxnOps, bool, error) {
+		previous = stored.Includes
+		stored.Id = doc.Id
+		stored.Includes = doc.Includes
+		stored.Metadata = doc.Metadata
+		for _, member := range members {
+			stored.AddMember(member)
+		}
+		return ops, true, nil
+	})
+	if err != nil {
+		cr.releaseIndexAfter(name, version, indexed)
+		cr.releaseIncludesAfter(name, version, includes)
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	if err = cr.removeIndex(includeIndexKeys(name, version, previous), includes); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "Successfully saved group")
+	return nil
+}
+
+// maxDocumentAttempts bounds the retries of a group document update that keeps losing to concurrent writes.
+const maxDocumentAttempts = 5
+
+// updateGroupDocument lets change edit the stored document of a group, or a new one when there is none, and commits
+// it with check-and-set in the same transaction as the ops change returns, along with the group's watch stamp. The
+// document is deleted when change does not keep it. When rev is not nil it snapshots the group as the update leaves it and is stored as the next
+// revision in the same transaction. When another write changed the document or took the revision number first the
+// update is retried on the fresh document.
+func (cr *ConfigRepository) updateGroupDocument(name string, version string, rev *model.GroupRevision, change func(doc *model.GroupDocument, exists bool) (api.TxnOps, bool, error)) error {
+	kv := cr.cli.KV()
+	key := ConstructGroupDocumentKey(name, version)
+	ver, err := model.ToVersion(version)
+	if err != nil {
+		return err
+	}
+
+	for attempt := 1; ; attempt++ {
+		pair, _, err := kv.Get(key, nil)
+		if err != nil {
+			return err
+		}
+		var members api.KVPairs
+		if rev != nil {
+			if members, _, err = kv.List(ConstructConfigGroupKey(name, version, "", ""), nil); err != nil {
+				return err
+			}
+		}
+		doc := &model.GroupDocument{Name: name, Version: *ver}
+		var index uint64
+		if pair != nil {
+			if err = json.Unmarshal(pair.Value, doc); err != nil {
+				return err
+			}
+			index = pair.ModifyIndex
+		}
+
+		listed := memberKeys(doc)
+		ops, keep, err := change(doc, pair != nil)
+		if err != nil {
+			return err
+		}
+		after := map[string]bool{}
+		if keep {
+			after = memberKeys(doc)
+		}
+		all := ops
+		switch {
+		case keep:
+			if doc.Members == nil {
+				doc.Members = []model.GroupMember{}
+			}
+			data, err := json.Marshal(doc)
+			if err != nil {
+				return err
+			}
+			all = append(all, casOp(key, data, index), setOp(ConstructWatchKey(model.KindGroup, name, version), nil))
+		case pair != nil:
+			all = append(all, deleteCASOp(key, index), deleteOp(ConstructWatchKey(model.KindGroup, name, version)))
+		}
+		var revOp *api.TxnOp
+		if rev != nil {
+			rev.Deleted = !keep
+			rev.Group = nil
+			if keep {
+				if rev.Group, err = groupAfter(doc, members, all); err != nil {
+					return err
+				}
+			}
+			if revOp, err = cr.groupRevisionOp(name, version, rev); err != nil {
+				return err
+			}
+			all = append(all, revOp)
+		}
+
+		var commitErr error
+		if len(all) <= maxTxnOps {
+			commitErr = cr.commit(all)
+		} else {
+			commitErr = cr.commitAroundDocument(name, version, ops, all[len(ops):], listed, after)
+		}
+		if commitErr == nil || !errors.Is(commitErr, errRolledBack) || attempt == maxDocumentAttempts {
+			return commitErr
+		}
+		// Only retry when the document changed or the revision number was taken, other failed checks fail the same
+		// way again.
+		current, _, err := kv.Get(key, nil)
+		if err != nil {
+			return err
+		}
+		changed := (current == nil) != (pair == nil) || (current != nil && current.ModifyIndex != index)
+		if !changed && revOp != nil {
+			if changed, err = cr.revisionTaken(revOp); err != nil {
+				return err
+			}
+		}
+		if !changed {
+			return commitErr
+		}
+	}
+}
+
+// memberKeys returns the keys of the members doc lists.
+func memberKeys(doc *model.GroupDocument) map[string]bool {
+	keys := make(map[string]bool, len(doc.Members))
+	for _, member := range doc.Members {
+		keys[ConstructConfigGroupKey(doc.Name, model.ToString(doc.Version), member.Labels, member.Name)] = true
+	}
+	return keys
+}
+
+// commitAroundDocument commits a group update too large for one transaction. Readers only see the members the group
+// document lists, so member writes the old document does not list are committed before the document and member
+// deletes the new document does not list after it. Everything else commits with the document ops in one
+// transaction, which must fit. Members written before a failed document update stay unlisted and are overwritten by
+// the next write of the same member.
+func (cr *ConfigRepository) commitAroundDocument(name string, version string, ops api.TxnOps, docOps api.TxnOps, listed map[string]bool, after map[string]bool) error {
+	before, pivot, trailing := splitAroundDocument(ConstructConfigGroupKey(name, version, "", ""), ops, len(docOps), listed, after)
+	if len(pivot)+len(docOps) > maxTxnOps {
+		return fmt.Errorf("group %s %s: %d operations must be committed together, consul allows at most %d: %w", name, version, len(pivot)+len(docOps), maxTxnOps, model.ErrInvalid)
+	}
+
+	if err := cr.commitChunks(before); err != nil {
+		return err
+	}
+	if err := cr.commit(append(pivot, docOps...)); err != nil {
+		return err
+	}
+	if err := cr.commitChunks(trailing); err != nil {
+		// The update is committed, the members left behind are no longer listed and read by nobody.
+		log.Printf("Removing unlisted members of group %s %s failed: %v", name, version, err)
+	}
+	return nil
+}
+
+// splitAroundDocument splits the ops of a group update into the member writes committed before the document, the
+// ops committed with the docOps document ops and the member deletes committed after it. prefix is the member prefix
+// of the group version.
+func splitAroundDocument(prefix string, ops api.TxnOps, docOps int, listed map[string]bool, after map[string]bool) (api.TxnOps, api.TxnOps, api.TxnOps) {
+	// A tree delete over the members would remove the members written ahead of it.
+	stage := true
+	for _, op := range ops {
+		if op.KV != nil && op.KV.Verb == api.KVDeleteTree && strings.HasPrefix(prefix, op.KV.Key) {
+			stage = false
+		}
+	}
+	var before, pivot, trailing api.TxnOps
+	for _, op := range ops {
+		member := op.KV != nil && strings.HasPrefix(op.KV.Key, prefix)
+		switch {
+		case stage && member && (op.KV.Verb == api.KVSet || op.KV.Verb == api.KVCAS) && !listed[op.KV.Key]:
+			before = append(before, op)
+		case member && (op.KV.Verb == api.KVDelete || op.KV.Verb == api.KVDeleteCAS) && !after[op.KV.Key]:
+			trailing = append(trailing, op)
+		default:
+			pivot = append(pivot, op)
+		}
+	}
+	// Keep as many member ops as fit in the document transaction so the split only happens where it must.
+	for len(before) > 0 && len(pivot)+docOps < maxTxnOps {
+		pivot = append(pivot, before[len(before)-1])
+		before = before[:len(before)-1]
+	}
+	for len(trailing) > 0 && len(pivot)+docOps < maxTxnOps {
+		pivot = append(pivot, trailing[0])
+		trailing = trailing[1:]
+	}
+	return before, pivot, trailing
+}
+
+// groupAfter returns the group as a committed update leaves it, with the members doc lists read from the stored
+// members with ops applied.
+func groupAfter(doc *model.GroupDocument, members api.KVPairs, ops api.TxnOps) (*model.ConfigurationGroup, error) {
+	values := make(map[string][]byte, len(members))
+	for _, pair := range members {
+		values[pair.Key] = pair.Value
+	}
+	for _, op := range ops {
+		if op.KV == nil {
+			continue
+		}
+		switch op.KV.Verb {
+		case api.KVSet, api.KVCAS:
+			values[op.KV.Key] = op.KV.Value
+		case api.KVDelete, api.KVDeleteCAS:
+			delete(values, op.KV.Key)
+		case api.KVDeleteTree:
+			for k := range values {
+				if strings.HasPrefix(k, op.KV.Key) {
+					delete(values, k)
+				}
+			}
+		}
+	}
+
+	prefix := ConstructConfigGroupKey(doc.Name, model.ToString(doc.Version), "", "")
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	group := &model.ConfigurationGroup{
+		Name:           doc.Name,
+		Id:             doc.Id,
+		Version:        doc.Version,
+		Configurations: []model.Configuration{},
+		Includes:       doc.Includes,
+		Metadata:       doc.Metadata,
+	}
+	for _, k := range keys {
+		config := model.Configuration{}
+		if err := json.Unmarshal(values[k], &config); err != nil {
+			return nil, err
+		}
+		if doc.HasMember(model.MemberOf(config)) {
+			config.Binding = config.ComputeBinding()
+			group.Configurations = append(group.Configurations, config)
+		}
+	}
+	return group, nil
+}
@@ -0,0 +1,137 @@
+package repositories
+
+import (
+	"ars_projekat/model"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func largeGroup(n int) []model.Configuration {
+	configs := make([]model.Configuration, n)
+	for i := range configs {
+		configs[i] = model.Configuration{Name: fmt.Sprintf("config%d", i), Labels: model.Labels{"env": "prod"}}
+	}
+	return configs
+}
+
+func keysOf(name string, version string, configs []model.Configuration) map[string]bool {
+	doc := &model.GroupDocument{Name: name, Version: model.Version{Major: 1}}
+	for _, c := range configs {
+		doc.AddMember(model.MemberOf(c))
+	}
+	return memberKeys(doc)
+}
+
+func assertSplit(t *testing.T, ops api.TxnOps, docOps int, before api.TxnOps, pivot api.TxnOps, trailing api.TxnOps) {
+	assert.LessOrEqual(t, len(pivot)+docOps, maxTxnOps)
+	assert.Equal(t, len(ops), len(before)+len(pivot)+len(trailing))
+	seen := map[*api.KVTxnOp]int{}
+	for _, part := range []api.TxnOps{before, pivot, trailing} {
+		for _, op := range part {
+			seen[op.KV]++
+		}
+	}
+	for _, op := range ops {
+		assert.Equal(t, 1, seen[op.KV])
+	}
+}
+
+func TestSplitAroundDocument_TrashMoreThanATransaction(t *testing.T) {
+	configs := largeGroup(70)
+	prefix := ConstructConfigGroupKey("backend", "1.0.0", "", "")
+	ops := api.TxnOps{setOp(ConstructTrashedGroupKey("backend", "1.0.0", time.Time{}), nil)}
+	ops = append(ops, deleteMembersOps("backend", "1.0.0", false, configs)...)
+
+	before, pivot, trailing := splitAroundDocument(prefix, ops, 2, keysOf("backend", "1.0.0", configs), map[string]bool{})
+	assertSplit(t, ops, 2, before, pivot, trailing)
+	assert.Empty(t, before)
+	assert.Equal(t, ops[0], pivot[0], "the trash entry is written with the document")
+	assert.Len(t, trailing, 70+1+2-maxTxnOps)
+	for _, op := range trailing {
+		assert.Equal(t, api.KVDelete, op.KV.Verb)
+	}
+}
+
+func TestSplitAroundDocument_SaveMoreThanATransaction(t *testing.T) {
+	configs := largeGroup(70)
+	prefix := ConstructConfigGroupKey("backend", "1.0.0", "", "")
+	var ops api.TxnOps
+	for _, c := range configs {
+		ops = append(ops, setOp(ConstructConfigGroupKey("backend", "1.0.0", model.SortLabels(c.Labels), c.Name), nil))
+	}
+
+	before, pivot, trailing := splitAroundDocument(prefix, ops, 2, map[string]bool{}, keysOf("backend", "1.0.0", configs))
+	assertSplit(t, ops, 2, before, pivot, trailing)
+	assert.Len(t, before, 70+2-maxTxnOps)
+	assert.Empty(t, trailing)
+}
+
+func TestDeleteMembersOps_WholeGroup(t *testing.T) {
+	ops := deleteMembersOps("backend", "1.0.0", true, largeGroup(70))
+	assert.Len(t, ops, 1)
+	assert.Equal(t, api.KVDeleteTree, ops[0].KV.Verb)
+
+	before, pivot, trailing := splitAroundDocument(ConstructConfigGroupKey("backend", "1.0.0", "", ""), ops, 2, map[string]bool{}, map[string]bool{})
+	assert.Empty(t, before)
+	assert.Len(t, pivot, 1)
+	assert.Empty(t, trailing)
+}
+
+func TestReplaceMembersOps_RollbackMoreThanATransaction(t *testing.T) {
+	current, target := largeGroup(70), largeGroup(70)
+	var stored api.KVPairs
+	for _, c := range current {
+		data, _ := json.Marshal(c)
+		stored = append(stored, &api.KVPair{Key: ConstructConfigGroupKey("backend", "1.0.0", model.SortLabels(c.Labels), c.Name), Value: data})
+	}
+	// The revision changes two members and swaps the labels of ten others.
+	target[0].Parameters = map[string]string{"port": "8080"}
+	target[1].Parameters = map[string]string{"port": "8081"}
+	for i := 60; i < 70; i++ {
+		target[i].Labels = model.Labels{"env": "dev"}
+	}
+
+	ops, members, err := replaceMembersOps("backend", "1.0.0", stored, target)
+	assert.NoError(t, err)
+	assert.Len(t, members, 70)
+	assert.Len(t, ops, 2+10+10)
+	for _, op := range ops {
+		assert.NotEqual(t, api.KVDeleteTree, op.KV.Verb)
+	}
+
+	before, pivot, trailing := splitAroundDocument(ConstructConfigGroupKey("backend", "1.0.0", "", ""), ops, 2, keysOf("backend", "1.0.0", current), keysOf("backend", "1.0.0", target))
+	assertSplit(t, ops, 2, before, pivot, trailing)
+}
+
+func TestReplaceMembersOps_Unchanged(t *testing.T) {
+	configs := largeGroup(70)
+	var stored api.KVPairs
+	for _, c := range configs {
+		data, _ := json.Marshal(c)
+		stored = append(stored, &api.KVPair{Key: ConstructConfigGroupKey("backend", "1.0.0", model.SortLabels(c.Labels), c.Name), Value: data})
+	}
+
+	ops, members, err := replaceMembersOps("backend", "1.0.0", stored, configs)
+	assert.NoError(t, err)
+	assert.Empty(t, ops)
+	assert.Len(t, members, 70)
+}
+
+func TestIndexOps_UpdateTimeByName(t *testing.T) {
+	updated := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	ops, err := indexOps(42, model.ObjectRef{Kind: model.KindConfig, Name: "team/db", Version: model.Version{Major: 1}}, updated)
+	assert.NoError(t, err)
+	assert.Len(t, ops, 2)
+	assert.Equal(t, ConstructObjectIdKey(42), ops[0].KV.Key)
+	assert.Equal(t, "update-index/config/team%2Fdb/1.0.0", ops[1].KV.Key)
+	assert.Equal(t, ops[0].KV.Value, ops[1].KV.Value)
+	// Listing a name prefix only reads the entries below it.
+	assert.True(t, strings.HasPrefix(ops[1].KV.Key, ConstructUpdateIndexPrefix(model.KindConfig, "team/")))
+	assert.False(t, strings.HasPrefix(ops[1].KV.Key, ConstructUpdateIndexPrefix(model.KindGroup, "")))
+}
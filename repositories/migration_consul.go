@@ -0,0 +1,404 @@
+package repositories
+
+import (
+	"ars_projekat/model"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+	"go.opentelemetry.io/otel/codes"
+)
+
+const (
+	canonicalLabelsMigration = "canonical-labels"
+	groupDocumentsMigration  = "group-documents"
+	escapedNamesMigration    = "escaped-names"
+	updateIndexMigration     = "update-index"
+)
+
+// namedKeys is a key prefix whose keys hold a name followed by more segments. split cuts what follows the prefix
+// into the stored name and the rest of the key, name reads the name out of a stored value and returns "" when the
+// value does not hold it. raw keys were never escaped, their stored name is the name when no value holds it.
+type namedKeys struct {
+	prefix string
+	split  func(rest string) (string, string, bool)
+	name   func(value []byte) (string, error)
+	raw    bool
+}
+
+// splitLast keeps the last n segments of a key as its rest, for names that may contain slashes.
+func splitLast(n int) func(string) (string, string, bool) {
+	return func(rest string) (string, string, bool) {
+		segments := strings.Split(rest, "/")
+		if len(segments) <= n {
+			return "", "", false
+		}
+		cut := len(segments) - n
+		return strings.Join(segments[:cut], "/"), strings.Join(segments[cut:], "/"), true
+	}
+}
+
+// splitFirst takes the first segment of a key as its name, group names never contain slashes.
+func splitFirst(rest string) (string, string, bool) {
+	return strings.Cut(rest, "/")
+}
+
+// escapedNameKeys lists every key that holds a config, schema or group name. Families sharing a name source are
+// migrated together, so revisions that no longer hold their config are moved with it.
+var escapedNameKeys = [][]namedKeys{
+	{
+		{prefix: configFolder, split: splitLast(2), name: func(value []byte) (string, error) {
+			config := model.Configuration{}
+			err := json.Unmarshal(value, &config)
+			return config.Name, err
+		}},
+		{prefix: allTrashedConfigs, split: splitLast(1), name: func(value []byte) (string, error) {
+			trashed := model.TrashedConfiguration{}
+			err := json.Unmarshal(value, &trashed)
+			return trashed.Configuration.Name, err
+		}},
+		{prefix: allConfigRevisions, split: splitLast(2), name: func(value []byte) (string, error) {
+			rev := model.ConfigurationRevision{}
+			if err := json.Unmarshal(value, &rev); err != nil || rev.Configuration == nil {
+				return "", err
+			}
+			return rev.Configuration.Name, nil
+		}},
+	},
+	{
+		{prefix: allSchemas, split: splitLast(1), name: func(value []byte) (string, error) {
+			schema := model.Schema{}
+			err := json.Unmarshal(value, &schema)
+			return schema.Name, err
+		}},
+	},
+	{
+		{prefix: groupDocumentFolder, split: splitFirst, raw: true, name: func(value []byte) (string, error) {
+			doc := model.GroupDocument{}
+			err := json.Unmarshal(value, &doc)
+			return doc.Name, err
+		}},
+		{prefix: allTrashedGroups, split: splitFirst, raw: true, name: func(value []byte) (string, error) {
+			trashed := model.TrashedGroup{}
+			err := json.Unmarshal(value, &trashed)
+			return trashed.Name, err
+		}},
+		{prefix: allGroupRevisions, split: splitFirst, raw: true, name: func(value []byte) (string, error) {
+			rev := model.GroupRevision{}
+			if err := json.Unmarshal(value, &rev); err != nil || rev.Group == nil {
+				return "", err
+			}
+			return rev.Group.Name, nil
+		}},
+		{prefix: allGroups + "/", split: splitFirst, raw: true},
+	},
+}
+
+// RekeyEscapedNames moves keys written before names were escaped in keys to their escaped key, a config named
+// team/db moves from configs/team/db/{version}/ to configs/team%2Fdb/{version}/. Names are read from the stored
+// values, a group no stored value names keeps the name of its key segment. A key whose escaped key is taken is left in place and logged. It runs once, a marker key records that it
+// completed. It returns the number of keys moved.
+func (cr *ConfigRepository) RekeyEscapedNames(ctx context.Context) (int, error) {
+	_, span := cr.Tracer.Start(ctx, "ConfigRepository.RekeyEscapedNames")
+	defer span.End()
+
+	kv := cr.cli.KV()
+	marker, _, err := kv.Get(ConstructMigrationKey(escapedNamesMigration), nil)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return 0, err
+	}
+	if marker != nil {
+		span.SetStatus(codes.Ok, "Migration already applied")
+		return 0, nil
+	}
+
+	var ops api.TxnOps
+	moved := 0
+	for _, family := range escapedNameKeys {
+		names := make(map[string]string)
+		listed := make([]api.KVPairs, len(family))
+		taken := make(map[string]bool)
+		for j, keys := range family {
+			if listed[j], _, err = kv.List(keys.prefix, nil); err != nil {
+				span.SetStatus(codes.Error, err.Error())
+				return moved, err
+			}
+			for _, pair := range listed[j] {
+				taken[pair.Key] = true
+				stored, _, ok := keys.split(strings.TrimPrefix(pair.Key, keys.prefix))
+				if !ok || keys.name == nil {
+					continue
+				}
+				name, err := keys.name(pair.Value)
+				if err != nil {
+					span.SetStatus(codes.Error, err.Error())
+					return moved, fmt.Errorf("reading the name stored under %s: %w", pair.Key, err)
+				}
+				if name != "" {
+					names[stored] = name
+				}
+			}
+		}
+
+		for j, keys := range family {
+			for _, pair := range listed[j] {
+				stored, rest, ok := keys.split(strings.TrimPrefix(pair.Key, keys.prefix))
+				if !ok {
+					continue
+				}
+				name, ok := names[stored]
+				if !ok && !keys.raw {
+					continue
+				}
+				if !ok {
+					name = stored
+				}
+				key := keys.prefix + escapeName(name) + "/" + rest
+				if key == pair.Key {
+					continue
+				}
+				if taken[key] {
+					log.Printf("Not moving %s, %s already exists", pair.Key, key)
+					continue
+				}
+				if len(ops)+2 > maxTxnOps {
+					if err = cr.commit(ops); err != nil {
+						span.SetStatus(codes.Error, err.Error())
+						return moved, err
+					}
+					ops = nil
+				}
+				ops = append(ops, setOp(key, pair.Value), deleteOp(pair.Key))
+				taken[key] = true
+				moved++
+			}
+		}
+	}
+
+	ops = append(ops, setOp(ConstructMigrationKey(escapedNamesMigration), []byte(time.Now().UTC().Format(time.RFC3339))))
+	if err = cr.commit(ops); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return moved, err
+	}
+
+	span.SetStatus(codes.Ok, "Successfully re-keyed escaped names")
+	return moved, nil
+}
+
+// RekeyGroupMembers moves group members stored under a label key that is not the canonical encoding of their
+// labels, older releases encoded labels in random order and unescaped. It runs once, a marker key records that it
+// completed. It returns the number of members moved.
+func (cr *ConfigRepository) RekeyGroupMembers(ctx context.Context) (int, error) {
+	_, span := cr.Tracer.Start(ctx, "ConfigRepository.RekeyGroupMembers")
+	defer span.End()
+
+	kv := cr.cli.KV()
+	marker, _, err := kv.Get(ConstructMigrationKey(canonicalLabelsMigration), nil)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return 0, err
+	}
+	if marker != nil {
+		span.SetStatus(codes.Ok, "Migration already applied")
+		return 0, nil
+	}
+
+	data, _, err := kv.List(allGroups+"/", nil)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return 0, err
+	}
+
+	// Keys are groups/{name}/{version}/[{labels}/]{config}, each move is a set and a delete
+	var ops api.TxnOps
+	moved := 0
+	for _, pair := range data {
+		segments := strings.Split(pair.Key, "/")
+		if len(segments) < 4 {
+			continue
+		}
+		config := model.Configuration{}
+		if err = json.Unmarshal(pair.Value, &config); err != nil {
+			span.SetStatus(codes.Error, err.Error())
+			return moved, err
+		}
+		name, err := unescapeName(segments[1])
+		if err != nil {
+			span.SetStatus(codes.Error, err.Error())
+			return moved, err
+		}
+
+		key := ConstructConfigGroupKey(name, segments[2], model.SortLabels(config.Labels), config.Name)
+		if key == pair.Key {
+			continue
+		}
+		if len(ops)+2 > maxTxnOps {
+			if err = cr.commit(ops); err != nil {
+				span.SetStatus(codes.Error, err.Error())
+				return moved, err
+			}
+			ops = nil
+		}
+		ops = append(ops, setOp(key, pair.Value), deleteOp(pair.Key))
+		moved++
+	}
+
+	ops = append(ops, setOp(ConstructMigrationKey(canonicalLabelsMigration), []byte(time.Now().UTC().Format(time.RFC3339))))
+	if err = cr.commit(ops); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return moved, err
+	}
+
+	span.SetStatus(codes.Ok, "Successfully re-keyed group members")
+	return moved, nil
+}
+
+// BuildGroupDocuments lists the members stored under each group version in its document, creating documents without
+// an ID for groups written before documents existed. It runs once, a marker key records that it completed. It
+// returns the number of documents written.
+func (cr *ConfigRepository) BuildGroupDocuments(ctx context.Context) (int, error) {
+	_, span := cr.Tracer.Start(ctx, "ConfigRepository.BuildGroupDocuments")
+	defer span.End()
+
+	kv := cr.cli.KV()
+	marker, _, err := kv.Get(ConstructMigrationKey(groupDocumentsMigration), nil)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return 0, err
+	}
+	if marker != nil {
+		span.SetStatus(codes.Ok, "Migration already applied")
+		return 0, nil
+	}
+
+	data, _, err := kv.List(groupKeys, nil)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return 0, err
+	}
+
+	// Documents come first, group-docs/ sorts before groups/
+	docs := make(map[string]*model.GroupDocument)
+	for _, pair := range data {
+		segments := strings.Split(pair.Key, "/")
+		switch {
+		case strings.HasPrefix(pair.Key, groupDocumentFolder):
+			doc := &model.GroupDocument{}
+			if err = json.Unmarshal(pair.Value, doc); err != nil {
+				span.SetStatus(codes.Error, err.Error())
+				return 0, err
+			}
+			docs[pair.Key] = doc
+		case len(segments) >= 4:
+			config := model.Configuration{}
+			if err = json.Unmarshal(pair.Value, &config); err != nil {
+				span.SetStatus(codes.Error, err.Error())
+				return 0, err
+			}
+			name, err := unescapeName(segments[1])
+			if err != nil {
+				span.SetStatus(codes.Error, err.Error())
+				return 0, err
+			}
+			key := ConstructGroupDocumentKey(name, segments[2])
+			doc, ok := docs[key]
+			if !ok {
+				version, err := model.ToVersion(segments[2])
+				if err != nil {
+					span.SetStatus(codes.Error, err.Error())
+					return 0, err
+				}
+				doc = &model.GroupDocument{Name: name, Version: *version}
+				docs[key] = doc
+			}
+			doc.AddMember(model.MemberOf(config))
+		}
+	}
+
+	keys := make([]string, 0, len(docs))
+	for key := range docs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var ops api.TxnOps
+	for _, key := range keys {
+		if docs[key].Members == nil {
+			docs[key].Members = []model.GroupMember{}
+		}
+		value, err := json.Marshal(docs[key])
+		if err != nil {
+			span.SetStatus(codes.Error, err.Error())
+			return 0, err
+		}
+		ops = append(ops, setOp(key, value))
+	}
+	ops = append(ops, setOp(ConstructMigrationKey(groupDocumentsMigration), []byte(time.Now().UTC().Format(time.RFC3339))))
+	if err = cr.commitChunks(ops); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return 0, err
+	}
+
+	span.SetStatus(codes.Ok, "Successfully built group documents")
+	return len(keys), nil
+}
+
+// BuildUpdateIndex copies the update times of the ID index into the update time index, objects written before it
+// existed have no entry there. The latest update of a name and version wins, IDs left by trashed objects are older
+// than those of the objects stored again. It runs once, a marker key records that it completed. It returns the
+// number of entries written.
+func (cr *ConfigRepository) BuildUpdateIndex(ctx context.Context) (int, error) {
+	_, span := cr.Tracer.Start(ctx, "ConfigRepository.BuildUpdateIndex")
+	defer span.End()
+
+	kv := cr.cli.KV()
+	marker, _, err := kv.Get(ConstructMigrationKey(updateIndexMigration), nil)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return 0, err
+	}
+	if marker != nil {
+		span.SetStatus(codes.Ok, "Migration already applied")
+		return 0, nil
+	}
+
+	data, _, err := kv.List(allObjectIds, nil)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return 0, err
+	}
+	latest := make(map[string]model.IndexedObject)
+	values := make(map[string][]byte)
+	for _, pair := range data {
+		object := model.IndexedObject{}
+		if err = json.Unmarshal(pair.Value, &object); err != nil {
+			span.SetStatus(codes.Error, err.Error())
+			return 0, err
+		}
+		key := ConstructUpdateIndexKey(object.Kind, object.Name, model.ToString(object.Version))
+		if current, ok := latest[key]; ok && !object.UpdatedAt.After(current.UpdatedAt) {
+			continue
+		}
+		latest[key] = object
+		values[key] = pair.Value
+	}
+
+	ops := make(api.TxnOps, 0, len(values)+1)
+	for key, value := range values {
+		ops = append(ops, setOp(key, value))
+	}
+	ops = append(ops, setOp(ConstructMigrationKey(updateIndexMigration), []byte(time.Now().UTC().Format(time.RFC3339))))
+	if err = cr.commitChunks(ops); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return 0, err
+	}
+
+	span.SetStatus(codes.Ok, "Successfully built update time index")
+	return len(values), nil
+}
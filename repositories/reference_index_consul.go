@@ -0,0 +1,280 @@
+package repositories
+
+import (
+	"ars_projekat/model"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// The source index maps every config name to the group members that read it through a source reference. Its
+// entries are written and removed together with the label index entries of the members, so it follows the same
+// rules: it may briefly name a member that no longer reads the config but never misses one. The include index maps
+// every group version to the groups that include it, with entries added before a group document is written and
+// removed after, under the same rules.
+
+const (
+	sourceIndexMigration  = "source-index"
+	includeIndexMigration = "include-index"
+)
+
+func includeIndexKeys(name string, version string, includes []model.GroupInclude) []string {
+	keys := make([]string, 0, len(includes))
+	for _, include := range includes {
+		keys = append(keys, ConstructIncludeIndexKey(include.Name, model.ToString(include.Version), name, version))
+	}
+	return keys
+}
+
+// releaseIncludesAfter removes the entries in keys that the stored document of the group version does not include,
+// a write that failed after indexing its includes leaves them behind otherwise. The write already failed, so a
+// failed cleanup is only logged.
+func (cr *ConfigRepository) releaseIncludesAfter(name string, version string, keys []string) {
+	var keep []string
+	pair, _, err := cr.cli.KV().Get(ConstructGroupDocumentKey(name, version), nil)
+	if err == nil && pair != nil {
+		doc := model.GroupDocument{}
+		if err = json.Unmarshal(pair.Value, &doc); err == nil {
+			keep = includeIndexKeys(name, version, doc.Includes)
+		}
+	}
+	if err == nil {
+		err = cr.removeIndex(keys, keep)
+	}
+	if err != nil {
+		log.Printf("Removing the include index entries of group %s %s failed: %v", name, version, err)
+	}
+}
+
+// FindSourceMembers returns the group versions with members that read the config name through a source reference,
+// each with only those members. Members the group document does not list are left out.
+func (cr *ConfigRepository) FindSourceMembers(source string, ctx context.Context) ([]model.ConfigurationGroup, error) {
+	_, span := cr.Tracer.Start(ctx, "ConfigRepository.FindSourceMembers")
+	defer span.End()
+
+	// Keys are reference-index/sources/{source}/{name}/{version}/{labels/member}
+	kv := cr.cli.KV()
+	prefix := ConstructSourceIndexPrefix(source)
+	keys, _, err := kv.Keys(prefix, "", nil)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	var groups []model.ConfigurationGroup
+	docs := make(map[string]*model.GroupDocument)
+	found := make(map[string]int)
+	for _, k := range keys {
+		segments := strings.Split(strings.TrimPrefix(k, prefix), "/")
+		if len(segments) != 3 {
+			continue
+		}
+		name, err := unescapeName(segments[0])
+		if err != nil {
+			span.SetStatus(codes.Error, err.Error())
+			return nil, fmt.Errorf("malformed source index key %s: %w", k, err)
+		}
+		member, err := unescapeName(segments[2])
+		if err != nil {
+			span.SetStatus(codes.Error, err.Error())
+			return nil, fmt.Errorf("malformed source index key %s: %w", k, err)
+		}
+		labels, memberName, _ := strings.Cut(member, "/")
+		version := segments[1]
+
+		group := segments[0] + "/" + version
+		doc, ok := docs[group]
+		if !ok {
+			if doc, err = cr.GetGroupDocument(name, version, ctx); err != nil && !errors.Is(err, model.ErrNotFound) {
+				span.SetStatus(codes.Error, err.Error())
+				return nil, err
+			}
+			docs[group] = doc
+		}
+		if doc == nil || !doc.HasMember(model.GroupMember{Name: memberName, Labels: labels}) {
+			continue
+		}
+
+		pair, _, err := kv.Get(ConstructConfigGroupKey(name, version, labels, memberName), nil)
+		if err != nil {
+			span.SetStatus(codes.Error, err.Error())
+			return nil, err
+		}
+		if pair == nil {
+			continue
+		}
+		config := model.Configuration{}
+		if err = json.Unmarshal(pair.Value, &config); err != nil {
+			span.SetStatus(codes.Error, err.Error())
+			return nil, err
+		}
+		if config.Source == nil || config.Source.Name != source {
+			continue
+		}
+		i, ok := found[group]
+		if !ok {
+			i = len(groups)
+			found[group] = i
+			groups = append(groups, model.ConfigurationGroup{Name: doc.Name, Version: doc.Version})
+		}
+		groups[i].Configurations = append(groups[i].Configurations, config)
+	}
+
+	span.SetStatus(codes.Ok, "Success fetching source index")
+	return groups, nil
+}
+
+// BuildSourceIndex indexes the group members with a source reference written before the source index existed. It
+// runs once, a marker key records that it completed. It returns the number of entries written.
+func (cr *ConfigRepository) BuildSourceIndex(ctx context.Context) (int, error) {
+	_, span := cr.Tracer.Start(ctx, "ConfigRepository.BuildSourceIndex")
+	defer span.End()
+
+	kv := cr.cli.KV()
+	marker, _, err := kv.Get(ConstructMigrationKey(sourceIndexMigration), nil)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return 0, err
+	}
+	if marker != nil {
+		span.SetStatus(codes.Ok, "Migration already applied")
+		return 0, nil
+	}
+
+	members, _, err := kv.List(allGroups+"/", nil)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return 0, err
+	}
+	var keys []string
+	for _, pair := range members {
+		segments := strings.Split(pair.Key, "/")
+		if len(segments) < 4 {
+			continue
+		}
+		config := model.Configuration{}
+		if err = json.Unmarshal(pair.Value, &config); err != nil {
+			span.SetStatus(codes.Error, err.Error())
+			return 0, err
+		}
+		if config.Source == nil {
+			continue
+		}
+		name, err := unescapeName(segments[1])
+		if err != nil {
+			span.SetStatus(codes.Error, err.Error())
+			return 0, err
+		}
+		member := model.SortLabels(config.Labels) + "/" + config.Name
+		keys = append(keys, ConstructSourceIndexKey(config.Source.Name, name, segments[2], member))
+	}
+
+	if err = cr.addIndex(keys); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return 0, err
+	}
+	if _, err = kv.Put(&api.KVPair{Key: ConstructMigrationKey(sourceIndexMigration), Value: []byte(time.Now().UTC().Format(time.RFC3339))}, nil); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return 0, err
+	}
+
+	span.SetStatus(codes.Ok, "Successfully built source index")
+	return len(keys), nil
+}
+
+// FindIncluders returns the group versions whose documents include the group version, with their includes.
+func (cr *ConfigRepository) FindIncluders(name string, version string, ctx context.Context) ([]model.ConfigurationGroup, error) {
+	_, span := cr.Tracer.Start(ctx, "ConfigRepository.FindIncluders")
+	defer span.End()
+
+	// Keys are reference-index/includes/{included}/{includedVersion}/{name}/{version}
+	kv := cr.cli.KV()
+	prefix := ConstructIncludeIndexPrefix(name, version)
+	keys, _, err := kv.Keys(prefix, "", nil)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	var groups []model.ConfigurationGroup
+	for _, k := range keys {
+		segments := strings.Split(strings.TrimPrefix(k, prefix), "/")
+		if len(segments) != 2 {
+			continue
+		}
+		includer, err := unescapeName(segments[0])
+		if err != nil {
+			span.SetStatus(codes.Error, err.Error())
+			return nil, fmt.Errorf("malformed include index key %s: %w", k, err)
+		}
+		doc, err := cr.GetGroupDocument(includer, segments[1], ctx)
+		if errors.Is(err, model.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			span.SetStatus(codes.Error, err.Error())
+			return nil, err
+		}
+		for _, include := range doc.Includes {
+			if include.Name == name && model.ToString(include.Version) == version {
+				groups = append(groups, model.ConfigurationGroup{Name: doc.Name, Version: doc.Version, Includes: doc.Includes})
+				break
+			}
+		}
+	}
+
+	span.SetStatus(codes.Ok, "Success fetching include index")
+	return groups, nil
+}
+
+// BuildIncludeIndex indexes the includes of the group documents written before the include index existed. It runs
+// once, a marker key records that it completed. It returns the number of entries written.
+func (cr *ConfigRepository) BuildIncludeIndex(ctx context.Context) (int, error) {
+	_, span := cr.Tracer.Start(ctx, "ConfigRepository.BuildIncludeIndex")
+	defer span.End()
+
+	kv := cr.cli.KV()
+	marker, _, err := kv.Get(ConstructMigrationKey(includeIndexMigration), nil)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return 0, err
+	}
+	if marker != nil {
+		span.SetStatus(codes.Ok, "Migration already applied")
+		return 0, nil
+	}
+
+	docs, _, err := kv.List(groupDocumentFolder, nil)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return 0, err
+	}
+	var keys []string
+	for _, pair := range docs {
+		doc := model.GroupDocument{}
+		if err = json.Unmarshal(pair.Value, &doc); err != nil {
+			span.SetStatus(codes.Error, err.Error())
+			return 0, err
+		}
+		keys = append(keys, includeIndexKeys(doc.Name, model.ToString(doc.Version), doc.Includes)...)
+	}
+
+	if err = cr.addIndex(keys); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return 0, err
+	}
+	if _, err = kv.Put(&api.KVPair{Key: ConstructMigrationKey(includeIndexMigration), Value: []byte(time.Now().UTC().Format(time.RFC3339))}, nil); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return 0, err
+	}
+
+	span.SetStatus(codes.Ok, "Successfully built include index")
+	return len(keys), nil
+}
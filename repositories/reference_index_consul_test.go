@@ -0,0 +1,29 @@
+package repositories
+
+import (
+	"ars_projekat/model"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemberIndexKeys_Source(t *testing.T) {
+	embedded := model.Configuration{Name: "cache", Labels: model.Labels{"env": "prod"}}
+	sourced := model.Configuration{Name: "database", Labels: model.Labels{"env": "prod"}, Source: &model.ConfigReference{Name: "team/db", Version: "^1"}}
+
+	assert.Equal(t, []string{ConstructGroupLabelKey("env", "prod", "backend", "1.0.0", "env:prod/cache")}, memberIndexKeys("backend", "1.0.0", embedded))
+	assert.Equal(t, []string{
+		ConstructGroupLabelKey("env", "prod", "backend", "1.0.0", "env:prod/database"),
+		"reference-index/sources/team%2Fdb/backend/1.0.0/env:prod%2Fdatabase",
+	}, memberIndexKeys("backend", "1.0.0", sourced))
+}
+
+func TestIncludeIndexKeys(t *testing.T) {
+	includes := []model.GroupInclude{{Name: "platform/base", Version: model.Version{Major: 1}}, {Name: "logging", Version: model.Version{Major: 2, Minor: 1}}}
+
+	assert.Equal(t, []string{
+		"reference-index/includes/platform%2Fbase/1.0.0/orders/1.0.0",
+		"reference-index/includes/logging/2.1.0/orders/1.0.0",
+	}, includeIndexKeys("orders", "1.0.0", includes))
+	assert.Empty(t, includeIndexKeys("orders", "1.0.0", nil))
+}
@@ -2,11 +2,15 @@ package repositories
 
 import (
 	"fmt"
+	"net/url"
+	"time"
 )
 
 const (
-	configurations = "configs/%s/%s/"
-	allConfigs     = "configs"
+	configurations   = "configs/%s/%s/"
+	configNamePrefix = "configs/%s/"
+	allConfigs       = "configs"
+	configFolder     = "configs/"
 )
 
 var (
@@ -14,21 +18,189 @@ var (
 	allGroups = "groups"
 )
 
+const (
+	labelIndex        = "label-index/"
+	labelIndexConfigs = "configs"
+	labelIndexGroups  = "groups"
+)
+
+const (
+	referenceIndex         = "reference-index/"
+	referenceIndexSources  = "sources"
+	referenceIndexIncludes = "includes"
+)
+
+const (
+	groupDocumentFolder = "group-docs/"
+	groupDocuments      = "group-docs/%s/%s"
+	objectIds           = "ids/%020d"
+	allObjectIds        = "ids/"
+	updateIndex         = "update-index/"
+	watchStamps         = "watch/"
+)
+
 const (
 	idempotencyRequests = "idempotency_requests/%s/"
+	migrations          = "migrations/%s"
+)
+
+const (
+	trashedConfigs    = "trash/configs/%s/%s"
+	trashedGroups     = "trash/groups/%s/%s/"
+	allTrashedConfigs = "trash/configs/"
+	allTrashedGroups  = "trash/groups/"
+)
+
+const (
+	retentionPolicies   = "retention/policies/%s"
+	allRetentionPolices = "retention/policies/"
+)
+
+const (
+	schemas    = "schemas/%s/"
+	allSchemas = "schemas/"
+)
+
+const (
+	configRevisions    = "revisions/configs/%s/%s/"
+	groupRevisions     = "revisions/groups/%s/%s/"
+	allConfigRevisions = "revisions/configs/"
+	allGroupRevisions  = "revisions/groups/"
+	revisionNumber     = "%020d"
 )
 
+// escapeName keeps hierarchical names such as team/payments/db in a single key segment, names without
+// reserved characters are stored unchanged.
+func escapeName(name string) string {
+	return url.PathEscape(name)
+}
+
+// unescapeName reads a name back out of a key segment written with escapeName.
+func unescapeName(segment string) (string, error) {
+	return url.PathUnescape(segment)
+}
+
+func ConstructConfigNamePrefix(name string) string {
+	return fmt.Sprintf(configNamePrefix, escapeName(name))
+}
+
+// ConstructConfigFolderPrefix returns the key prefix of every config whose name starts with prefix.
+func ConstructConfigFolderPrefix(prefix string) string {
+	return configFolder + escapeName(prefix)
+}
+
 func ConstructConfigKey(name string, version string) string {
-	return fmt.Sprintf(configurations, name, version)
+	return fmt.Sprintf(configurations, escapeName(name), version)
 }
 
 func ConstructConfigGroupKey(name string, version string, labels string, configName string) string {
 	if labels == "" {
-		return fmt.Sprintf("groups/%s/%s/%s", name, version, configName)
+		return fmt.Sprintf("groups/%s/%s/%s", escapeName(name), version, escapeName(configName))
 	}
-	return fmt.Sprintf(groups, name, version, labels, configName)
+	return fmt.Sprintf(groups, escapeName(name), version, labels, escapeName(configName))
 }
 
 func ConstructIdempotencyRequestKey(key string) string {
 	return fmt.Sprintf(idempotencyRequests, key)
 }
+
+func ConstructConfigRevisionPrefix(name string, version string) string {
+	return fmt.Sprintf(configRevisions, escapeName(name), version)
+}
+
+func ConstructGroupRevisionPrefix(name string, version string) string {
+	return fmt.Sprintf(groupRevisions, escapeName(name), version)
+}
+
+func ConstructRevisionKey(prefix string, number int64) string {
+	return prefix + fmt.Sprintf(revisionNumber, number)
+}
+
+func ConstructRetentionPolicyKey(name string) string {
+	return fmt.Sprintf(retentionPolicies, name)
+}
+
+func ConstructTrashedConfigKey(name string, version string) string {
+	return fmt.Sprintf(trashedConfigs, escapeName(name), version)
+}
+
+func ConstructTrashedGroupPrefix(name string, version string) string {
+	return fmt.Sprintf(trashedGroups, escapeName(name), version)
+}
+
+func ConstructTrashedGroupKey(name string, version string, deletedAt time.Time) string {
+	return ConstructTrashedGroupPrefix(name, version) + fmt.Sprintf("%020d", deletedAt.UnixNano())
+}
+
+func ConstructSchemaPrefix(name string) string {
+	return fmt.Sprintf(schemas, escapeName(name))
+}
+
+func ConstructGroupDocumentKey(name string, version string) string {
+	return fmt.Sprintf(groupDocuments, escapeName(name), version)
+}
+
+// ConstructGroupDocumentFolderPrefix returns the key prefix of the documents of every group whose name starts with
+// prefix.
+func ConstructGroupDocumentFolderPrefix(prefix string) string {
+	return groupDocumentFolder + escapeName(prefix)
+}
+
+// ConstructUpdateIndexPrefix returns the prefix of the update time index entries of the objects of kind whose name
+// starts with prefix.
+func ConstructUpdateIndexPrefix(kind string, prefix string) string {
+	return updateIndex + kind + "/" + escapeName(prefix)
+}
+
+func ConstructUpdateIndexKey(kind string, name string, version string) string {
+	return ConstructUpdateIndexPrefix(kind, name) + "/" + version
+}
+
+// ConstructWatchPrefix returns the prefix of the watch stamps of the live objects of kind.
+func ConstructWatchPrefix(kind string) string {
+	return watchStamps + kind + "/"
+}
+
+func ConstructWatchKey(kind string, name string, version string) string {
+	return ConstructWatchPrefix(kind) + escapeName(name) + "/" + version
+}
+
+func ConstructObjectIdKey(id int64) string {
+	return fmt.Sprintf(objectIds, id)
+}
+
+func ConstructMigrationKey(name string) string {
+	return fmt.Sprintf(migrations, name)
+}
+
+// ConstructLabelIndexPrefix returns the prefix of the index entries of a label key, kind is configs or groups.
+func ConstructLabelIndexPrefix(kind string, key string) string {
+	return labelIndex + kind + "/" + escapeName(key) + "/"
+}
+
+func ConstructConfigLabelKey(key string, value string, name string, version string) string {
+	return ConstructLabelIndexPrefix(labelIndexConfigs, key) + escapeName(value) + "/" + escapeName(name) + "/" + version
+}
+
+// ConstructSourceIndexPrefix returns the prefix of the index entries of the group members that read the config name
+// through a source reference.
+func ConstructSourceIndexPrefix(source string) string {
+	return referenceIndex + referenceIndexSources + "/" + escapeName(source) + "/"
+}
+
+func ConstructSourceIndexKey(source string, name string, version string, member string) string {
+	return ConstructSourceIndexPrefix(source) + escapeName(name) + "/" + version + "/" + escapeName(member)
+}
+
+// ConstructIncludeIndexPrefix returns the prefix of the index entries of the groups that include the group version.
+func ConstructIncludeIndexPrefix(name string, version string) string {
+	return referenceIndex + referenceIndexIncludes + "/" + escapeName(name) + "/" + version + "/"
+}
+
+func ConstructIncludeIndexKey(included string, includedVersion string, name string, version string) string {
+	return ConstructIncludeIndexPrefix(included, includedVersion) + escapeName(name) + "/" + version
+}
+
+func ConstructGroupLabelKey(key string, value string, name string, version string, member string) string {
+	return ConstructLabelIndexPrefix(labelIndexGroups, key) + escapeName(value) + "/" + escapeName(name) + "/" + version + "/" + escapeName(member)
+}
@@ -0,0 +1,97 @@
+package repositories
+
+import (
+	"ars_projekat/model"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/hashicorp/consul/api"
+	"go.opentelemetry.io/otel/codes"
+)
+
+func (cr *ConfigRepository) AddRetentionPolicy(policy *model.RetentionPolicy, ctx context.Context) error {
+	_, span := cr.Tracer.Start(ctx, "RetentionRepository.AddRetentionPolicy")
+	defer span.End()
+
+	kv := cr.cli.KV()
+
+	data, err := json.Marshal(policy)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+
+	keyValue := &api.KVPair{Key: ConstructRetentionPolicyKey(policy.Name), Value: data}
+	_, err = kv.Put(keyValue, nil)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "Successfully added retention policy")
+	return nil
+}
+
+func (cr *ConfigRepository) GetRetentionPolicies(ctx context.Context) ([]model.RetentionPolicy, error) {
+	_, span := cr.Tracer.Start(ctx, "RetentionRepository.GetRetentionPolicies")
+	defer span.End()
+
+	kv := cr.cli.KV()
+	data, _, err := kv.List(allRetentionPolices, nil)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	var policies []model.RetentionPolicy
+	for _, pair := range data {
+		policy := model.RetentionPolicy{}
+		if err = json.Unmarshal(pair.Value, &policy); err != nil {
+			span.SetStatus(codes.Error, err.Error())
+			return nil, err
+		}
+		policies = append(policies, policy)
+	}
+
+	span.SetStatus(codes.Ok, "Success fetching retention policies")
+	return policies, nil
+}
+
+func (cr *ConfigRepository) GetRetentionPolicy(name string, ctx context.Context) (*model.RetentionPolicy, error) {
+	_, span := cr.Tracer.Start(ctx, "RetentionRepository.GetRetentionPolicy")
+	defer span.End()
+
+	kv := cr.cli.KV()
+	data, _, err := kv.Get(ConstructRetentionPolicyKey(name), nil)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	if data == nil {
+		return nil, fmt.Errorf("retention policy %s %w", name, model.ErrNotFound)
+	}
+
+	policy := &model.RetentionPolicy{}
+	if err = json.Unmarshal(data.Value, policy); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	span.SetStatus(codes.Ok, "Success fetching retention policy")
+	return policy, nil
+}
+
+func (cr *ConfigRepository) DeleteRetentionPolicy(name string, ctx context.Context) error {
+	_, span := cr.Tracer.Start(ctx, "RetentionRepository.DeleteRetentionPolicy")
+	defer span.End()
+
+	kv := cr.cli.KV()
+	if _, err := kv.Delete(ConstructRetentionPolicyKey(name), nil); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "Success deleting retention policy")
+	return nil
+}
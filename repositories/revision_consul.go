@@ -0,0 +1,208 @@
+package repositories
+
+import (
+	"ars_projekat/model"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/consul/api"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// Revisions are immutable, a revision key is written once with a CAS on ModifyIndex 0 in the same transaction as
+// the write it records. When two writers race for the same number the loser retries with the next one, which keeps
+// numbers monotonic per object.
+const revisionWriteAttempts = 5
+
+// configRevisionOp numbers rev as the next revision of the configuration and returns the op that stores it.
+func (cr *ConfigRepository) configRevisionOp(name string, version string, rev *model.ConfigurationRevision) (*api.TxnOp, error) {
+	return cr.revisionOp(ConstructConfigRevisionPrefix(name, version), func(number int64) ([]byte, error) {
+		rev.Number = number
+		return json.Marshal(rev)
+	})
+}
+
+// groupRevisionOp numbers rev as the next revision of the group and returns the op that stores it.
+func (cr *ConfigRepository) groupRevisionOp(name string, version string, rev *model.GroupRevision) (*api.TxnOp, error) {
+	return cr.revisionOp(ConstructGroupRevisionPrefix(name, version), func(number int64) ([]byte, error) {
+		rev.Number = number
+		return json.Marshal(rev)
+	})
+}
+
+func (cr *ConfigRepository) GetConfigRevisions(name string, version string, ctx context.Context) ([]model.ConfigurationRevision, error) {
+	_, span := cr.Tracer.Start(ctx, "RevisionRepository.GetConfigRevisions")
+	defer span.End()
+
+	kv := cr.cli.KV()
+	data, _, err := kv.List(ConstructConfigRevisionPrefix(name, version), nil)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	var revisions []model.ConfigurationRevision
+	for _, pair := range data {
+		rev := model.ConfigurationRevision{}
+		if err = json.Unmarshal(pair.Value, &rev); err != nil {
+			span.SetStatus(codes.Error, err.Error())
+			return nil, err
+		}
+		revisions = append(revisions, rev)
+	}
+
+	span.SetStatus(codes.Ok, "Success fetching configuration revisions")
+	return revisions, nil
+}
+
+func (cr *ConfigRepository) GetConfigRevision(name string, version string, number int64, ctx context.Context) (*model.ConfigurationRevision, error) {
+	_, span := cr.Tracer.Start(ctx, "RevisionRepository.GetConfigRevision")
+	defer span.End()
+
+	kv := cr.cli.KV()
+	data, _, err := kv.Get(ConstructRevisionKey(ConstructConfigRevisionPrefix(name, version), number), nil)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	if data == nil {
+		return nil, fmt.Errorf("revision %d %w", number, model.ErrNotFound)
+	}
+
+	rev := &model.ConfigurationRevision{}
+	if err = json.Unmarshal(data.Value, rev); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	span.SetStatus(codes.Ok, "Success fetching configuration revision")
+	return rev, nil
+}
+
+func (cr *ConfigRepository) GetGroupRevisions(name string, version string, ctx context.Context) ([]model.GroupRevision, error) {
+	_, span := cr.Tracer.Start(ctx, "RevisionRepository.GetGroupRevisions")
+	defer span.End()
+
+	kv := cr.cli.KV()
+	data, _, err := kv.List(ConstructGroupRevisionPrefix(name, version), nil)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	var revisions []model.GroupRevision
+	for _, pair := range data {
+		rev := model.GroupRevision{}
+		if err = json.Unmarshal(pair.Value, &rev); err != nil {
+			span.SetStatus(codes.Error, err.Error())
+			return nil, err
+		}
+		revisions = append(revisions, rev)
+	}
+
+	span.SetStatus(codes.Ok, "Success fetching group revisions")
+	return revisions, nil
+}
+
+func (cr *ConfigRepository) GetGroupRevision(name string, version string, number int64, ctx context.Context) (*model.GroupRevision, error) {
+	_, span := cr.Tracer.Start(ctx, "RevisionRepository.GetGroupRevision")
+	defer span.End()
+
+	kv := cr.cli.KV()
+	data, _, err := kv.Get(ConstructRevisionKey(ConstructGroupRevisionPrefix(name, version), number), nil)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	if data == nil {
+		return nil, fmt.Errorf("revision %d %w", number, model.ErrNotFound)
+	}
+
+	rev := &model.GroupRevision{}
+	if err = json.Unmarshal(data.Value, rev); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	span.SetStatus(codes.Ok, "Success fetching group revision")
+	return rev, nil
+}
+
+func (cr *ConfigRepository) revisionOp(prefix string, encode func(number int64) ([]byte, error)) (*api.TxnOp, error) {
+	keys, _, err := cr.cli.KV().Keys(prefix, "", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var number int64 = 1
+	if len(keys) > 0 {
+		last := keys[len(keys)-1]
+		current, err := strconv.ParseInt(strings.TrimPrefix(last, prefix), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("malformed revision key %s: %w", last, err)
+		}
+		number = current + 1
+	}
+
+	data, err := encode(number)
+	if err != nil {
+		return nil, err
+	}
+	return casOp(ConstructRevisionKey(prefix, number), data, 0), nil
+}
+
+// commitConfigWrite commits ops together with rev as the next revision of the configuration, rev may be nil.
+func (cr *ConfigRepository) commitConfigWrite(name string, version string, ops api.TxnOps, rev *model.ConfigurationRevision) error {
+	if rev == nil {
+		return cr.commit(ops)
+	}
+	return cr.commitNumbered(ops, func() (*api.TxnOp, error) {
+		return cr.configRevisionOp(name, version, rev)
+	})
+}
+
+// putRevision stores the next numbered entry under prefix on its own.
+func (cr *ConfigRepository) putRevision(prefix string, encode func(number int64) ([]byte, error)) error {
+	return cr.commitNumbered(nil, func() (*api.TxnOp, error) {
+		return cr.revisionOp(prefix, encode)
+	})
+}
+
+// commitNumbered commits ops together with the numbered entry next builds, the transaction is retried with a fresh
+// number when a concurrent write took it.
+func (cr *ConfigRepository) commitNumbered(ops api.TxnOps, next func() (*api.TxnOp, error)) error {
+	for attempt := 1; ; attempt++ {
+		op, err := next()
+		if err != nil {
+			return err
+		}
+		commitErr := cr.commit(append(ops[:len(ops):len(ops)], op))
+		if commitErr == nil || !errors.Is(commitErr, errRolledBack) {
+			return commitErr
+		}
+		taken, err := cr.revisionTaken(op)
+		if err != nil {
+			return err
+		}
+		if !taken {
+			return commitErr
+		}
+		if attempt == revisionWriteAttempts {
+			return errors.New("could not allocate revision number, too many concurrent writes")
+		}
+	}
+}
+
+// revisionTaken reports whether the revision key of op was written by someone else, only then is a rolled back
+// transaction worth retrying.
+func (cr *ConfigRepository) revisionTaken(op *api.TxnOp) (bool, error) {
+	pair, _, err := cr.cli.KV().Get(op.KV.Key, nil)
+	if err != nil {
+		return false, err
+	}
+	return pair != nil, nil
+}
@@ -0,0 +1,121 @@
+package repositories
+
+import (
+	"ars_projekat/model"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"go.opentelemetry.io/otel/codes"
+)
+
+// AddSchema stores a new version of the schema, versions are allocated the same way as revision numbers.
+func (cr *ConfigRepository) AddSchema(schema *model.Schema, ctx context.Context) error {
+	_, span := cr.Tracer.Start(ctx, "SchemaRepository.AddSchema")
+	defer span.End()
+
+	err := cr.putRevision(ConstructSchemaPrefix(schema.Name), func(number int64) ([]byte, error) {
+		schema.Version = number
+		return json.Marshal(schema)
+	})
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "Successfully added schema")
+	return nil
+}
+
+// GetSchemas returns the latest version of every registered schema.
+func (cr *ConfigRepository) GetSchemas(ctx context.Context) ([]model.Schema, error) {
+	_, span := cr.Tracer.Start(ctx, "SchemaRepository.GetSchemas")
+	defer span.End()
+
+	all, err := cr.listSchemas(allSchemas)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	var schemas []model.Schema
+	for i, s := range all {
+		if i+1 < len(all) && all[i+1].Name == s.Name {
+			continue
+		}
+		schemas = append(schemas, s)
+	}
+
+	span.SetStatus(codes.Ok, "Success fetching schemas")
+	return schemas, nil
+}
+
+func (cr *ConfigRepository) GetSchemaVersions(name string, ctx context.Context) ([]model.Schema, error) {
+	_, span := cr.Tracer.Start(ctx, "SchemaRepository.GetSchemaVersions")
+	defer span.End()
+
+	schemas, err := cr.listSchemas(ConstructSchemaPrefix(name))
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	span.SetStatus(codes.Ok, "Success fetching schema versions")
+	return schemas, nil
+}
+
+func (cr *ConfigRepository) GetSchema(name string, version int64, ctx context.Context) (*model.Schema, error) {
+	_, span := cr.Tracer.Start(ctx, "SchemaRepository.GetSchema")
+	defer span.End()
+
+	kv := cr.cli.KV()
+	data, _, err := kv.Get(ConstructRevisionKey(ConstructSchemaPrefix(name), version), nil)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	if data == nil {
+		return nil, fmt.Errorf("schema %s version %d %w", name, version, model.ErrNotFound)
+	}
+
+	schema := &model.Schema{}
+	if err = json.Unmarshal(data.Value, schema); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	span.SetStatus(codes.Ok, "Success fetching schema")
+	return schema, nil
+}
+
+func (cr *ConfigRepository) DeleteSchema(name string, ctx context.Context) error {
+	_, span := cr.Tracer.Start(ctx, "SchemaRepository.DeleteSchema")
+	defer span.End()
+
+	kv := cr.cli.KV()
+	if _, err := kv.DeleteTree(ConstructSchemaPrefix(name), nil); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "Successfully deleted schema")
+	return nil
+}
+
+func (cr *ConfigRepository) listSchemas(prefix string) ([]model.Schema, error) {
+	kv := cr.cli.KV()
+	data, _, err := kv.List(prefix, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var schemas []model.Schema
+	for _, pair := range data {
+		schema := model.Schema{}
+		if err = json.Unmarshal(pair.Value, &schema); err != nil {
+			return nil, err
+		}
+		schemas = append(schemas, schema)
+	}
+	return schemas, nil
+}
@@ -0,0 +1,410 @@
+package repositories
+
+import (
+	"ars_projekat/model"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/hashicorp/consul/api"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// TrashConfig moves a configuration to the trash, both keys change and rev is recorded in a single transaction.
+func (cr *ConfigRepository) TrashConfig(trashed *model.TrashedConfiguration, rev *model.ConfigurationRevision, ctx context.Context) error {
+	_, span := cr.Tracer.Start(ctx, "TrashRepository.TrashConfig")
+	defer span.End()
+
+	data, err := json.Marshal(trashed)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+
+	name := trashed.Configuration.Name
+	version := model.ToString(trashed.Configuration.Version)
+	ops := api.TxnOps{
+		setOp(ConstructTrashedConfigKey(name, version), data),
+		deleteOp(ConstructConfigKey(name, version)),
+		deleteOp(ConstructWatchKey(model.KindConfig, name, version)),
+	}
+	if err = cr.commitConfigWrite(name, version, ops, rev); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	if err = cr.removeIndex(configIndexKeys(trashed.Configuration), nil); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "Successfully moved configuration to trash")
+	return nil
+}
+
+// TrashGroup moves trashed.Configurations, the members whose labels include trashed.Labels, to the trash and takes
+// them off the group document. With trashed.Member only that member is trashed, and it fails with
+// model.ErrNotFound when the group no longer lists it. Without labels or member the whole group is trashed, its
+// document along with the members.
+func (cr *ConfigRepository) TrashGroup(trashed *model.TrashedGroup, rev *model.GroupRevision, ctx context.Context) error {
+	_, span := cr.Tracer.Start(ctx, "TrashRepository.TrashGroup")
+	defer span.End()
+
+	version := model.ToString(trashed.Version)
+	var dropped []model.GroupInclude
+	err := cr.updateGroupDocument(trashed.Name, version, rev, func(doc *model.GroupDocument, exists bool) (api.TxnOps, bool, error) {
+		whole := trashed.Labels == "" && trashed.Member == nil
+		if trashed.Member != nil && (!exists || !doc.HasMember(*trashed.Member)) {
+			return nil, false, fmt.Errorf("member %s of group %s %s %w", trashed.Member.Name, trashed.Name, version, model.ErrNotFound)
+		}
+		if whole && exists {
+			kept := *doc
+			trashed.Document = &kept
+			dropped = doc.Includes
+		}
+		for _, config := range trashed.Configurations {
+			doc.RemoveMember(model.MemberOf(config))
+		}
+		data, err := json.Marshal(trashed)
+		if err != nil {
+			return nil, false, err
+		}
+		ops := api.TxnOps{
+			setOp(ConstructTrashedGroupKey(trashed.Name, version, trashed.DeletedAt), data),
+		}
+		ops = append(ops, deleteMembersOps(trashed.Name, version, whole, trashed.Configurations)...)
+		return ops, exists && !whole, nil
+	})
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	if err = cr.removeIndex(membersIndexKeys(trashed.Name, version, trashed.Configurations), nil); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	if err = cr.removeIndex(includeIndexKeys(trashed.Name, version, dropped), nil); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "Successfully moved configuration group to trash")
+	return nil
+}
+
+func (cr *ConfigRepository) GetTrashedConfig(name string, version string, ctx context.Context) (*model.TrashedConfiguration, error) {
+	_, span := cr.Tracer.Start(ctx, "TrashRepository.GetTrashedConfig")
+	defer span.End()
+
+	kv := cr.cli.KV()
+	data, _, err := kv.Get(ConstructTrashedConfigKey(name, version), nil)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	if data == nil {
+		return nil, fmt.Errorf("trashed config %s %s %w", name, version, model.ErrNotFound)
+	}
+
+	trashed := &model.TrashedConfiguration{}
+	if err = json.Unmarshal(data.Value, trashed); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	span.SetStatus(codes.Ok, "Success fetching trashed configuration")
+	return trashed, nil
+}
+
+// GetTrashedGroups returns every trashed part of a group version, oldest delete first.
+func (cr *ConfigRepository) GetTrashedGroups(name string, version string, ctx context.Context) ([]model.TrashedGroup, error) {
+	_, span := cr.Tracer.Start(ctx, "TrashRepository.GetTrashedGroups")
+	defer span.End()
+
+	groups, err := cr.listTrashedGroups(ConstructTrashedGroupPrefix(name, version))
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	span.SetStatus(codes.Ok, "Success fetching trashed configuration groups")
+	return groups, nil
+}
+
+func (cr *ConfigRepository) GetTrash(ctx context.Context) (*model.Trash, error) {
+	_, span := cr.Tracer.Start(ctx, "TrashRepository.GetTrash")
+	defer span.End()
+
+	kv := cr.cli.KV()
+	data, _, err := kv.List(allTrashedConfigs, nil)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	trash := &model.Trash{Configurations: []model.TrashedConfiguration{}}
+	for _, pair := range data {
+		trashed := model.TrashedConfiguration{}
+		if err = json.Unmarshal(pair.Value, &trashed); err != nil {
+			span.SetStatus(codes.Error, err.Error())
+			return nil, err
+		}
+		trash.Configurations = append(trash.Configurations, trashed)
+	}
+
+	trash.Groups, err = cr.listTrashedGroups(allTrashedGroups)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	span.SetStatus(codes.Ok, "Success fetching trash")
+	return trash, nil
+}
+
+// RestoreConfig moves a trashed configuration back, the transaction fails if the configuration was re-created meanwhile.
+func (cr *ConfigRepository) RestoreConfig(config *model.Configuration, rev *model.ConfigurationRevision, ctx context.Context) error {
+	_, span := cr.Tracer.Start(ctx, "TrashRepository.RestoreConfig")
+	defer span.End()
+
+	data, err := json.Marshal(config)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+
+	name := config.Name
+	version := model.ToString(config.Version)
+	if err = cr.addIndex(configIndexKeys(*config)); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	ops := api.TxnOps{
+		{KV: &api.KVTxnOp{Verb: api.KVCheckNotExists, Key: ConstructConfigKey(name, version)}},
+		setOp(ConstructConfigKey(name, version), data),
+		setOp(ConstructWatchKey(model.KindConfig, name, version), nil),
+		deleteOp(ConstructTrashedConfigKey(name, version)),
+	}
+	if err = cr.commitConfigWrite(name, version, ops, rev); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "Successfully restored configuration")
+	return nil
+}
+
+// RestoreGroup writes the given members back and empties the trash of the group version. The members are listed
+// in the group document and the trash is emptied in one transaction, members of large groups are written ahead of
+// it. A group deleted as a whole gets the ID, metadata and includes of its trashed document back. It fails with
+// model.ErrAlreadyExists when the group lists one of the members again.
+func (cr *ConfigRepository) RestoreGroup(name string, version string, configs []model.Configuration, rev *model.GroupRevision, ctx context.Context) error {
+	_, span := cr.Tracer.Start(ctx, "TrashRepository.RestoreGroup")
+	defer span.End()
+
+	trashed, err := cr.listTrashedGroups(ConstructTrashedGroupPrefix(name, version))
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	indexed := membersIndexKeys(name, version, configs)
+	// The includes come back only when the group is restored as a whole, the entries are released after otherwise.
+	var included []string
+	for i := len(trashed) - 1; i >= 0; i-- {
+		if trashed[i].Document != nil {
+			included = includeIndexKeys(name, version, trashed[i].Document.Includes)
+			break
+		}
+	}
+	if err = cr.addIndex(append(indexed, included...)); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	ops := api.TxnOps{deleteTreeOp(ConstructTrashedGroupPrefix(name, version))}
+	for _, config := range configs {
+		data, err := json.Marshal(config)
+		if err != nil {
+			span.SetStatus(codes.Error, err.Error())
+			return err
+		}
+		key := ConstructConfigGroupKey(name, version, model.SortLabels(config.Labels), config.Name)
+		ops = append(ops, setOp(key, data))
+	}
+
+	var restored []model.GroupInclude
+	err = cr.updateGroupDocument(name, version, rev, func(doc *model.GroupDocument, exists bool) (api.TxnOps, bool, error) {
+		for i := len(trashed) - 1; i >= 0 && !exists; i-- {
+			if trashed[i].Document != nil {
+				doc.Id = trashed[i].Document.Id
+				doc.Includes = trashed[i].Document.Includes
+				doc.Metadata = trashed[i].Document.Metadata
+				break
+			}
+		}
+		for _, config := range configs {
+			member := model.MemberOf(config)
+			if doc.HasMember(member) {
+				return nil, false, fmt.Errorf("member %s of group %s %s with labels %q %w", config.Name, name, version, member.Labels, model.ErrAlreadyExists)
+			}
+			doc.AddMember(member)
+		}
+		restored = doc.Includes
+		return ops, true, nil
+	})
+	if err != nil {
+		cr.releaseIndexAfter(name, version, indexed)
+		cr.releaseIncludesAfter(name, version, included)
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	if err = cr.removeIndex(included, includeIndexKeys(name, version, restored)); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "Successfully restored configuration group")
+	return nil
+}
+
+// PurgeConfig removes a trashed configuration for good, together with its ID index entry unless a configuration
+// stored again under the same name and version holds the ID, and its update time index entry unless one is stored.
+func (cr *ConfigRepository) PurgeConfig(name string, version string, ctx context.Context) error {
+	_, span := cr.Tracer.Start(ctx, "TrashRepository.PurgeConfig")
+	defer span.End()
+
+	kv := cr.cli.KV()
+	pair, _, err := kv.Get(ConstructTrashedConfigKey(name, version), nil)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	if pair == nil {
+		span.SetStatus(codes.Ok, "Configuration already purged")
+		return nil
+	}
+	trashed := model.TrashedConfiguration{}
+	if err = json.Unmarshal(pair.Value, &trashed); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+
+	var liveId int64
+	live, _, err := kv.Get(ConstructConfigKey(name, version), nil)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	if live != nil {
+		config := model.Configuration{}
+		if err = json.Unmarshal(live.Value, &config); err != nil {
+			span.SetStatus(codes.Error, err.Error())
+			return err
+		}
+		liveId = config.Id
+	}
+
+	ops := api.TxnOps{deleteCASOp(pair.Key, pair.ModifyIndex)}
+	if live == nil {
+		ops = append(ops, deleteOp(ConstructUpdateIndexKey(model.KindConfig, name, version)))
+	}
+	if ops, err = cr.releaseIdOps(ops, []int64{trashed.Configuration.Id}, liveId); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	if err = cr.commit(ops); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "Successfully purged configuration")
+	return nil
+}
+
+// PurgeGroup removes every trashed part of a group version for good, together with the ID index entries of the
+// trashed group documents unless the group stored again under the same name and version holds the ID, and the update
+// time index entry unless the group is stored again.
+func (cr *ConfigRepository) PurgeGroup(name string, version string, ctx context.Context) error {
+	_, span := cr.Tracer.Start(ctx, "TrashRepository.PurgeGroup")
+	defer span.End()
+
+	trashed, err := cr.listTrashedGroups(ConstructTrashedGroupPrefix(name, version))
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	var ids []int64
+	for _, t := range trashed {
+		if t.Document != nil {
+			ids = append(ids, t.Document.Id)
+		}
+	}
+
+	kv := cr.cli.KV()
+	var liveId int64
+	live, _, err := kv.Get(ConstructGroupDocumentKey(name, version), nil)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	if live != nil {
+		doc := model.GroupDocument{}
+		if err = json.Unmarshal(live.Value, &doc); err != nil {
+			span.SetStatus(codes.Error, err.Error())
+			return err
+		}
+		liveId = doc.Id
+	}
+
+	ops := api.TxnOps{deleteTreeOp(ConstructTrashedGroupPrefix(name, version))}
+	if live == nil {
+		ops = append(ops, deleteOp(ConstructUpdateIndexKey(model.KindGroup, name, version)))
+	}
+	if ops, err = cr.releaseIdOps(ops, ids, liveId); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	if err = cr.commitChunks(ops); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "Successfully purged configuration group")
+	return nil
+}
+
+// releaseIdOps appends the deletes of the ID index entries of purged objects to ops. IDs that are unset, already
+// released or held by liveId, the object now stored under the same name and version, are kept.
+func (cr *ConfigRepository) releaseIdOps(ops api.TxnOps, ids []int64, liveId int64) (api.TxnOps, error) {
+	released := make(map[int64]bool)
+	for _, id := range ids {
+		if id == 0 || id == liveId || released[id] {
+			continue
+		}
+		released[id] = true
+		pair, _, err := cr.cli.KV().Get(ConstructObjectIdKey(id), nil)
+		if err != nil {
+			return nil, err
+		}
+		if pair != nil {
+			ops = append(ops, deleteCASOp(pair.Key, pair.ModifyIndex))
+		}
+	}
+	return ops, nil
+}
+
+func (cr *ConfigRepository) listTrashedGroups(prefix string) ([]model.TrashedGroup, error) {
+	kv := cr.cli.KV()
+	data, _, err := kv.List(prefix, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := []model.TrashedGroup{}
+	for _, pair := range data {
+		trashed := model.TrashedGroup{}
+		if err = json.Unmarshal(pair.Value, &trashed); err != nil {
+			return nil, err
+		}
+		groups = append(groups, trashed)
+	}
+	return groups, nil
+}
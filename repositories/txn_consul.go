@@ -0,0 +1,57 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/consul/api"
+)
+
+// Consul rejects transactions with more than 64 operations.
+const maxTxnOps = 64
+
+// errRolledBack is returned when a check in a transaction failed, such as a check-and-set on a changed key.
+var errRolledBack = errors.New("transaction rolled back")
+
+// commit applies all operations in a single Consul transaction, either every operation is applied or none is.
+func (cr *ConfigRepository) commit(ops api.TxnOps) error {
+	if len(ops) > maxTxnOps {
+		return fmt.Errorf("transaction has %d operations, consul allows at most %d", len(ops), maxTxnOps)
+	}
+
+	ok, resp, _, err := cr.cli.Txn().Txn(ops, nil)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		var reasons []string
+		for _, e := range resp.Errors {
+			reasons = append(reasons, fmt.Sprintf("op %d: %s", e.OpIndex, e.What))
+		}
+		return fmt.Errorf("%w: %s", errRolledBack, strings.Join(reasons, "; "))
+	}
+	return nil
+}
+
+func setOp(key string, value []byte) *api.TxnOp {
+	return &api.TxnOp{KV: &api.KVTxnOp{Verb: api.KVSet, Key: key, Value: value}}
+}
+
+func deleteOp(key string) *api.TxnOp {
+	return &api.TxnOp{KV: &api.KVTxnOp{Verb: api.KVDelete, Key: key}}
+}
+
+func deleteTreeOp(prefix string) *api.TxnOp {
+	return &api.TxnOp{KV: &api.KVTxnOp{Verb: api.KVDeleteTree, Key: prefix}}
+}
+
+// casOp writes key only if it was not modified since index, an index of 0 only creates it.
+func casOp(key string, value []byte, index uint64) *api.TxnOp {
+	return &api.TxnOp{KV: &api.KVTxnOp{Verb: api.KVCAS, Key: key, Value: value, Index: index}}
+}
+
+// deleteCASOp deletes key only if it was not modified since index.
+func deleteCASOp(key string, index uint64) *api.TxnOp {
+	return &api.TxnOp{KV: &api.KVTxnOp{Verb: api.KVDeleteCAS, Key: key, Index: index}}
+}
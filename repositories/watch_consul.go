@@ -0,0 +1,231 @@
+package repositories
+
+import (
+	"ars_projekat/model"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// Every live configuration and group version has an empty watch stamp under watch/{kind}/{name}/{version}, written
+// and deleted in the same transaction as the object. Watches block on the stamps, whose modify indexes name the
+// objects that changed, so a wake-up reads only those objects instead of the whole keyspace.
+
+// groupKeys is the prefix shared by group members and group documents, one list reads both.
+const groupKeys = "group"
+
+const watchIndexMigration = "watch-index"
+
+// parseWatchKey returns the object a watch stamp of kind stands for, or nil when key is not a stamp.
+func parseWatchKey(kind string, key string) (*model.ObjectRef, error) {
+	segments := strings.Split(strings.TrimPrefix(key, ConstructWatchPrefix(kind)), "/")
+	if len(segments) != 2 {
+		return nil, nil
+	}
+	name, err := unescapeName(segments[0])
+	if err != nil {
+		return nil, fmt.Errorf("malformed watch key %s: %w", key, err)
+	}
+	version, err := model.ToVersion(segments[1])
+	if err != nil {
+		return nil, fmt.Errorf("malformed watch key %s: %w", key, err)
+	}
+	return &model.ObjectRef{Kind: kind, Name: name, Version: *version}, nil
+}
+
+// watchedObjects returns every object with a stamp of kind and those whose stamps were written after waitIndex.
+func watchedObjects(kind string, stamps api.KVPairs, waitIndex uint64) ([]model.ObjectRef, []model.ObjectRef, error) {
+	present := make([]model.ObjectRef, 0, len(stamps))
+	var changed []model.ObjectRef
+	for _, pair := range stamps {
+		ref, err := parseWatchKey(kind, pair.Key)
+		if err != nil {
+			return nil, nil, err
+		}
+		if ref == nil {
+			continue
+		}
+		present = append(present, *ref)
+		if pair.ModifyIndex > waitIndex {
+			changed = append(changed, *ref)
+		}
+	}
+	return present, changed, nil
+}
+
+// WatchConfigs returns the configurations written after waitIndex once a config was written or deleted, or when wait
+// passed without a change. Only those are read, every other config is only named in Present. A zero waitIndex
+// returns every configuration immediately. It also returns the index to wait on next.
+func (cr *ConfigRepository) WatchConfigs(waitIndex uint64, wait time.Duration, ctx context.Context) (*model.ConfigChanges, uint64, error) {
+	_, span := cr.Tracer.Start(ctx, "ConfigRepository.WatchConfigs")
+	defer span.End()
+
+	kv := cr.cli.KV()
+	stamps, meta, err := kv.List(ConstructWatchPrefix(model.KindConfig), (&api.QueryOptions{WaitIndex: waitIndex, WaitTime: wait}).WithContext(ctx))
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, waitIndex, err
+	}
+	present, changed, err := watchedObjects(model.KindConfig, stamps, waitIndex)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, waitIndex, err
+	}
+
+	// The first watch reads every config in one list rather than one by one.
+	var data api.KVPairs
+	if waitIndex == 0 {
+		if data, _, err = kv.List(configFolder, nil); err != nil {
+			span.SetStatus(codes.Error, err.Error())
+			return nil, waitIndex, err
+		}
+	} else {
+		for _, ref := range changed {
+			pair, _, err := kv.Get(ConstructConfigKey(ref.Name, model.ToString(ref.Version)), nil)
+			if err != nil {
+				span.SetStatus(codes.Error, err.Error())
+				return nil, waitIndex, err
+			}
+			// Deleted after its stamp was listed, the next wake-up drops it
+			if pair != nil {
+				data = append(data, pair)
+			}
+		}
+	}
+
+	changes := &model.ConfigChanges{Present: present}
+	for _, pair := range data {
+		config := model.Configuration{}
+		if err = json.Unmarshal(pair.Value, &config); err != nil {
+			span.SetStatus(codes.Error, err.Error())
+			return nil, waitIndex, err
+		}
+		changes.Changed = append(changes.Changed, config)
+	}
+
+	span.SetStatus(codes.Ok, "Success watching configurations")
+	return changes, meta.LastIndex, nil
+}
+
+// WatchGroups is WatchConfigs for groups. A group is rebuilt from its document and members when its stamp was
+// written after waitIndex, every member write rewrites the document and its stamp.
+func (cr *ConfigRepository) WatchGroups(waitIndex uint64, wait time.Duration, ctx context.Context) (*model.GroupChanges, uint64, error) {
+	_, span := cr.Tracer.Start(ctx, "ConfigRepository.WatchGroups")
+	defer span.End()
+
+	kv := cr.cli.KV()
+	stamps, meta, err := kv.List(ConstructWatchPrefix(model.KindGroup), (&api.QueryOptions{WaitIndex: waitIndex, WaitTime: wait}).WithContext(ctx))
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, waitIndex, err
+	}
+	present, changed, err := watchedObjects(model.KindGroup, stamps, waitIndex)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, waitIndex, err
+	}
+
+	var pairs api.KVPairs
+	if waitIndex == 0 {
+		if pairs, _, err = kv.List(groupKeys, nil); err != nil {
+			span.SetStatus(codes.Error, err.Error())
+			return nil, waitIndex, err
+		}
+	} else {
+		for _, ref := range changed {
+			version := model.ToString(ref.Version)
+			doc, _, err := kv.Get(ConstructGroupDocumentKey(ref.Name, version), nil)
+			if err != nil {
+				span.SetStatus(codes.Error, err.Error())
+				return nil, waitIndex, err
+			}
+			if doc == nil {
+				continue
+			}
+			members, _, err := kv.List(ConstructConfigGroupKey(ref.Name, version, "", ""), nil)
+			if err != nil {
+				span.SetStatus(codes.Error, err.Error())
+				return nil, waitIndex, err
+			}
+			pairs = append(append(pairs, doc), members...)
+		}
+	}
+
+	changes := &model.GroupChanges{Present: present}
+	if changes.Changed, err = groupsFromPairs(pairs); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, waitIndex, err
+	}
+
+	span.SetStatus(codes.Ok, "Success watching configuration groups")
+	return changes, meta.LastIndex, nil
+}
+
+// BuildWatchIndex stamps the configurations and group versions written before watch stamps existed, without a stamp
+// the watches take them for deleted. It runs once, a marker key records that it completed. It returns the number of
+// stamps written.
+func (cr *ConfigRepository) BuildWatchIndex(ctx context.Context) (int, error) {
+	_, span := cr.Tracer.Start(ctx, "ConfigRepository.BuildWatchIndex")
+	defer span.End()
+
+	kv := cr.cli.KV()
+	marker, _, err := kv.Get(ConstructMigrationKey(watchIndexMigration), nil)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return 0, err
+	}
+	if marker != nil {
+		span.SetStatus(codes.Ok, "Migration already applied")
+		return 0, nil
+	}
+
+	configs, _, err := kv.Keys(configFolder, "", nil)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return 0, err
+	}
+	docs, _, err := kv.Keys(groupDocumentFolder, "", nil)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return 0, err
+	}
+	var keys []string
+	for _, k := range configs {
+		ref, err := parseConfigKey(k)
+		if err != nil {
+			span.SetStatus(codes.Error, err.Error())
+			return 0, err
+		}
+		if ref != nil {
+			keys = append(keys, ConstructWatchKey(model.KindConfig, ref.Name, model.ToString(ref.Version)))
+		}
+	}
+	for _, k := range docs {
+		ref, err := parseGroupDocumentKey(k)
+		if err != nil {
+			span.SetStatus(codes.Error, err.Error())
+			return 0, err
+		}
+		if ref != nil {
+			keys = append(keys, ConstructWatchKey(model.KindGroup, ref.Name, model.ToString(ref.Version)))
+		}
+	}
+
+	ops := make(api.TxnOps, 0, len(keys)+1)
+	for _, k := range keys {
+		ops = append(ops, setOp(k, nil))
+	}
+	ops = append(ops, setOp(ConstructMigrationKey(watchIndexMigration), []byte(time.Now().UTC().Format(time.RFC3339))))
+	if err = cr.commitChunks(ops); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return 0, err
+	}
+
+	span.SetStatus(codes.Ok, "Successfully built watch index")
+	return len(keys), nil
+}
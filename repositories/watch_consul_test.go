@@ -0,0 +1,33 @@
+package repositories
+
+import (
+	"ars_projekat/model"
+	"testing"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatchedObjects_ChangedAfterWaitIndex(t *testing.T) {
+	stamps := api.KVPairs{
+		{Key: ConstructWatchKey(model.KindConfig, "team/db", "1.0.0"), ModifyIndex: 7},
+		{Key: ConstructWatchKey(model.KindConfig, "cache", "2.1.0"), ModifyIndex: 12},
+	}
+
+	present, changed, err := watchedObjects(model.KindConfig, stamps, 10)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []model.ObjectRef{
+		{Kind: model.KindConfig, Name: "team/db", Version: model.Version{Major: 1}},
+		{Kind: model.KindConfig, Name: "cache", Version: model.Version{Major: 2, Minor: 1}},
+	}, present)
+	assert.Equal(t, []model.ObjectRef{{Kind: model.KindConfig, Name: "cache", Version: model.Version{Major: 2, Minor: 1}}}, changed)
+}
+
+func TestWatchedObjects_MalformedKey(t *testing.T) {
+	stamps := api.KVPairs{{Key: "watch/group/orders/latest", ModifyIndex: 3}}
+
+	_, _, err := watchedObjects(model.KindGroup, stamps, 0)
+
+	assert.Error(t, err)
+}
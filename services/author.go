@@ -0,0 +1,20 @@
+package services
+
+import "context"
+
+type authorKey struct{}
+
+const anonymousAuthor = "anonymous"
+
+func WithAuthor(ctx context.Context, author string) context.Context {
+	return context.WithValue(ctx, authorKey{}, author)
+}
+
+// AuthorFromContext returns the author of the current request, used when recording revisions.
+func AuthorFromContext(ctx context.Context) string {
+	author, _ := ctx.Value(authorKey{}).(string)
+	if author == "" {
+		return anonymousAuthor
+	}
+	return author
+}
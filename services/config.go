@@ -4,6 +4,12 @@ import (
 	"ars_projekat/model"
 	"ars_projekat/repositories"
 	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
 	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/trace"
 )
@@ -24,7 +30,43 @@ func (s ConfigurationService) Add(config *model.Configuration, ctx context.Conte
 	ctx, span := s.Tracer.Start(ctx, "ConfigurationService.Add")
 	defer span.End()
 
-	_, err := s.repo.Add(config, ctx)
+	if err := model.ValidateConfigName(config.Name); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	if err := config.Labels.Validate(); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	if err := config.ValidateParameters(); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	if config.Source != nil {
+		err := fmt.Errorf("config %s: source is only allowed on group members: %w", config.Name, model.ErrInvalid)
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	config.Binding = ""
+	if config.Extends != nil {
+		config.Extends.Resolved = nil
+		if err := config.Extends.Validate(); err != nil {
+			span.SetStatus(codes.Error, err.Error())
+			return err
+		}
+	}
+	effective, err := resolveEffective(s.repo, *config, ctx)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	if err = checkSchemas(s.repo, []model.Configuration{*effective}, ctx); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+
+	stamp(&config.Id, &config.Metadata, 0, model.Metadata{}, ctx)
+	_, err = s.repo.Add(config, newConfigRevision(config, 0, ctx), ctx)
 	if err != nil {
 		span.SetStatus(codes.Error, err.Error())
 		return err
@@ -38,14 +80,438 @@ func (s ConfigurationService) Get(name string, version string, ctx context.Conte
 	ctx, span := s.Tracer.Start(ctx, "ConfigurationService.Get")
 	defer span.End()
 
-	return s.repo.GetById(name, version, ctx)
+	config, err := s.repo.GetById(name, version, ctx)
+	if errors.Is(err, model.ErrNotFound) {
+		if trashed, trashErr := s.repo.GetTrashedConfig(name, version, ctx); trashErr == nil {
+			err = fmt.Errorf("config %s %s was deleted by %s at %s, restore it with POST /trash/configs/%s/%s/restore: %w",
+				name, version, trashed.DeletedBy, trashed.DeletedAt.Format(time.RFC3339), url.PathEscape(name), url.PathEscape(version), model.ErrGone)
+		}
+	}
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// GetEffective returns the configuration merged with the configurations it extends, with every placeholder resolved.
+func (s ConfigurationService) GetEffective(name string, version string, ctx context.Context) (*model.Configuration, error) {
+	ctx, span := s.Tracer.Start(ctx, "ConfigurationService.GetEffective")
+	defer span.End()
+
+	config, err := s.Get(name, version, ctx)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	effective, err := resolveEffective(s.repo, *config, ctx)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	merged := *effective
+	effective, err = newInterpolator(s.repo, ctx).interpolate(configDoc(merged), effective)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	if interpolated(merged) {
+		if err = checkSchemas(s.repo, []model.Configuration{*effective}, ctx); err != nil {
+			span.SetStatus(codes.Error, err.Error())
+			return nil, err
+		}
+	}
+
+	span.SetStatus(codes.Ok, "SERVICE - Success")
+	return effective, nil
 }
 
+// Delete moves the configuration to the trash, it stays restorable until the trash is purged.
 func (s ConfigurationService) Delete(config model.Configuration, ctx context.Context) error {
 	ctx, span := s.Tracer.Start(ctx, "ConfigurationService.Delete")
 	defer span.End()
 
-	ver := model.ToString(config.Version)
+	if err := s.checkUnreferenced(config, ctx); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	if err := s.trash(config, ctx); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "SERVICE - Success")
+	return nil
+}
+
+// trash moves the configuration to the trash without checking who reads it.
+func (s ConfigurationService) trash(config model.Configuration, ctx context.Context) error {
+	trashed := &model.TrashedConfiguration{
+		Configuration: config,
+		DeletedAt:     time.Now().UTC(),
+		DeletedBy:     AuthorFromContext(ctx),
+	}
+	return s.repo.TrashConfig(trashed, newConfigRevision(nil, 0, ctx), ctx)
+}
+
+// checkUnreferenced fails with model.ErrInUse when a group member cannot be read without the configuration, because
+// it references exactly its version or no other version matches its constraint.
+func (s ConfigurationService) checkUnreferenced(config model.Configuration, ctx context.Context) error {
+	groups, err := s.repo.FindSourceMembers(config.Name, ctx)
+	if err != nil {
+		return err
+	}
+	versions, err := s.repo.GetVersions(config.Name, ctx)
+	if err != nil {
+		return err
+	}
+	readers := findReferences(groups, versions).required[referenceKey(config.Name, config.Version)]
+	if len(readers) > 0 {
+		return fmt.Errorf("config %s %s is %w by the group members %s, point them at another version first",
+			config.Name, model.ToString(config.Version), model.ErrInUse, strings.Join(readers, ", "))
+	}
+	return nil
+}
+
+// Restore moves a trashed configuration back, it fails when the same version was created again in the meantime.
+func (s ConfigurationService) Restore(name string, version string, ctx context.Context) (*model.Configuration, error) {
+	ctx, span := s.Tracer.Start(ctx, "ConfigurationService.Restore")
+	defer span.End()
+
+	trashed, err := s.repo.GetTrashedConfig(name, version, ctx)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	current, err := s.repo.GetById(name, version, ctx)
+	if err != nil && !errors.Is(err, model.ErrNotFound) {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	if current != nil {
+		err = fmt.Errorf("config %s %s %w, delete it before restoring from the trash", name, version, model.ErrAlreadyExists)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	config := trashed.Configuration
+	if err = s.repo.RestoreConfig(&config, newConfigRevision(&config, 0, ctx), ctx); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	span.SetStatus(codes.Ok, "SERVICE - Success")
+	return &config, nil
+}
+
+func (s ConfigurationService) Clone(name string, version string, req model.CloneRequest, ctx context.Context) (*model.Configuration, error) {
+	ctx, span := s.Tracer.Start(ctx, "ConfigurationService.Clone")
+	defer span.End()
+
+	source, err := s.repo.GetById(name, version, ctx)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	target, err := s.repo.GetById(name, model.ToString(req.Version), ctx)
+	if err != nil && !errors.Is(err, model.ErrNotFound) {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	if target != nil {
+		err = fmt.Errorf("config %s %s %w", name, model.ToString(req.Version), model.ErrAlreadyExists)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	clone := *source
+	if req.Patch != nil {
+		clone = req.Patch.Apply(clone)
+	}
+	clone.SetVersion(req.Version)
+
+	if err = s.Add(&clone, ctx); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	span.SetStatus(codes.Ok, "SERVICE - Success")
+	return &clone, nil
+}
+
+func (s ConfigurationService) Diff(name string, from string, to string, ctx context.Context) (*model.ConfigurationDiff, error) {
+	ctx, span := s.Tracer.Start(ctx, "ConfigurationService.Diff")
+	defer span.End()
+
+	fromConfig, err := s.repo.GetById(name, from, ctx)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, fmt.Errorf("config %s %s: %w", name, from, err)
+	}
+	toConfig, err := s.repo.GetById(name, to, ctx)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, fmt.Errorf("config %s %s: %w", name, to, err)
+	}
+
+	diff := model.DiffConfigurations(*fromConfig, *toConfig)
+	span.SetStatus(codes.Ok, "SERVICE - Success")
+	return &diff, nil
+}
+
+func (s ConfigurationService) History(name string, version string, ctx context.Context) ([]model.ConfigurationRevision, error) {
+	ctx, span := s.Tracer.Start(ctx, "ConfigurationService.History")
+	defer span.End()
+
+	revisions, err := s.repo.GetConfigRevisions(name, version, ctx)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	if len(revisions) == 0 {
+		err = fmt.Errorf("history of config %s %s %w", name, version, model.ErrNotFound)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	span.SetStatus(codes.Ok, "SERVICE - Success")
+	return revisions, nil
+}
+
+// GetRevision returns the configuration exactly as it was written in the given revision.
+func (s ConfigurationService) GetRevision(name string, version string, number int64, ctx context.Context) (*model.Configuration, error) {
+	ctx, span := s.Tracer.Start(ctx, "ConfigurationService.GetRevision")
+	defer span.End()
+
+	rev, err := s.repo.GetConfigRevision(name, version, number, ctx)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	if rev.Deleted {
+		err = fmt.Errorf("config %s %s was deleted in revision %d: %w", name, version, number, model.ErrNotFound)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	span.SetStatus(codes.Ok, "SERVICE - Success")
+	return rev.Configuration, nil
+}
+
+// GetAsOf reconstructs the configuration as a consumer would have read it at the given point in time.
+func (s ConfigurationService) GetAsOf(name string, version string, asOf time.Time, ctx context.Context) (*model.Configuration, error) {
+	ctx, span := s.Tracer.Start(ctx, "ConfigurationService.GetAsOf")
+	defer span.End()
+
+	revisions, err := s.repo.GetConfigRevisions(name, version, ctx)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	rev, ok := model.ConfigurationRevisionAsOf(revisions, asOf)
+	if !ok || rev.Deleted {
+		err = fmt.Errorf("config %s %s at %s %w", name, version, asOf.Format(time.RFC3339), model.ErrNotFound)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	span.SetStatus(codes.Ok, "SERVICE - Success")
+	return rev.Configuration, nil
+}
+
+// PreviewRollback shows what rolling the configuration back to the given revision would change.
+func (s ConfigurationService) PreviewRollback(name string, version string, number int64, ctx context.Context) (*model.ConfigurationDiff, error) {
+	ctx, span := s.Tracer.Start(ctx, "ConfigurationService.PreviewRollback")
+	defer span.End()
+
+	current, target, err := s.rollbackStates(name, version, number, ctx)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	diff := model.DiffConfigurations(*current, *target)
+	span.SetStatus(codes.Ok, "SERVICE - Success")
+	return &diff, nil
+}
+
+// Rollback restores the configuration to the given revision, the restore is recorded as a new revision.
+func (s ConfigurationService) Rollback(name string, version string, number int64, ctx context.Context) (*model.Configuration, error) {
+	ctx, span := s.Tracer.Start(ctx, "ConfigurationService.Rollback")
+	defer span.End()
+
+	current, target, err := s.rollbackStates(name, version, number, ctx)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	stamp(&target.Id, &target.Metadata, current.Id, current.Metadata, ctx)
+	if _, err = s.repo.Add(target, newConfigRevision(target, number, ctx), ctx); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
 
-	return s.repo.Delete(config.Name, ver, ctx)
+	span.SetStatus(codes.Ok, "SERVICE - Success")
+	return target, nil
+}
+
+// rollbackStates loads the current configuration, or an empty one when it was deleted, and the one stored in the revision.
+func (s ConfigurationService) rollbackStates(name string, version string, number int64, ctx context.Context) (*model.Configuration, *model.Configuration, error) {
+	rev, err := s.repo.GetConfigRevision(name, version, number, ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if rev.Deleted {
+		return nil, nil, fmt.Errorf("revision %d deleted the config and cannot be restored: %w", number, model.ErrInvalid)
+	}
+
+	current, err := s.repo.GetById(name, version, ctx)
+	if errors.Is(err, model.ErrNotFound) {
+		current, err = &model.Configuration{Name: name, Version: rev.Configuration.Version}, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return current, rev.Configuration, nil
+}
+
+// newConfigRevision returns the revision a write records, stamped with the time and author. A revision without a
+// configuration marks a delete, restoredFrom is set on rollbacks.
+func newConfigRevision(config *model.Configuration, restoredFrom int64, ctx context.Context) *model.ConfigurationRevision {
+	return &model.ConfigurationRevision{
+		Timestamp:     time.Now().UTC(),
+		Author:        AuthorFromContext(ctx),
+		Deleted:       config == nil,
+		RestoredFrom:  restoredFrom,
+		Configuration: config,
+	}
+}
+
+// GetByObjectId returns the configuration the server assigned the given ID to.
+func (s ConfigurationService) GetByObjectId(id int64, ctx context.Context) (*model.Configuration, error) {
+	ctx, span := s.Tracer.Start(ctx, "ConfigurationService.GetByObjectId")
+	defer span.End()
+
+	ref, err := s.repo.GetObjectRef(id, ctx)
+	if err == nil && ref.Kind != model.KindConfig {
+		err = fmt.Errorf("id %d belongs to a %s: %w", id, ref.Kind, model.ErrNotFound)
+	}
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	config, err := s.Get(ref.Name, model.ToString(ref.Version), ctx)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	span.SetStatus(codes.Ok, "SERVICE - Success")
+	return config, nil
+}
+
+// List returns one page of the configurations under opts.Prefix whose labels match opts.Selector, with a delimiter
+// the prefix names a folder. Entries are listed from Consul keys and only the configurations on the page are read,
+// update times come from the ID index.
+func (s ConfigurationService) List(opts model.ListOptions, ctx context.Context) (*model.ConfigurationPage, error) {
+	ctx, span := s.Tracer.Start(ctx, "ConfigurationService.List")
+	defer span.End()
+
+	cursor, err := model.DecodeCursor(opts.Cursor, opts)
+	if err == nil {
+		err = opts.Validate()
+	}
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	if opts.Delimiter != "" {
+		opts.Prefix = model.FolderPrefix(opts.Prefix)
+	}
+
+	refs, err := s.repo.ListConfigRefs(opts.Prefix, ctx)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	var updatedAt map[string]time.Time
+	if opts.Sort == model.SortByUpdatedAt {
+		if updatedAt, err = updateTimes(s.repo, model.KindConfig, opts.Prefix, ctx); err != nil {
+			span.SetStatus(codes.Error, err.Error())
+			return nil, err
+		}
+	}
+	entries := make([]listEntry, 0, len(refs))
+	loaded := make(map[string]*model.Configuration)
+	for _, ref := range refs {
+		e := listEntry{ObjectRef: ref}
+		if updatedAt != nil {
+			key := referenceKey(ref.Name, ref.Version)
+			t, ok := updatedAt[key]
+			if !ok {
+				config, err := s.repo.GetById(ref.Name, model.ToString(ref.Version), ctx)
+				if errors.Is(err, model.ErrNotFound) {
+					continue
+				}
+				if err != nil {
+					span.SetStatus(codes.Error, err.Error())
+					return nil, err
+				}
+				loaded[key], t = config, config.UpdatedAt
+			}
+			e.UpdatedAt = t
+		}
+		entries = append(entries, e)
+	}
+
+	page := &model.ConfigurationPage{Items: []model.Configuration{}}
+	if opts.Delimiter != "" {
+		var folders []string
+		entries, folders = foldEntries(entries, opts.Prefix)
+		if cursor == nil {
+			page.Folders = folders
+		}
+	}
+	refs, indexed, err := indexedCandidates(s.repo, model.KindConfig, opts.Selector, ctx)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	if indexed {
+		entries = restrictEntries(entries, refs)
+	}
+
+	page.NextCursor, err = fillPage(entriesAfter(entries, opts, cursor), opts, func(e listEntry) (bool, error) {
+		config, ok := loaded[referenceKey(e.Name, e.Version)]
+		if !ok {
+			var err error
+			config, err = s.repo.GetById(e.Name, model.ToString(e.Version), ctx)
+			if errors.Is(err, model.ErrNotFound) {
+				return false, nil
+			}
+			if err != nil {
+				return false, err
+			}
+		}
+		if !opts.Selector.Matches(config.Labels) {
+			return false, nil
+		}
+		page.Items = append(page.Items, *config)
+		return true, nil
+	})
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	span.SetStatus(codes.Ok, "SERVICE - Success")
+	return page, nil
 }
@@ -4,6 +4,12 @@ import (
 	"ars_projekat/model"
 	"ars_projekat/repositories"
 	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
 	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/trace"
 )
@@ -24,15 +30,19 @@ func (s ConfigurationGroupService) Add(configGroup model.ConfigurationGroup, ctx
 	ctx, span := s.Tracer.Start(ctx, "ConfigurationGroupService.Add")
 	defer span.End()
 
-	name := configGroup.Name
-	version := model.ToString(configGroup.Version)
-	for _, v := range configGroup.Configurations {
-		labels := model.SortLabels(v.Labels)
-		err := s.repo.AddGroup(name, version, labels, v, ctx)
-		if err != nil {
-			span.SetStatus(codes.Error, err.Error())
-			return err
-		}
+	prepareMembers(configGroup.Configurations)
+	if err := s.validateMembers(configGroup.Configurations, ctx); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	if err := s.validateIncludes(&configGroup, ctx); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+
+	if err := s.save(&configGroup, ctx); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return err
 	}
 
 	span.SetStatus(codes.Ok, "SERVICE - Success")
@@ -43,29 +53,863 @@ func (s ConfigurationGroupService) Save(configGroup *model.ConfigurationGroup, c
 	ctx, span := s.Tracer.Start(ctx, "ConfigurationGroupService.Save")
 	defer span.End()
 
-	for _, v := range configGroup.Configurations {
-		labels := model.SortLabels(v.Labels)
-		err := s.repo.AddGroup(configGroup.Name, model.ToString(configGroup.Version), labels, v, ctx)
+	prepareMembers(configGroup.Configurations)
+	if err := s.validateMembers(configGroup.Configurations, ctx); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	if err := s.validateIncludes(configGroup, ctx); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+
+	if err := s.save(configGroup, ctx); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "SERVICE - Success")
+	return nil
+}
+
+func (s ConfigurationGroupService) Get(name string, version model.Version, labels string, ctx context.Context) (*model.ConfigurationGroup, error) {
+	ctx, span := s.Tracer.Start(ctx, "ConfigurationGroupService.Get")
+	defer span.End()
+
+	group, err := s.repo.GetGroupByParams(name, model.ToString(version), labels, ctx)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	if group != nil {
+		span.SetStatus(codes.Ok, "SERVICE - Success")
+		return group, nil
+	}
+
+	trashed, err := s.repo.GetTrashedGroups(name, model.ToString(version), ctx)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	for i := len(trashed) - 1; i >= 0; i-- {
+		if trashedMatches(trashed[i], labels) {
+			err = fmt.Errorf("group %s %s was deleted by %s at %s, restore it with POST /trash/groups/%s/%s/restore: %w",
+				name, model.ToString(version), trashed[i].DeletedBy, trashed[i].DeletedAt.Format(time.RFC3339), url.PathEscape(name), url.PathEscape(model.ToString(version)), model.ErrGone)
+			span.SetStatus(codes.Error, err.Error())
+			return nil, err
+		}
+	}
+
+	span.SetStatus(codes.Ok, "SERVICE - Success")
+	return nil, nil
+}
+
+// GetEffective returns the group with every member merged with the configurations it extends and its placeholders resolved.
+func (s ConfigurationGroupService) GetEffective(name string, version model.Version, labels string, ctx context.Context) (*model.ConfigurationGroup, error) {
+	ctx, span := s.Tracer.Start(ctx, "ConfigurationGroupService.GetEffective")
+	defer span.End()
+
+	group, err := s.Get(name, version, "", ctx)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	if group == nil {
+		span.SetStatus(codes.Ok, "SERVICE - Success")
+		return nil, nil
+	}
+	if len(group.Includes) > 0 {
+		tree, err := s.includeTree(group, nil, model.ErrDangling, ctx)
+		if err != nil {
+			span.SetStatus(codes.Error, err.Error())
+			return nil, err
+		}
+		flat := *group
+		flat.Configurations = tree.Flatten()
+		group = &flat
+	}
+	group = filterMembers(group, labels)
+	if labels != "" && len(group.Configurations) == 0 {
+		// Nothing matches, Get tells a missing member from a trashed one.
+		group, err = s.Get(name, version, labels, ctx)
+		if err != nil {
+			span.SetStatus(codes.Error, err.Error())
+			return nil, err
+		}
+		span.SetStatus(codes.Ok, "SERVICE - Success")
+		return group, nil
+	}
+
+	in := newInterpolator(s.repo, ctx)
+	effective := *group
+	effective.Configurations = make([]model.Configuration, 0, len(group.Configurations))
+	var resolved []model.Configuration
+	for _, c := range group.Configurations {
+		merged, err := resolveEffective(s.repo, c, ctx)
+		var values *model.Configuration
+		if err == nil {
+			values, err = in.interpolate(memberDoc(name, model.ToString(version), c), merged)
+		}
 		if err != nil {
+			err = fmt.Errorf("member %s: %w", c.Name, err)
+			span.SetStatus(codes.Error, err.Error())
+			return nil, err
+		}
+		if interpolated(*merged) {
+			resolved = append(resolved, *values)
+		}
+		effective.Configurations = append(effective.Configurations, *values)
+	}
+	if len(resolved) > 0 {
+		if err = checkSchemas(s.repo, resolved, ctx); err != nil {
+			span.SetStatus(codes.Error, err.Error())
+			return nil, err
+		}
+	}
+
+	span.SetStatus(codes.Ok, "SERVICE - Success")
+	return &effective, nil
+}
+
+// Resolve merges the effective members of the group that apply to the client context of req into one parameter
+// map, more specific members override less specific ones.
+func (s ConfigurationGroupService) Resolve(name string, version model.Version, req model.ResolveRequest, ctx context.Context) (*model.ResolvedGroup, error) {
+	ctx, span := s.Tracer.Start(ctx, "ConfigurationGroupService.Resolve")
+	defer span.End()
+
+	if err := req.Validate(); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	group, err := s.GetEffective(name, version, "", ctx)
+	if err == nil && group == nil {
+		err = fmt.Errorf("group %s %s %w", name, model.ToString(version), model.ErrNotFound)
+	}
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	span.SetStatus(codes.Ok, "SERVICE - Success")
+	return model.Resolve(*group, req), nil
+}
+
+// Export resolves a group for a client context and renders the parameter map as a file.
+func (s ConfigurationGroupService) Export(name string, version model.Version, req model.ResolveRequest, export model.ExportRequest, ctx context.Context) (*model.ExportFile, error) {
+	ctx, span := s.Tracer.Start(ctx, "ConfigurationGroupService.Export")
+	defer span.End()
+
+	if err := export.Validate(); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	resolved, err := s.Resolve(name, version, req, ctx)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	file, err := model.Export(resolved, export)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	span.SetStatus(codes.Ok, "SERVICE - Success")
+	return file, nil
+}
+
+// GetTree returns the group with the groups it includes, each with its own stored members.
+func (s ConfigurationGroupService) GetTree(name string, version model.Version, ctx context.Context) (*model.GroupTree, error) {
+	ctx, span := s.Tracer.Start(ctx, "ConfigurationGroupService.GetTree")
+	defer span.End()
+
+	group, err := s.Get(name, version, "", ctx)
+	if err == nil && group == nil {
+		err = fmt.Errorf("group %s %s %w", name, model.ToString(version), model.ErrNotFound)
+	}
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	tree, err := s.includeTree(group, nil, model.ErrDangling, ctx)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	span.SetStatus(codes.Ok, "SERVICE - Success")
+	return tree, nil
+}
+
+// includeTree loads the groups group includes, and the groups they include. path holds the groups being expanded,
+// including one of them again is a cycle. An included group that does not exist fails with missing.
+func (s ConfigurationGroupService) includeTree(group *model.ConfigurationGroup, path []string, missing error, ctx context.Context) (*model.GroupTree, error) {
+	path = append(path[:len(path):len(path)], referenceKey(group.Name, group.Version))
+	tree := &model.GroupTree{Name: group.Name, Version: group.Version, Configurations: group.Configurations}
+	for _, include := range group.Includes {
+		for _, p := range path {
+			if p == include.String() {
+				return nil, fmt.Errorf("group include cycle %s -> %s: %w", strings.Join(path, " -> "), include, model.ErrInvalid)
+			}
+		}
+		if len(path) > model.MaxIncludeDepth {
+			return nil, fmt.Errorf("group %s includes groups more than %d levels deep: %w", path[0], model.MaxIncludeDepth, model.ErrInvalid)
+		}
+
+		included, err := s.repo.GetGroupByParams(include.Name, model.ToString(include.Version), "", ctx)
+		if err != nil {
+			return nil, err
+		}
+		if included == nil {
+			return nil, fmt.Errorf("group %s included by %s does not exist: %w", include, path[len(path)-1], missing)
+		}
+		child, err := s.includeTree(included, path, missing, ctx)
+		if err != nil {
+			return nil, err
+		}
+		tree.Includes = append(tree.Includes, *child)
+	}
+	return tree, nil
+}
+
+// validateIncludes checks that the groups group includes exist and neither include group again nor nest deeper
+// than model.MaxIncludeDepth.
+func (s ConfigurationGroupService) validateIncludes(group *model.ConfigurationGroup, ctx context.Context) error {
+	if err := model.ValidateIncludes(group.Includes); err != nil {
+		return err
+	}
+	_, err := s.includeTree(group, nil, model.ErrInvalid, ctx)
+	return err
+}
+
+// Delete moves the members selected by labels to the trash, they stay restorable until the trash is purged.
+func (s ConfigurationGroupService) Delete(name string, version string, labels string, ctx context.Context) error {
+	ctx, span := s.Tracer.Start(ctx, "ConfigurationGroupService.Delete")
+	defer span.End()
+
+	group, err := s.repo.GetGroupByParams(name, version, labels, ctx)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	if group == nil {
+		err = fmt.Errorf("group %s %s %w", name, version, model.ErrNotFound)
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	if labels == "" {
+		if err = s.checkNotIncluded(*group, ctx); err != nil {
 			span.SetStatus(codes.Error, err.Error())
 			return err
 		}
 	}
 
+	if err = s.trash(*group, labels, ctx); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+
 	span.SetStatus(codes.Ok, "SERVICE - Success")
 	return nil
 }
 
-func (s ConfigurationGroupService) Get(name string, version model.Version, labels string, ctx context.Context) (*model.ConfigurationGroup, error) {
-	ctx, span := s.Tracer.Start(ctx, "ConfigurationGroupService.Get")
+// trash moves the members of group, the ones selected by labels, to the trash without checking who includes it.
+func (s ConfigurationGroupService) trash(group model.ConfigurationGroup, labels string, ctx context.Context) error {
+	trashed := &model.TrashedGroup{
+		Name:           group.Name,
+		Version:        group.Version,
+		Labels:         labels,
+		Configurations: group.Configurations,
+		DeletedAt:      time.Now().UTC(),
+		DeletedBy:      AuthorFromContext(ctx),
+	}
+	return s.repo.TrashGroup(trashed, newGroupRevision(0, ctx), ctx)
+}
+
+// checkNotIncluded fails with model.ErrInUse when another group includes the group version.
+func (s ConfigurationGroupService) checkNotIncluded(group model.ConfigurationGroup, ctx context.Context) error {
+	groups, err := s.repo.FindIncluders(group.Name, model.ToString(group.Version), ctx)
+	if err != nil {
+		return err
+	}
+	includers := findReferences(groups, nil).included[referenceKey(group.Name, group.Version)]
+	if len(includers) > 0 {
+		return fmt.Errorf("group %s %s is %w, the groups %s include it", group.Name, model.ToString(group.Version),
+			model.ErrInUse, strings.Join(includers, ", "))
+	}
+	return nil
+}
+
+// ReplaceMember replaces the member named configName with config, labels qualify the member when the group has
+// several members with that name. config is named configName when it sets no name, it may change the labels.
+func (s ConfigurationGroupService) ReplaceMember(name string, version model.Version, configName string, labels string, config model.Configuration, ctx context.Context) (*model.Configuration, error) {
+	ctx, span := s.Tracer.Start(ctx, "ConfigurationGroupService.ReplaceMember")
 	defer span.End()
 
-	return s.repo.GetGroupByParams(name, model.ToString(version), labels, ctx)
+	previous, err := s.findMember(name, version, configName, labels, ctx)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	if config.MemberName() == "" {
+		config.Name = configName
+	}
+	members := []model.Configuration{config}
+	prepareMembers(members)
+	if members[0].Name != configName {
+		err = fmt.Errorf("member %s cannot be renamed to %s: %w", configName, members[0].Name, model.ErrInvalid)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	if err = s.validateMembers(members, ctx); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	ver := model.ToString(version)
+	if err = s.repo.ReplaceGroupMember(name, ver, *previous, members[0], newGroupRevision(0, ctx), ctx); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	span.SetStatus(codes.Ok, "SERVICE - Success")
+	return &members[0], nil
 }
 
-func (s ConfigurationGroupService) Delete(name string, version string, labels string, ctx context.Context) error {
-	ctx, span := s.Tracer.Start(ctx, "ConfigurationGroupService.Get")
+// RemoveMember moves the member named configName to the trash, labels qualify the member when the group has several
+// members with that name. The group stays, also when it has no members left.
+func (s ConfigurationGroupService) RemoveMember(name string, version model.Version, configName string, labels string, ctx context.Context) error {
+	ctx, span := s.Tracer.Start(ctx, "ConfigurationGroupService.RemoveMember")
 	defer span.End()
 
-	return s.repo.DeleteGroupByParams(name, version, labels, ctx)
+	member, err := s.findMember(name, version, configName, labels, ctx)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+
+	removed := model.MemberOf(*member)
+	trashed := &model.TrashedGroup{
+		Name:           name,
+		Version:        version,
+		Configurations: []model.Configuration{*member},
+		Member:         &removed,
+		DeletedAt:      time.Now().UTC(),
+		DeletedBy:      AuthorFromContext(ctx),
+	}
+	if err = s.repo.TrashGroup(trashed, newGroupRevision(0, ctx), ctx); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "SERVICE - Success")
+	return nil
+}
+
+// findMember returns the stored member named configName with exactly the given labels, or the only member with
+// that name when labels are empty.
+func (s ConfigurationGroupService) findMember(name string, version model.Version, configName string, labels string, ctx context.Context) (*model.Configuration, error) {
+	ver := model.ToString(version)
+	group, err := s.repo.GetGroupByParams(name, ver, "", ctx)
+	if err != nil {
+		return nil, err
+	}
+	if group == nil {
+		return nil, fmt.Errorf("group %s %s %w", name, ver, model.ErrNotFound)
+	}
+
+	var matches []model.Configuration
+	for _, c := range group.Configurations {
+		if c.Name == configName && (labels == "" || model.SortLabels(c.Labels) == labels) {
+			matches = append(matches, c)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("member %s of group %s %s %w", configName, name, ver, model.ErrNotFound)
+	case 1:
+		return &matches[0], nil
+	}
+	return nil, fmt.Errorf("group %s %s has %d members named %s, qualify it with labels: %w", name, ver, len(matches), configName, model.ErrInvalid)
+}
+
+// Restore puts back every trashed part of the group version, it fails when any of the members was created again.
+func (s ConfigurationGroupService) Restore(name string, version model.Version, ctx context.Context) (*model.ConfigurationGroup, error) {
+	ctx, span := s.Tracer.Start(ctx, "ConfigurationGroupService.Restore")
+	defer span.End()
+
+	ver := model.ToString(version)
+	trashed, err := s.repo.GetTrashedGroups(name, ver, ctx)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	if len(trashed) == 0 {
+		err = fmt.Errorf("trashed group %s %s %w", name, ver, model.ErrNotFound)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	current, err := s.repo.GetGroupByParams(name, ver, "", ctx)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	var members []model.Configuration
+	for _, t := range trashed {
+		for _, c := range t.Configurations {
+			if current != nil && hasMember(current, c) {
+				err = fmt.Errorf("member %s of group %s %s %w, delete it before restoring from the trash", c.Name, name, ver, model.ErrAlreadyExists)
+				span.SetStatus(codes.Error, err.Error())
+				return nil, err
+			}
+			members = append(members, c)
+		}
+	}
+
+	if err = s.repo.RestoreGroup(name, ver, members, newGroupRevision(0, ctx), ctx); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	span.SetStatus(codes.Ok, "SERVICE - Success")
+	return &model.ConfigurationGroup{Name: name, Version: version, Configurations: members}, nil
+}
+
+func (s ConfigurationGroupService) Clone(name string, version model.Version, req model.CloneRequest, ctx context.Context) (*model.ConfigurationGroup, error) {
+	ctx, span := s.Tracer.Start(ctx, "ConfigurationGroupService.Clone")
+	defer span.End()
+
+	source, err := s.repo.GetGroupByParams(name, model.ToString(version), "", ctx)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	if source == nil {
+		err = fmt.Errorf("group %s %s %w", name, model.ToString(version), model.ErrNotFound)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	target, err := s.repo.GetGroupByParams(name, model.ToString(req.Version), "", ctx)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	if target != nil {
+		err = fmt.Errorf("group %s %s %w", name, model.ToString(req.Version), model.ErrAlreadyExists)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	clone := &model.ConfigurationGroup{Includes: source.Includes, Metadata: source.Metadata}
+	clone.SetName(name)
+	clone.SetVersion(req.Version)
+	for _, v := range source.Configurations {
+		if req.Patch != nil {
+			v = req.Patch.Apply(v)
+		}
+		if patch, ok := req.Members[v.Name]; ok {
+			v = patch.Apply(v)
+		}
+		clone.Configurations = append(clone.Configurations, v)
+	}
+
+	if err = s.Save(clone, ctx); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	span.SetStatus(codes.Ok, "SERVICE - Success")
+	return clone, nil
+}
+
+func (s ConfigurationGroupService) Diff(name string, from model.Version, to model.Version, ctx context.Context) (*model.GroupDiff, error) {
+	ctx, span := s.Tracer.Start(ctx, "ConfigurationGroupService.Diff")
+	defer span.End()
+
+	var groups []*model.ConfigurationGroup
+	for _, version := range []model.Version{from, to} {
+		group, err := s.repo.GetGroupByParams(name, model.ToString(version), "", ctx)
+		if err != nil {
+			span.SetStatus(codes.Error, err.Error())
+			return nil, err
+		}
+		if group == nil {
+			err = fmt.Errorf("group %s %s %w", name, model.ToString(version), model.ErrNotFound)
+			span.SetStatus(codes.Error, err.Error())
+			return nil, err
+		}
+		groups = append(groups, group)
+	}
+
+	diff := model.DiffGroups(*groups[0], *groups[1])
+	span.SetStatus(codes.Ok, "SERVICE - Success")
+	return &diff, nil
+}
+
+func (s ConfigurationGroupService) History(name string, version model.Version, ctx context.Context) ([]model.GroupRevision, error) {
+	ctx, span := s.Tracer.Start(ctx, "ConfigurationGroupService.History")
+	defer span.End()
+
+	revisions, err := s.repo.GetGroupRevisions(name, model.ToString(version), ctx)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	if len(revisions) == 0 {
+		err = fmt.Errorf("history of group %s %s %w", name, model.ToString(version), model.ErrNotFound)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	span.SetStatus(codes.Ok, "SERVICE - Success")
+	return revisions, nil
+}
+
+// GetRevision returns the group as it was after the given revision, limited to members with the given labels.
+func (s ConfigurationGroupService) GetRevision(name string, version model.Version, labels string, number int64, ctx context.Context) (*model.ConfigurationGroup, error) {
+	ctx, span := s.Tracer.Start(ctx, "ConfigurationGroupService.GetRevision")
+	defer span.End()
+
+	rev, err := s.repo.GetGroupRevision(name, model.ToString(version), number, ctx)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	if rev.Deleted {
+		err = fmt.Errorf("group %s %s was deleted in revision %d: %w", name, model.ToString(version), number, model.ErrNotFound)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	span.SetStatus(codes.Ok, "SERVICE - Success")
+	return filterMembers(rev.Group, labels), nil
+}
+
+// GetAsOf reconstructs the group as a consumer would have read it at the given point in time.
+func (s ConfigurationGroupService) GetAsOf(name string, version model.Version, labels string, asOf time.Time, ctx context.Context) (*model.ConfigurationGroup, error) {
+	ctx, span := s.Tracer.Start(ctx, "ConfigurationGroupService.GetAsOf")
+	defer span.End()
+
+	revisions, err := s.repo.GetGroupRevisions(name, model.ToString(version), ctx)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	rev, ok := model.GroupRevisionAsOf(revisions, asOf)
+	if !ok || rev.Deleted {
+		err = fmt.Errorf("group %s %s at %s %w", name, model.ToString(version), asOf.Format(time.RFC3339), model.ErrNotFound)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	span.SetStatus(codes.Ok, "SERVICE - Success")
+	return filterMembers(rev.Group, labels), nil
+}
+
+// PreviewRollback shows what rolling the group version back to the given revision would change.
+func (s ConfigurationGroupService) PreviewRollback(name string, version model.Version, number int64, ctx context.Context) (*model.GroupDiff, error) {
+	ctx, span := s.Tracer.Start(ctx, "ConfigurationGroupService.PreviewRollback")
+	defer span.End()
+
+	current, target, err := s.rollbackStates(name, version, number, ctx)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	diff := model.DiffGroups(*current, *target)
+	span.SetStatus(codes.Ok, "SERVICE - Success")
+	return &diff, nil
+}
+
+// Rollback atomically replaces all members and the includes of the group version with the ones stored in the given
+// revision, the includes must still exist and not form a cycle. The restore is recorded as a new revision.
+func (s ConfigurationGroupService) Rollback(name string, version model.Version, number int64, ctx context.Context) (*model.ConfigurationGroup, error) {
+	ctx, span := s.Tracer.Start(ctx, "ConfigurationGroupService.Rollback")
+	defer span.End()
+
+	_, target, err := s.rollbackStates(name, version, number, ctx)
+	if err == nil {
+		err = s.validateIncludes(target, ctx)
+	}
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	if err = s.repo.ReplaceGroup(name, model.ToString(version), target.Configurations, target.Includes, newGroupRevision(number, ctx), ctx); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	span.SetStatus(codes.Ok, "SERVICE - Success")
+	return target, nil
+}
+
+// rollbackStates loads the current group, or an empty one when it was deleted, and the one stored in the revision.
+func (s ConfigurationGroupService) rollbackStates(name string, version model.Version, number int64, ctx context.Context) (*model.ConfigurationGroup, *model.ConfigurationGroup, error) {
+	rev, err := s.repo.GetGroupRevision(name, model.ToString(version), number, ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if rev.Deleted {
+		return nil, nil, fmt.Errorf("revision %d deleted the group and cannot be restored: %w", number, model.ErrInvalid)
+	}
+
+	current, err := s.repo.GetGroupByParams(name, model.ToString(version), "", ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if current == nil {
+		current = &model.ConfigurationGroup{Name: name, Version: version}
+	}
+
+	return current, rev.Group, nil
+}
+
+// newGroupRevision returns the revision a write records, stamped with the time and author. The repository fills in
+// the snapshot of the group as the write leaves it, restoredFrom is set on rollbacks.
+func newGroupRevision(restoredFrom int64, ctx context.Context) *model.GroupRevision {
+	return &model.GroupRevision{
+		Timestamp:    time.Now().UTC(),
+		Author:       AuthorFromContext(ctx),
+		RestoredFrom: restoredFrom,
+	}
+}
+
+// List returns one page of the groups under opts.Prefix, with a selector only the groups with matching members
+// and only those members. Entries are listed from Consul keys and only the groups on the page are read.
+func (s ConfigurationGroupService) List(opts model.ListOptions, ctx context.Context) (*model.ConfigurationGroupPage, error) {
+	ctx, span := s.Tracer.Start(ctx, "ConfigurationGroupService.List")
+	defer span.End()
+
+	cursor, err := model.DecodeCursor(opts.Cursor, opts)
+	if err == nil {
+		err = opts.Validate()
+	}
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	refs, err := s.repo.ListGroupRefs(opts.Prefix, ctx)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	var updatedAt map[string]time.Time
+	if opts.Sort == model.SortByUpdatedAt {
+		if updatedAt, err = updateTimes(s.repo, model.KindGroup, opts.Prefix, ctx); err != nil {
+			span.SetStatus(codes.Error, err.Error())
+			return nil, err
+		}
+	}
+	entries := make([]listEntry, 0, len(refs))
+	for _, ref := range refs {
+		e := listEntry{ObjectRef: ref}
+		if updatedAt != nil {
+			t, ok := updatedAt[referenceKey(ref.Name, ref.Version)]
+			if !ok {
+				doc, err := s.repo.GetGroupDocument(ref.Name, model.ToString(ref.Version), ctx)
+				if errors.Is(err, model.ErrNotFound) {
+					continue
+				}
+				if err != nil {
+					span.SetStatus(codes.Error, err.Error())
+					return nil, err
+				}
+				t = doc.UpdatedAt
+			}
+			e.UpdatedAt = t
+		}
+		entries = append(entries, e)
+	}
+
+	indexedRefs, indexed, err := indexedCandidates(s.repo, model.KindGroup, opts.Selector, ctx)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	if indexed {
+		entries = restrictEntries(entries, indexedRefs)
+	}
+
+	page := &model.ConfigurationGroupPage{Items: []model.ConfigurationGroup{}}
+	page.NextCursor, err = fillPage(entriesAfter(entries, opts, cursor), opts, func(e listEntry) (bool, error) {
+		version := model.ToString(e.Version)
+		group, err := s.repo.GetGroupByParams(e.Name, version, "", ctx)
+		if err != nil {
+			return false, err
+		}
+		if group == nil {
+			return false, nil
+		}
+		if opts.Selector != nil {
+			group = opts.Selector.SelectMembers(group)
+			if len(group.Configurations) == 0 {
+				return false, nil
+			}
+		}
+		page.Items = append(page.Items, *group)
+		return true, nil
+	})
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	span.SetStatus(codes.Ok, "SERVICE - Success")
+	return page, nil
+}
+
+// save writes the members of group and its document with the metadata and includes of group and records the write
+// as a revision in one update, the stored ID and creation fields are kept.
+func (s ConfigurationGroupService) save(group *model.ConfigurationGroup, ctx context.Context) error {
+	previous, err := s.repo.GetGroupDocument(group.Name, model.ToString(group.Version), ctx)
+	if errors.Is(err, model.ErrNotFound) {
+		previous, err = &model.GroupDocument{}, nil
+	}
+	if err != nil {
+		return err
+	}
+
+	doc := &model.GroupDocument{Name: group.Name, Version: group.Version, Includes: group.Includes, Metadata: group.Metadata}
+	stamp(&doc.Id, &doc.Metadata, previous.Id, previous.Metadata, ctx)
+	if err = s.repo.SaveGroup(doc, group.Configurations, newGroupRevision(0, ctx), ctx); err != nil {
+		return err
+	}
+
+	group.Id = doc.Id
+	group.Metadata = doc.Metadata
+	return nil
+}
+
+// GetByObjectId returns the group the server assigned the given ID to.
+func (s ConfigurationGroupService) GetByObjectId(id int64, ctx context.Context) (*model.ConfigurationGroup, error) {
+	ctx, span := s.Tracer.Start(ctx, "ConfigurationGroupService.GetByObjectId")
+	defer span.End()
+
+	ref, err := s.repo.GetObjectRef(id, ctx)
+	if err == nil && ref.Kind != model.KindGroup {
+		err = fmt.Errorf("id %d belongs to a %s: %w", id, ref.Kind, model.ErrNotFound)
+	}
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	group, err := s.Get(ref.Name, ref.Version, "", ctx)
+	if err == nil && group == nil {
+		err = fmt.Errorf("group %s %s %w", ref.Name, model.ToString(ref.Version), model.ErrNotFound)
+	}
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	span.SetStatus(codes.Ok, "SERVICE - Success")
+	return group, nil
+}
+
+// filterMembers keeps the members whose labels include the given labels.
+func filterMembers(group *model.ConfigurationGroup, labels string) *model.ConfigurationGroup {
+	if labels == "" {
+		return group
+	}
+
+	wanted := model.ParseLabels(labels)
+	filtered := *group
+	filtered.Configurations = nil
+	for _, c := range group.Configurations {
+		if c.Labels.Includes(wanted) {
+			filtered.Configurations = append(filtered.Configurations, c)
+		}
+	}
+	return &filtered
+}
+
+func sameLabels(a map[string]string, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if other, ok := b[k]; !ok || other != v {
+			return false
+		}
+	}
+	return true
+}
+
+// trashedMatches reports whether a read with the given label filter would have returned members of the trashed group.
+func trashedMatches(trashed model.TrashedGroup, labels string) bool {
+	if labels == "" {
+		return true
+	}
+	wanted := model.ParseLabels(labels)
+	for _, c := range trashed.Configurations {
+		if c.Labels.Includes(wanted) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasMember(group *model.ConfigurationGroup, config model.Configuration) bool {
+	for _, c := range group.Configurations {
+		if c.Name == config.Name && sameLabels(c.Labels, config.Labels) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateMembers checks the declared parameter types, the bases and the applicable schemas of every member.
+func (s ConfigurationGroupService) validateMembers(configs []model.Configuration, ctx context.Context) error {
+	effective := make([]model.Configuration, 0, len(configs))
+	for _, c := range configs {
+		if err := model.ValidateConfigName(c.Name); err != nil {
+			return fmt.Errorf("member %s: %w", c.Name, err)
+		}
+		if err := c.Labels.Validate(); err != nil {
+			return fmt.Errorf("member %s: %w", c.Name, err)
+		}
+		if err := c.ValidateParameters(); err != nil {
+			return fmt.Errorf("member %s: %w", c.Name, err)
+		}
+		if err := c.ValidateSource(); err != nil {
+			return err
+		}
+		if c.Extends != nil {
+			if err := c.Extends.Validate(); err != nil {
+				return fmt.Errorf("member %s: %w", c.Name, err)
+			}
+		}
+		merged, err := resolveEffective(s.repo, c, ctx)
+		if err != nil {
+			return fmt.Errorf("member %s: %w", c.Name, err)
+		}
+		effective = append(effective, *merged)
+	}
+	return checkSchemas(s.repo, effective, ctx)
+}
+
+// prepareMembers names members after the configuration they reference and marks their binding. It drops the
+// resolved versions a client may send back from an effective read, they are only filled on reads.
+func prepareMembers(configs []model.Configuration) {
+	for i := range configs {
+		c := &configs[i]
+		c.Name = c.MemberName()
+		if c.Extends != nil {
+			c.Extends.Resolved = nil
+		}
+		if c.Source != nil {
+			c.Source.Resolved = nil
+		}
+		c.Binding = c.ComputeBinding()
+	}
 }
@@ -0,0 +1,128 @@
+package services
+
+import (
+	"ars_projekat/model"
+	"ars_projekat/repositories"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// maxInheritanceDepth bounds the chain of bases a configuration may extend.
+const maxInheritanceDepth = 16
+
+// resolveEffective merges config with the chain of configurations it extends, nearer bases override farther ones
+// and config overrides them all. The config itself counts as stored, so a write that would close a cycle is caught.
+// A group member referencing a configuration is first replaced by the configuration it reads.
+func resolveEffective(repo repositories.IConfigRepository, config model.Configuration, ctx context.Context) (*model.Configuration, error) {
+	if config.Source != nil {
+		resolved, err := resolveSource(repo, config, ctx)
+		if err != nil {
+			return nil, err
+		}
+		config = *resolved
+	}
+	if config.Extends == nil {
+		return &config, nil
+	}
+
+	chain := []string{referenceKey(config.Name, config.Version)}
+	seen := map[string]bool{chain[0]: true}
+
+	var bases []model.Configuration
+	current := config
+	for current.Extends != nil {
+		base, err := findConfig(repo, config, current.Extends.Name, current.Extends.Version, ctx)
+		if err != nil {
+			return nil, fmt.Errorf("%s extends %s: %w", current.Name, current.Extends.Name, err)
+		}
+
+		key := referenceKey(base.Name, base.Version)
+		chain = append(chain, key)
+		if seen[key] {
+			return nil, fmt.Errorf("inheritance cycle %s: %w", strings.Join(chain, " -> "), model.ErrInvalid)
+		}
+		if len(bases) == maxInheritanceDepth {
+			return nil, fmt.Errorf("config %s extends more than %d bases: %w", config.Name, maxInheritanceDepth, model.ErrInvalid)
+		}
+		seen[key] = true
+
+		bases = append(bases, *base)
+		current = *base
+	}
+
+	effective := config
+	for _, base := range bases {
+		effective = model.MergeBase(effective, base)
+	}
+	ref := *config.Extends
+	ref.Resolved = &bases[0].Version
+	effective.Extends = &ref
+	return &effective, nil
+}
+
+// resolveSource returns the configuration the member's source currently selects, under the member's name and
+// labels. Source.Resolved records the version that was read.
+func resolveSource(repo repositories.IConfigRepository, member model.Configuration, ctx context.Context) (*model.Configuration, error) {
+	config, err := findConfig(repo, model.Configuration{}, member.Source.Name, member.Source.Version, ctx)
+	if err != nil {
+		return nil, fmt.Errorf("source %s: %w", member.Source.Name, err)
+	}
+
+	resolved := *config
+	resolved.Name = member.Name
+	resolved.Labels = member.Labels
+	resolved.Binding = member.Binding
+	source := *member.Source
+	source.Resolved = &config.Version
+	resolved.Source = &source
+	return &resolved, nil
+}
+
+// findConfig returns the configuration with the given name and exact version, or the highest version matching
+// a constraint. root counts as stored, it is the configuration being written or read.
+func findConfig(repo repositories.IConfigRepository, root model.Configuration, name string, version string, ctx context.Context) (*model.Configuration, error) {
+	constraint, err := model.ParseVersionConstraint(version)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", err.Error(), model.ErrInvalid)
+	}
+
+	if exact, ok := constraint.Exact(); ok {
+		if name == root.Name && exact == root.Version {
+			return &root, nil
+		}
+		config, err := repo.GetById(name, model.ToString(exact), ctx)
+		if errors.Is(err, model.ErrNotFound) {
+			return nil, fmt.Errorf("config %s %s does not exist: %w", name, version, model.ErrInvalid)
+		}
+		return config, err
+	}
+
+	candidates, err := repo.GetVersions(name, ctx)
+	if err != nil {
+		return nil, err
+	}
+	if name == root.Name {
+		candidates = append(candidates, root)
+	}
+
+	var best *model.Configuration
+	for i := range candidates {
+		c := &candidates[i]
+		if !constraint.Matches(c.Version) {
+			continue
+		}
+		if best == nil || model.CompareVersions(c.Version, best.Version) > 0 {
+			best = c
+		}
+	}
+	if best == nil {
+		return nil, fmt.Errorf("no version of config %s matches %s: %w", name, version, model.ErrInvalid)
+	}
+	return best, nil
+}
+
+func referenceKey(name string, version model.Version) string {
+	return name + "@" + model.ToString(version)
+}
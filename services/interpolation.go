@@ -0,0 +1,178 @@
+package services
+
+import (
+	"ars_projekat/model"
+	"ars_projekat/repositories"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// interpolator resolves placeholders across configurations. Documents are identified as config:name@version or
+// group:name@version/member[labels] and parameters as document#key, which is also how cycles are reported.
+type interpolator struct {
+	repo   repositories.IConfigRepository
+	ctx    context.Context
+	docs   map[string]*model.Configuration
+	values map[string]string
+	path   []string
+}
+
+func newInterpolator(repo repositories.IConfigRepository, ctx context.Context) *interpolator {
+	return &interpolator{
+		repo:   repo,
+		ctx:    ctx,
+		docs:   make(map[string]*model.Configuration),
+		values: make(map[string]string),
+	}
+}
+
+func configDoc(config model.Configuration) string {
+	return "config:" + referenceKey(config.Name, config.Version)
+}
+
+func memberDoc(group string, version string, member model.Configuration) string {
+	doc := "group:" + group + "@" + version + "/" + member.Name
+	if len(member.Labels) > 0 {
+		doc += "[" + model.SortLabels(member.Labels) + "]"
+	}
+	return doc
+}
+
+// interpolated reports whether any parameter of config holds a placeholder. Schemas skip placeholders when a config
+// is written, so configs holding them are checked again once their values are resolved.
+func interpolated(config model.Configuration) bool {
+	for _, v := range config.Parameters {
+		if model.HasPlaceholder(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// interpolate returns a copy of config with every placeholder replaced, config must already be merged with its bases.
+func (in *interpolator) interpolate(doc string, config *model.Configuration) (*model.Configuration, error) {
+	in.docs[doc] = config
+
+	keys := make([]string, 0, len(config.Parameters))
+	for k := range config.Parameters {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	params := make(map[string]string, len(config.Parameters))
+	for _, k := range keys {
+		value, err := in.value(doc, k)
+		if err != nil {
+			return nil, err
+		}
+		params[k] = value
+	}
+
+	resolved := *config
+	resolved.Parameters = params
+	return &resolved, nil
+}
+
+// value resolves a single parameter, placeholders in the values it references are resolved first.
+func (in *interpolator) value(doc string, key string) (string, error) {
+	node := doc + "#" + key
+	if v, ok := in.values[node]; ok {
+		return v, nil
+	}
+	for _, n := range in.path {
+		if n == node {
+			return "", fmt.Errorf("interpolation cycle %s -> %s: %w", strings.Join(in.path, " -> "), node, model.ErrInvalid)
+		}
+	}
+
+	config := in.docs[doc]
+	raw, ok := config.Parameters[key]
+	if !ok {
+		return "", fmt.Errorf("%s has no parameter %s: %w", doc, key, model.ErrInvalid)
+	}
+	if !strings.Contains(raw, "${") {
+		in.values[node] = raw
+		return raw, nil
+	}
+
+	in.path = append(in.path, node)
+	value, err := model.Interpolate(raw, func(ref model.ParameterReference) (string, error) {
+		target, err := in.document(doc, ref)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", ref.String(), err)
+		}
+		return in.value(target, ref.Key)
+	})
+	in.path = in.path[:len(in.path)-1]
+	if err != nil {
+		return "", fmt.Errorf("resolving %s: %w", node, err)
+	}
+
+	value, err = config.TypeOf(key).Normalize(value)
+	if err != nil {
+		return "", fmt.Errorf("resolving %s: %s: %w", node, err.Error(), model.ErrInvalid)
+	}
+	in.values[node] = value
+	return value, nil
+}
+
+// document loads the configuration a placeholder points at and returns its document id.
+func (in *interpolator) document(from string, ref model.ParameterReference) (string, error) {
+	switch ref.Kind {
+	case model.RefConfig:
+		config, err := findConfig(in.repo, model.Configuration{}, ref.Name, ref.Version, in.ctx)
+		if err != nil {
+			return "", err
+		}
+		doc := configDoc(*config)
+		if _, ok := in.docs[doc]; !ok {
+			effective, err := resolveEffective(in.repo, *config, in.ctx)
+			if err != nil {
+				return "", err
+			}
+			in.docs[doc] = effective
+		}
+		return doc, nil
+
+	case model.RefGroup:
+		version, err := model.ToVersion(ref.Version)
+		if err != nil {
+			return "", fmt.Errorf("group version %q must be exact: %w", ref.Version, model.ErrInvalid)
+		}
+		group, err := in.repo.GetGroupByParams(ref.Name, model.ToString(*version), "", in.ctx)
+		if err != nil {
+			return "", err
+		}
+		if group == nil {
+			return "", fmt.Errorf("group %s %s does not exist: %w", ref.Name, ref.Version, model.ErrInvalid)
+		}
+		var members []model.Configuration
+		for _, c := range group.Configurations {
+			if c.Name == ref.Member {
+				members = append(members, c)
+			}
+		}
+		switch len(members) {
+		case 0:
+			return "", fmt.Errorf("group %s %s has no member %s: %w", ref.Name, ref.Version, ref.Member, model.ErrInvalid)
+		case 1:
+		default:
+			return "", fmt.Errorf("group %s %s has %d members named %s: %w", ref.Name, ref.Version, len(members), ref.Member, model.ErrInvalid)
+		}
+
+		doc := memberDoc(ref.Name, model.ToString(*version), members[0])
+		if _, ok := in.docs[doc]; ok {
+			return doc, nil
+		}
+		effective, err := resolveEffective(in.repo, members[0], in.ctx)
+		if err != nil {
+			return "", err
+		}
+		in.docs[doc] = effective
+		return doc, nil
+	}
+
+	return from, nil
+}
@@ -0,0 +1,134 @@
+package services
+
+import (
+	"ars_projekat/model"
+	"ars_projekat/repositories"
+	"context"
+	"sort"
+	"strings"
+	"time"
+)
+
+// listEntry is a listed object before it is read, UpdatedAt is only known when listing by update time.
+type listEntry struct {
+	model.ObjectRef
+	UpdatedAt time.Time
+}
+
+// updateTimes reads the update times of the objects of kind under prefix from the ID index, keyed by name@version.
+// Objects missing from the result were indexed without one and have to be read. The latest entry of a name and
+// version wins, entries left by trashed objects are older than those of the objects stored again.
+func updateTimes(repo repositories.IConfigRepository, kind string, prefix string, ctx context.Context) (map[string]time.Time, error) {
+	objects, err := repo.ListIndexedObjects(kind, prefix, ctx)
+	if err != nil {
+		return nil, err
+	}
+	times := make(map[string]time.Time, len(objects))
+	for _, o := range objects {
+		key := referenceKey(o.Name, o.Version)
+		if !o.UpdatedAt.IsZero() && o.UpdatedAt.After(times[key]) {
+			times[key] = o.UpdatedAt
+		}
+	}
+	return times, nil
+}
+
+func compareEntries(sortBy string, a listEntry, b listEntry) int {
+	switch sortBy {
+	case model.SortByVersion:
+		if c := model.CompareVersions(a.Version, b.Version); c != 0 {
+			return c
+		}
+	case model.SortByUpdatedAt:
+		if c := a.UpdatedAt.Compare(b.UpdatedAt); c != 0 {
+			return c
+		}
+	}
+	if c := strings.Compare(a.Name, b.Name); c != 0 {
+		return c
+	}
+	return model.CompareVersions(a.Version, b.Version)
+}
+
+// entriesAfter sorts entries the way opts asks and drops those up to and including the cursor.
+func entriesAfter(entries []listEntry, opts model.ListOptions, cursor *model.PageCursor) []listEntry {
+	compare := func(a listEntry, b listEntry) int {
+		if opts.Order == model.OrderDesc {
+			return compareEntries(opts.Sort, b, a)
+		}
+		return compareEntries(opts.Sort, a, b)
+	}
+	sort.Slice(entries, func(i, j int) bool { return compare(entries[i], entries[j]) < 0 })
+	if cursor == nil {
+		return entries
+	}
+
+	last := listEntry{
+		ObjectRef: model.ObjectRef{Name: cursor.Name, Version: cursor.Version},
+		UpdatedAt: cursor.UpdatedAt,
+	}
+	i := sort.Search(len(entries), func(i int) bool { return compare(entries[i], last) > 0 })
+	return entries[i:]
+}
+
+// restrictEntries keeps the entries named in refs.
+func restrictEntries(entries []listEntry, refs []model.ObjectRef) []listEntry {
+	in := make(map[string]bool, len(refs))
+	for _, ref := range refs {
+		in[referenceKey(ref.Name, ref.Version)] = true
+	}
+	kept := entries[:0]
+	for _, e := range entries {
+		if in[referenceKey(e.Name, e.Version)] {
+			kept = append(kept, e)
+		}
+	}
+	return kept
+}
+
+// foldEntries keeps the entries directly below prefix and returns the folders holding the others.
+func foldEntries(entries []listEntry, prefix string) ([]listEntry, []string) {
+	folders := make(map[string]bool)
+	kept := entries[:0]
+	for _, e := range entries {
+		rest := strings.TrimPrefix(e.Name, prefix)
+		if folder, _, ok := strings.Cut(rest, "/"); ok {
+			folders[prefix+folder+"/"] = true
+			continue
+		}
+		kept = append(kept, e)
+	}
+	names := make([]string, 0, len(folders))
+	for folder := range folders {
+		names = append(names, folder)
+	}
+	sort.Strings(names)
+	return kept, names
+}
+
+// fillPage reads entries in order until load accepted opts.Limit of them. It returns the cursor of the next page,
+// empty once every entry was read.
+func fillPage(entries []listEntry, opts model.ListOptions, load func(e listEntry) (bool, error)) (string, error) {
+	kept := 0
+	for i, e := range entries {
+		ok, err := load(e)
+		if err != nil {
+			return "", err
+		}
+		if !ok {
+			continue
+		}
+		kept++
+		if kept == opts.Limit && i < len(entries)-1 {
+			cursor := model.PageCursor{
+				Sort:      opts.Sort,
+				Order:     opts.Order,
+				Name:      e.Name,
+				Version:   e.Version,
+				UpdatedAt: e.UpdatedAt,
+			}
+			return cursor.Encode(), nil
+		}
+	}
+	return "", nil
+}
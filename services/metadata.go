@@ -0,0 +1,65 @@
+package services
+
+import (
+	"ars_projekat/model"
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// IDs are sortable by creation time: milliseconds since idEpoch, then a node number picked at startup so
+// replicas rarely collide, then a sequence for IDs handed out in the same millisecond.
+const (
+	nodeBits     = 10
+	sequenceBits = 12
+)
+
+var idEpoch = time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+type idGenerator struct {
+	mu       sync.Mutex
+	node     int64
+	last     int64
+	sequence int64
+}
+
+var ids = &idGenerator{node: rand.Int63n(1 << nodeBits)}
+
+func (g *idGenerator) next() int64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := time.Since(idEpoch).Milliseconds()
+	if now < g.last {
+		now = g.last
+	}
+	if now == g.last {
+		g.sequence = (g.sequence + 1) & (1<<sequenceBits - 1)
+		if g.sequence == 0 {
+			// Sequence exhausted, borrow the next millisecond
+			now++
+		}
+	} else {
+		g.sequence = 0
+	}
+	g.last = now
+
+	return now<<(nodeBits+sequenceBits) | g.node<<sequenceBits | g.sequence
+}
+
+// stamp fills the server managed fields of an object that is being written. previousId and previous are what is
+// stored for the object, a zero previousId marks a new object, which is given a new ID.
+func stamp(id *int64, meta *model.Metadata, previousId int64, previous model.Metadata, ctx context.Context) {
+	now := time.Now().UTC()
+	meta.UpdatedAt = now
+	if previousId == 0 {
+		*id = ids.next()
+		meta.CreatedAt = now
+		meta.CreatedBy = AuthorFromContext(ctx)
+		return
+	}
+	*id = previousId
+	meta.CreatedAt = previous.CreatedAt
+	meta.CreatedBy = previous.CreatedBy
+}
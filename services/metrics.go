@@ -11,6 +11,8 @@ type MetricsService struct {
 	HttpRequestDuration      *prometheus.HistogramVec
 	AverageRequestDuration   *prometheus.GaugeVec
 	RequestsPerTimeUnit      *prometheus.CounterVec
+	RetentionRuns            *prometheus.CounterVec
+	PrunedVersions           *prometheus.CounterVec
 	Registry                 *prometheus.Registry
 }
 
@@ -62,12 +64,32 @@ func NewMetricsService() *MetricsService {
 	)
 	registry.MustRegister(requestsPerTimeUnit)
 
+	retentionRuns := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "retention_runs_total",
+			Help: "Number of retention pruner runs, including dry runs.",
+		},
+		[]string{"dry_run"},
+	)
+	registry.MustRegister(retentionRuns)
+
+	prunedVersions := prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "retention_pruned_versions_total",
+			Help: "Number of configuration and group versions removed by retention policies.",
+		},
+		[]string{"kind", "policy"},
+	)
+	registry.MustRegister(prunedVersions)
+
 	return &MetricsService{
 		HttpTotalRequests:        httpTotalRequests,
 		HttpSuccessfulRequests:   httpSuccessfulRequests,
 		HttpUnsuccessfulRequests: httpUnsuccessfulRequests,
 		AverageRequestDuration:   averageRequestDuration,
 		RequestsPerTimeUnit:      requestsPerTimeUnit,
+		RetentionRuns:            retentionRuns,
+		PrunedVersions:           prunedVersions,
 		Registry:                 registry,
 	}
 }
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"ars_projekat/model"
+	"fmt"
+)
+
+// references records which config versions group members read and which group versions other groups include, keyed
+// by name@version with the readers as "group@version member" or "group@version" values.
+type references struct {
+	// resolved holds the version every member reads now, an embedded member reads its own name and version.
+	resolved map[string][]string
+	// required holds the versions a member cannot be read without, the exact version a pinned member references or
+	// the only version matching a floating member's constraint.
+	required map[string][]string
+	// included holds the group versions other groups include.
+	included map[string][]string
+}
+
+// findReferences indexes the config versions the members of groups read and the groups they include. Floating
+// members are resolved against configs, which must hold every version of the names they reference.
+func findReferences(groups []model.ConfigurationGroup, configs []model.Configuration) references {
+	versions := make(map[string][]model.Version)
+	for _, c := range configs {
+		versions[c.Name] = append(versions[c.Name], c.Version)
+	}
+
+	refs := references{resolved: map[string][]string{}, required: map[string][]string{}, included: map[string][]string{}}
+	for _, g := range groups {
+		for _, include := range g.Includes {
+			key := referenceKey(include.Name, include.Version)
+			refs.included[key] = append(refs.included[key], referenceKey(g.Name, g.Version))
+		}
+		for _, member := range g.Configurations {
+			reader := fmt.Sprintf("%s@%s %s", g.Name, model.ToString(g.Version), member.Name)
+			if member.Source == nil {
+				key := referenceKey(member.Name, member.Version)
+				refs.resolved[key] = append(refs.resolved[key], reader)
+				continue
+			}
+			constraint, err := model.ParseVersionConstraint(member.Source.Version)
+			if err != nil {
+				continue
+			}
+			if exact, ok := constraint.Exact(); ok {
+				key := referenceKey(member.Source.Name, exact)
+				refs.resolved[key] = append(refs.resolved[key], reader)
+				refs.required[key] = append(refs.required[key], reader)
+				continue
+			}
+			var matches []model.Version
+			for _, v := range versions[member.Source.Name] {
+				if constraint.Matches(v) {
+					matches = append(matches, v)
+				}
+			}
+			if len(matches) == 0 {
+				continue
+			}
+			best := matches[0]
+			for _, v := range matches[1:] {
+				if model.CompareVersions(v, best) > 0 {
+					best = v
+				}
+			}
+			key := referenceKey(member.Source.Name, best)
+			refs.resolved[key] = append(refs.resolved[key], reader)
+			if len(matches) == 1 {
+				refs.required[key] = append(refs.required[key], reader)
+			}
+		}
+	}
+	return refs
+}
+
+// inUse tells whether deleting the config or group version is refused, kind is model.RetentionConfigs or
+// model.RetentionGroups.
+func (r references) inUse(kind string, name string, version model.Version) bool {
+	if kind == model.RetentionConfigs {
+		return len(r.required[referenceKey(name, version)]) > 0
+	}
+	return len(r.included[referenceKey(name, version)]) > 0
+}
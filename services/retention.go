@@ -0,0 +1,283 @@
+package services
+
+import (
+	"ars_projekat/model"
+	"ars_projekat/repositories"
+	"context"
+	"log"
+	"sort"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type RetentionService struct {
+	repo    repositories.IConfigRepository
+	configs ConfigurationService
+	groups  ConfigurationGroupService
+	metrics *MetricsService
+	Tracer  trace.Tracer
+}
+
+func NewRetentionService(repo repositories.IConfigRepository, configs ConfigurationService, groups ConfigurationGroupService, metrics *MetricsService, tracer trace.Tracer) RetentionService {
+	return RetentionService{
+		repo:    repo,
+		configs: configs,
+		groups:  groups,
+		metrics: metrics,
+		Tracer:  tracer,
+	}
+}
+
+func (s RetentionService) AddPolicy(policy *model.RetentionPolicy, ctx context.Context) error {
+	ctx, span := s.Tracer.Start(ctx, "RetentionService.AddPolicy")
+	defer span.End()
+
+	if err := policy.Validate(); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+
+	return s.repo.AddRetentionPolicy(policy, ctx)
+}
+
+func (s RetentionService) GetPolicies(ctx context.Context) ([]model.RetentionPolicy, error) {
+	ctx, span := s.Tracer.Start(ctx, "RetentionService.GetPolicies")
+	defer span.End()
+
+	return s.repo.GetRetentionPolicies(ctx)
+}
+
+func (s RetentionService) GetPolicy(name string, ctx context.Context) (*model.RetentionPolicy, error) {
+	ctx, span := s.Tracer.Start(ctx, "RetentionService.GetPolicy")
+	defer span.End()
+
+	return s.repo.GetRetentionPolicy(name, ctx)
+}
+
+func (s RetentionService) DeletePolicy(name string, ctx context.Context) error {
+	ctx, span := s.Tracer.Start(ctx, "RetentionService.DeletePolicy")
+	defer span.End()
+
+	return s.repo.DeleteRetentionPolicy(name, ctx)
+}
+
+// retentionCandidate is a single config or group version that retention policies are evaluated against.
+type retentionCandidate struct {
+	kind      string
+	name      string
+	version   model.Version
+	labels    []map[string]string
+	createdAt time.Time
+	verdicts  []retentionVerdict
+	// config or group holds the version as loaded, a pruned version goes to the trash as it was.
+	config *model.Configuration
+	group  *model.ConfigurationGroup
+}
+
+type retentionVerdict struct {
+	policy string
+	prune  bool
+}
+
+// Run evaluates every policy and removes the versions none of the matching policies wants to keep.
+// With dryRun nothing is removed and the report lists what would have been pruned.
+func (s RetentionService) Run(dryRun bool, ctx context.Context) (*model.RetentionReport, error) {
+	ctx, span := s.Tracer.Start(ctx, "RetentionService.Run")
+	defer span.End()
+
+	report := &model.RetentionReport{DryRun: dryRun, StartedAt: time.Now().UTC(), Pruned: []model.PrunedVersion{}}
+	s.metrics.RetentionRuns.WithLabelValues(strconv.FormatBool(dryRun)).Inc()
+
+	policies, err := s.repo.GetRetentionPolicies(ctx)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	if len(policies) == 0 {
+		span.SetStatus(codes.Ok, "SERVICE - No policies")
+		return report, nil
+	}
+
+	candidates, refs, err := s.loadCandidates(ctx)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	report.Checked = len(candidates)
+
+	for _, policy := range policies {
+		if err = s.evaluate(policy, candidates, refs.resolved, report.StartedAt, ctx); err != nil {
+			span.SetStatus(codes.Error, err.Error())
+			return nil, err
+		}
+	}
+
+	pruneCtx := WithAuthor(ctx, "retention")
+	for _, c := range candidates {
+		if len(c.verdicts) == 0 {
+			continue
+		}
+		prune := true
+		for _, v := range c.verdicts {
+			prune = prune && v.prune
+		}
+		// Deleting a config a member cannot be read without or a group another group includes is refused, whatever
+		// the policies decide.
+		if !prune || refs.inUse(c.kind, c.name, c.version) {
+			continue
+		}
+
+		pruned := model.PrunedVersion{Kind: c.kind, Name: c.name, Version: c.version, Policy: c.verdicts[0].policy}
+		if !dryRun {
+			if err = s.prune(c, pruneCtx); err != nil {
+				span.SetStatus(codes.Error, err.Error())
+				return report, err
+			}
+			s.metrics.PrunedVersions.WithLabelValues(c.kind, pruned.Policy).Inc()
+		}
+		report.Pruned = append(report.Pruned, pruned)
+	}
+
+	span.SetStatus(codes.Ok, "SERVICE - Success")
+	return report, nil
+}
+
+// Start runs the pruner every interval until ctx is cancelled.
+func (s RetentionService) Start(interval time.Duration, ctx context.Context) {
+	ticker := time.NewTicker(interval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				report, err := s.Run(false, ctx)
+				if err != nil {
+					log.Println("Retention run failed:", err)
+					continue
+				}
+				if len(report.Pruned) > 0 {
+					log.Printf("Retention pruned %d of %d versions", len(report.Pruned), report.Checked)
+				}
+			}
+		}
+	}()
+}
+
+// loadCandidates lists every config and group version, together with the config versions group members read and
+// the group versions groups include.
+func (s RetentionService) loadCandidates(ctx context.Context) ([]*retentionCandidate, references, error) {
+	configs, err := s.repo.GetAll(ctx)
+	if err != nil {
+		return nil, references{}, err
+	}
+	groups, err := s.repo.GetAllGroups(ctx)
+	if err != nil {
+		return nil, references{}, err
+	}
+
+	var candidates []*retentionCandidate
+	for i, c := range configs {
+		candidates = append(candidates, &retentionCandidate{
+			kind:      model.RetentionConfigs,
+			name:      c.Name,
+			version:   c.Version,
+			labels:    []map[string]string{c.Labels},
+			createdAt: c.CreatedAt,
+			config:    &configs[i],
+		})
+	}
+
+	for i, g := range groups {
+		candidate := &retentionCandidate{kind: model.RetentionGroups, name: g.Name, version: g.Version, createdAt: g.CreatedAt, group: &groups[i]}
+		for _, member := range g.Configurations {
+			candidate.labels = append(candidate.labels, member.Labels)
+		}
+		candidates = append(candidates, candidate)
+	}
+
+	return candidates, findReferences(groups, configs), nil
+}
+
+func (s RetentionService) evaluate(policy model.RetentionPolicy, candidates []*retentionCandidate, referenced map[string][]string, now time.Time, ctx context.Context) error {
+	maxAge, err := policy.MaxAge()
+	if err != nil {
+		return err
+	}
+
+	byName := make(map[string][]*retentionCandidate)
+	for _, c := range candidates {
+		if policy.Matches(c.kind, c.name, c.labels) {
+			byName[c.kind+"/"+c.name] = append(byName[c.kind+"/"+c.name], c)
+		}
+	}
+
+	for _, versions := range byName {
+		sort.Slice(versions, func(i, j int) bool {
+			return model.CompareVersions(versions[i].version, versions[j].version) > 0
+		})
+
+		for i, c := range versions {
+			keep := i < policy.KeepLast
+			if !keep && policy.KeepReferenced && c.kind == model.RetentionConfigs {
+				keep = len(referenced[referenceKey(c.name, c.version)]) > 0
+			}
+			if !keep && maxAge > 0 {
+				if err = s.loadCreatedAt(c, ctx); err != nil {
+					return err
+				}
+				keep = !c.createdAt.IsZero() && now.Sub(c.createdAt) < maxAge
+			}
+			c.verdicts = append(c.verdicts, retentionVerdict{policy: policy.Name, prune: !keep})
+		}
+	}
+
+	return nil
+}
+
+// loadCreatedAt takes the creation time of a version without Metadata.CreatedAt from the first revision after its
+// last delete. Versions written before metadata and revisions were recorded have no known age and are only
+// protected by keepLast.
+func (s RetentionService) loadCreatedAt(c *retentionCandidate, ctx context.Context) error {
+	if !c.createdAt.IsZero() {
+		return nil
+	}
+
+	version := model.ToString(c.version)
+	if c.kind == model.RetentionConfigs {
+		revisions, err := s.repo.GetConfigRevisions(c.name, version, ctx)
+		if err != nil {
+			return err
+		}
+		for i, rev := range revisions {
+			if i == 0 || revisions[i-1].Deleted {
+				c.createdAt = rev.Timestamp
+			}
+		}
+		return nil
+	}
+
+	revisions, err := s.repo.GetGroupRevisions(c.name, version, ctx)
+	if err != nil {
+		return err
+	}
+	for i, rev := range revisions {
+		if i == 0 || revisions[i-1].Deleted {
+			c.createdAt = rev.Timestamp
+		}
+	}
+	return nil
+}
+
+// prune moves the version to the trash. Run already checked against the references it loaded that nothing needs
+// the version, so the checks of a delete are not repeated.
+func (s RetentionService) prune(c *retentionCandidate, ctx context.Context) error {
+	if c.kind == model.RetentionConfigs {
+		return s.configs.trash(*c.config, ctx)
+	}
+	return s.groups.trash(*c.group, "", ctx)
+}
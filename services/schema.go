@@ -0,0 +1,151 @@
+package services
+
+import (
+	"ars_projekat/model"
+	"ars_projekat/repositories"
+	"context"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type SchemaService struct {
+	repo   repositories.IConfigRepository
+	Tracer trace.Tracer
+}
+
+func NewSchemaService(repo repositories.IConfigRepository, tracer trace.Tracer) SchemaService {
+	return SchemaService{
+		repo:   repo,
+		Tracer: tracer,
+	}
+}
+
+// Register stores the schema as a new version, earlier versions stay readable but only the latest one is enforced.
+func (s SchemaService) Register(schema *model.Schema, ctx context.Context) error {
+	ctx, span := s.Tracer.Start(ctx, "SchemaService.Register")
+	defer span.End()
+
+	if err := schema.Validate(); err != nil {
+		err = fmt.Errorf("%s: %w", err.Error(), model.ErrInvalid)
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+
+	schema.CreatedAt = time.Now().UTC()
+	schema.CreatedBy = AuthorFromContext(ctx)
+	if err := s.repo.AddSchema(schema, ctx); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "SERVICE - Success")
+	return nil
+}
+
+func (s SchemaService) List(ctx context.Context) ([]model.Schema, error) {
+	ctx, span := s.Tracer.Start(ctx, "SchemaService.List")
+	defer span.End()
+
+	schemas, err := s.repo.GetSchemas(ctx)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	span.SetStatus(codes.Ok, "SERVICE - Success")
+	return schemas, nil
+}
+
+func (s SchemaService) Versions(name string, ctx context.Context) ([]model.Schema, error) {
+	ctx, span := s.Tracer.Start(ctx, "SchemaService.Versions")
+	defer span.End()
+
+	schemas, err := s.repo.GetSchemaVersions(name, ctx)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	if len(schemas) == 0 {
+		err = fmt.Errorf("schema %s %w", name, model.ErrNotFound)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	span.SetStatus(codes.Ok, "SERVICE - Success")
+	return schemas, nil
+}
+
+// Get returns the given version of a schema, version 0 selects the latest one.
+func (s SchemaService) Get(name string, version int64, ctx context.Context) (*model.Schema, error) {
+	ctx, span := s.Tracer.Start(ctx, "SchemaService.Get")
+	defer span.End()
+
+	if version == 0 {
+		schemas, err := s.Versions(name, ctx)
+		if err != nil {
+			span.SetStatus(codes.Error, err.Error())
+			return nil, err
+		}
+		return &schemas[len(schemas)-1], nil
+	}
+
+	schema, err := s.repo.GetSchema(name, version, ctx)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	span.SetStatus(codes.Ok, "SERVICE - Success")
+	return schema, nil
+}
+
+func (s SchemaService) Delete(name string, ctx context.Context) error {
+	ctx, span := s.Tracer.Start(ctx, "SchemaService.Delete")
+	defer span.End()
+
+	if err := model.ValidateConfigName(name); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	if _, err := s.Versions(name, ctx); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	if err := s.repo.DeleteSchema(name, ctx); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "SERVICE - Success")
+	return nil
+}
+
+// checkSchemas validates configurations against the latest version of every schema that applies to them.
+// All violations are collected into a single *model.ValidationError.
+func checkSchemas(repo repositories.IConfigRepository, configs []model.Configuration, ctx context.Context) error {
+	schemas, err := repo.GetSchemas(ctx)
+	if err != nil {
+		return err
+	}
+
+	var violations []model.Violation
+	for _, config := range configs {
+		for _, schema := range schemas {
+			if !schema.AppliesTo(config) {
+				continue
+			}
+			for _, v := range schema.Check(config) {
+				v.Config = config.Name
+				violations = append(violations, v)
+			}
+		}
+	}
+
+	if len(violations) > 0 {
+		return &model.ValidationError{Violations: violations}
+	}
+	return nil
+}
@@ -0,0 +1,402 @@
+package services
+
+import (
+	"ars_projekat/model"
+	"ars_projekat/repositories"
+	"context"
+	"encoding/json"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	searchWatchWait  = 5 * time.Minute
+	searchRetryDelay = 5 * time.Second
+)
+
+// Hits score the weight of every matching field multiplied by how closely it matched.
+var searchFieldWeights = map[string]float64{
+	model.FieldName:           5,
+	model.FieldParameterKey:   4,
+	model.FieldLabel:          3,
+	model.FieldMetadata:       2,
+	model.FieldParameterValue: 1,
+}
+
+const (
+	exactMatchWeight     = 3
+	prefixMatchWeight    = 2
+	substringMatchWeight = 1
+	// Fields of group members count half, a group is a weaker match than the config it holds.
+	memberMatchWeight = 0.5
+)
+
+// SearchService answers searches from an in-memory index. The index follows the configs and groups keyspaces
+// through blocking queries, so a search never reads Consul. Each wake-up only decodes and re-indexes the keys
+// written since the last one.
+type SearchService struct {
+	repo   repositories.IConfigRepository
+	index  *searchIndex
+	Tracer trace.Tracer
+}
+
+func NewSearchService(repo repositories.IConfigRepository, tracer trace.Tracer) SearchService {
+	return SearchService{
+		repo:   repo,
+		index:  newSearchIndex(),
+		Tracer: tracer,
+	}
+}
+
+// Refresh loads every config and group into the index without waiting for a change.
+func (s SearchService) Refresh(ctx context.Context) error {
+	ctx, span := s.Tracer.Start(ctx, "SearchService.Refresh")
+	defer span.End()
+
+	if _, err := s.refreshConfigs(0, 0, ctx); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	if _, err := s.refreshGroups(0, 0, ctx); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	span.SetStatus(codes.Ok, "Success refreshing search index")
+	return nil
+}
+
+// Start keeps the index up to date until ctx is cancelled.
+func (s SearchService) Start(ctx context.Context) {
+	go s.watch("configs", s.refreshConfigs, ctx)
+	go s.watch("groups", s.refreshGroups, ctx)
+}
+
+func (s SearchService) watch(name string, refresh func(uint64, time.Duration, context.Context) (uint64, error), ctx context.Context) {
+	var index uint64
+	for ctx.Err() == nil {
+		next, err := refresh(index, searchWatchWait, ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("Search index watch of %s failed: %v", name, err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(searchRetryDelay):
+			}
+			continue
+		}
+		// An index going backwards means the keyspace was reset, start over from a full read.
+		if next < index {
+			next = 0
+		}
+		index = next
+	}
+}
+
+func (s SearchService) refreshConfigs(waitIndex uint64, wait time.Duration, ctx context.Context) (uint64, error) {
+	changes, next, err := s.repo.WatchConfigs(waitIndex, wait, ctx)
+	if err != nil {
+		return waitIndex, err
+	}
+	docs := make([]*searchDocument, 0, len(changes.Changed))
+	for _, config := range changes.Changed {
+		docs = append(docs, configDocument(config))
+	}
+	s.index.update(model.KindConfig, docs, changes.Present)
+	return next, nil
+}
+
+func (s SearchService) refreshGroups(waitIndex uint64, wait time.Duration, ctx context.Context) (uint64, error) {
+	changes, next, err := s.repo.WatchGroups(waitIndex, wait, ctx)
+	if err != nil {
+		return waitIndex, err
+	}
+	docs := make([]*searchDocument, 0, len(changes.Changed))
+	for _, group := range changes.Changed {
+		docs = append(docs, groupDocument(group))
+	}
+	s.index.update(model.KindGroup, docs, changes.Present)
+	return next, nil
+}
+
+func (s SearchService) Search(query model.SearchQuery, ctx context.Context) (*model.SearchResult, error) {
+	_, span := s.Tracer.Start(ctx, "SearchService.Search")
+	defer span.End()
+
+	if err := query.Validate(); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	hits := s.index.search(query)
+	result := &model.SearchResult{
+		Query: query.Text,
+		Mode:  query.Mode,
+		Total: len(hits),
+		Hits:  hits,
+	}
+	if len(result.Hits) > query.Limit {
+		result.Hits = result.Hits[:query.Limit]
+	}
+
+	span.SetStatus(codes.Ok, "Success searching")
+	return result, nil
+}
+
+type searchField struct {
+	field  string
+	member string
+	key    string
+	value  string
+}
+
+type searchDocument struct {
+	kind    string
+	name    string
+	version model.Version
+	fields  []searchField
+	// source is the JSON of the indexed object, a document is only re-indexed when it differs.
+	source string
+}
+
+func (d *searchDocument) id() string {
+	return d.kind + "/" + d.name + "/" + model.ToString(d.version)
+}
+
+func configDocument(config model.Configuration) *searchDocument {
+	doc := &searchDocument{kind: model.KindConfig, name: config.Name, version: config.Version}
+	doc.addConfig(config, "")
+	doc.addMetadata(config.Metadata, "")
+	doc.setSource(config)
+	return doc
+}
+
+func groupDocument(group model.ConfigurationGroup) *searchDocument {
+	doc := &searchDocument{kind: model.KindGroup, name: group.Name, version: group.Version}
+	doc.fields = append(doc.fields, searchField{field: model.FieldName, value: group.Name})
+	doc.addMetadata(group.Metadata, "")
+	for _, member := range group.Configurations {
+		doc.addConfig(member, member.Name)
+	}
+	doc.setSource(group)
+	return doc
+}
+
+func (d *searchDocument) addConfig(config model.Configuration, member string) {
+	d.fields = append(d.fields, searchField{field: model.FieldName, member: member, value: config.Name})
+	for k, v := range config.Parameters {
+		d.fields = append(d.fields,
+			searchField{field: model.FieldParameterKey, member: member, key: k, value: k},
+			searchField{field: model.FieldParameterValue, member: member, key: k, value: v})
+	}
+	for k, v := range config.Labels {
+		d.fields = append(d.fields, searchField{field: model.FieldLabel, member: member, key: k, value: k + "=" + v})
+	}
+}
+
+func (d *searchDocument) addMetadata(meta model.Metadata, member string) {
+	for key, value := range map[string]string{
+		"description": meta.Description,
+		"owner":       meta.Owner,
+		"createdBy":   meta.CreatedBy,
+	} {
+		if value != "" {
+			d.fields = append(d.fields, searchField{field: model.FieldMetadata, member: member, key: key, value: value})
+		}
+	}
+	for k, v := range meta.Annotations {
+		d.fields = append(d.fields, searchField{field: model.FieldMetadata, member: member, key: k, value: k + "=" + v})
+	}
+}
+
+func (d *searchDocument) setSource(v interface{}) {
+	source, err := json.Marshal(v)
+	if err == nil {
+		d.source = string(source)
+	}
+}
+
+type searchPosting struct {
+	doc   string
+	field int
+}
+
+// searchIndex maps the lowercased value of every field to the fields holding it. The sorted vocabulary lets prefix
+// queries seek to their first term instead of checking all of them.
+type searchIndex struct {
+	mu         sync.RWMutex
+	docs       map[string]*searchDocument
+	postings   map[string][]searchPosting
+	vocabulary []string
+}
+
+func newSearchIndex() *searchIndex {
+	return &searchIndex{
+		docs:     make(map[string]*searchDocument),
+		postings: make(map[string][]searchPosting),
+	}
+}
+
+// update indexes the changed docs of kind and drops the indexed documents of kind that present no longer names.
+func (ix *searchIndex) update(kind string, docs []*searchDocument, present []model.ObjectRef) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	changed := false
+	current := make(map[string]bool, len(present))
+	for _, ref := range present {
+		current[(&searchDocument{kind: kind, name: ref.Name, version: ref.Version}).id()] = true
+	}
+	for _, doc := range docs {
+		id := doc.id()
+		if old, ok := ix.docs[id]; ok {
+			if old.source == doc.source {
+				continue
+			}
+			ix.remove(id)
+		}
+		ix.add(id, doc)
+		changed = true
+	}
+	for id, doc := range ix.docs {
+		if doc.kind == kind && !current[id] {
+			ix.remove(id)
+			changed = true
+		}
+	}
+
+	if changed {
+		ix.vocabulary = ix.vocabulary[:0]
+		for term := range ix.postings {
+			ix.vocabulary = append(ix.vocabulary, term)
+		}
+		sort.Strings(ix.vocabulary)
+	}
+}
+
+func (ix *searchIndex) add(id string, doc *searchDocument) {
+	ix.docs[id] = doc
+	for i, f := range doc.fields {
+		term := strings.ToLower(f.value)
+		ix.postings[term] = append(ix.postings[term], searchPosting{doc: id, field: i})
+	}
+}
+
+func (ix *searchIndex) remove(id string) {
+	doc := ix.docs[id]
+	delete(ix.docs, id)
+	for _, f := range doc.fields {
+		term := strings.ToLower(f.value)
+		kept := ix.postings[term][:0]
+		for _, p := range ix.postings[term] {
+			if p.doc != id {
+				kept = append(kept, p)
+			}
+		}
+		if len(kept) == 0 {
+			delete(ix.postings, term)
+		} else {
+			ix.postings[term] = kept
+		}
+	}
+}
+
+func (ix *searchIndex) search(query model.SearchQuery) []model.SearchHit {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	text := strings.ToLower(strings.TrimSpace(query.Text))
+	fields := make(map[string]bool)
+	for _, f := range query.Fields {
+		fields[f] = true
+	}
+
+	var terms []string
+	switch query.Mode {
+	case model.SearchExact:
+		if _, ok := ix.postings[text]; ok {
+			terms = append(terms, text)
+		}
+	case model.SearchPrefix:
+		for i := sort.SearchStrings(ix.vocabulary, text); i < len(ix.vocabulary) && strings.HasPrefix(ix.vocabulary[i], text); i++ {
+			terms = append(terms, ix.vocabulary[i])
+		}
+	case model.SearchSubstring:
+		for _, term := range ix.vocabulary {
+			if strings.Contains(term, text) {
+				terms = append(terms, term)
+			}
+		}
+	}
+
+	hits := make(map[string]*model.SearchHit)
+	for _, term := range terms {
+		closeness := float64(substringMatchWeight)
+		if term == text {
+			closeness = exactMatchWeight
+		} else if strings.HasPrefix(term, text) {
+			closeness = prefixMatchWeight
+		}
+		for _, p := range ix.postings[term] {
+			doc := ix.docs[p.doc]
+			f := doc.fields[p.field]
+			if query.Kind != "" && doc.kind != query.Kind || len(fields) > 0 && !fields[f.field] {
+				continue
+			}
+			hit, ok := hits[p.doc]
+			if !ok {
+				hit = &model.SearchHit{Kind: doc.kind, Name: doc.name, Version: doc.version}
+				hits[p.doc] = hit
+			}
+			score := searchFieldWeights[f.field] * closeness
+			if f.member != "" {
+				score *= memberMatchWeight
+			}
+			hit.Score += score
+			hit.Matches = append(hit.Matches, model.SearchMatch{
+				Field:     f.field,
+				Member:    f.member,
+				Key:       f.key,
+				Value:     f.value,
+				Highlight: model.Highlight(f.value, text),
+			})
+		}
+	}
+
+	result := make([]model.SearchHit, 0, len(hits))
+	for _, hit := range hits {
+		sort.Slice(hit.Matches, func(i, j int) bool {
+			a, b := hit.Matches[i], hit.Matches[j]
+			if wa, wb := searchFieldWeights[a.Field], searchFieldWeights[b.Field]; wa != wb {
+				return wa > wb
+			}
+			if a.Member != b.Member {
+				return a.Member < b.Member
+			}
+			return a.Key < b.Key
+		})
+		result = append(result, *hit)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return model.CompareVersions(a.Version, b.Version) < 0
+	})
+	return result
+}
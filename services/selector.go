@@ -0,0 +1,50 @@
+package services
+
+import (
+	"ars_projekat/model"
+	"ars_projekat/repositories"
+	"context"
+)
+
+// indexedCandidates intersects the objects the label index names for every positive requirement of selector. It
+// reports false when the selector has no positive requirement, the index cannot narrow such a query down.
+func indexedCandidates(repo repositories.IConfigRepository, kind string, selector model.Selector, ctx context.Context) ([]model.ObjectRef, bool, error) {
+	var candidates []model.ObjectRef
+	indexed := false
+	for _, r := range selector {
+		if !r.Positive() {
+			continue
+		}
+		values := r.Values
+		if r.Operator == model.SelectExists {
+			values = nil
+		}
+		refs, err := repo.FindByLabel(kind, r.Key, values, ctx)
+		if err != nil {
+			return nil, true, err
+		}
+		if !indexed {
+			candidates, indexed = refs, true
+		} else {
+			candidates = intersectRefs(candidates, refs)
+		}
+		if len(candidates) == 0 {
+			break
+		}
+	}
+	return candidates, indexed, nil
+}
+
+func intersectRefs(a []model.ObjectRef, b []model.ObjectRef) []model.ObjectRef {
+	in := make(map[string]bool, len(b))
+	for _, ref := range b {
+		in[referenceKey(ref.Name, ref.Version)] = true
+	}
+	var both []model.ObjectRef
+	for _, ref := range a {
+		if in[referenceKey(ref.Name, ref.Version)] {
+			both = append(both, ref)
+		}
+	}
+	return both
+}
@@ -0,0 +1,158 @@
+package services
+
+import (
+	"ars_projekat/model"
+	"ars_projekat/repositories"
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TrashService lists and purges deleted configs and groups, they are purged for good once purgeDelay has passed.
+type TrashService struct {
+	repo       repositories.IConfigRepository
+	purgeDelay time.Duration
+	Tracer     trace.Tracer
+}
+
+func NewTrashService(repo repositories.IConfigRepository, purgeDelay time.Duration, tracer trace.Tracer) TrashService {
+	return TrashService{
+		repo:       repo,
+		purgeDelay: purgeDelay,
+		Tracer:     tracer,
+	}
+}
+
+func (s TrashService) List(ctx context.Context) (*model.Trash, error) {
+	ctx, span := s.Tracer.Start(ctx, "TrashService.List")
+	defer span.End()
+
+	trash, err := s.repo.GetTrash(ctx)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	for i := range trash.Configurations {
+		trash.Configurations[i].PurgeAt = trash.Configurations[i].DeletedAt.Add(s.purgeDelay)
+	}
+	for i := range trash.Groups {
+		trash.Groups[i].PurgeAt = trash.Groups[i].DeletedAt.Add(s.purgeDelay)
+	}
+
+	span.SetStatus(codes.Ok, "SERVICE - Success")
+	return trash, nil
+}
+
+func (s TrashService) PurgeConfig(name string, version string, ctx context.Context) error {
+	ctx, span := s.Tracer.Start(ctx, "TrashService.PurgeConfig")
+	defer span.End()
+
+	if _, err := s.repo.GetTrashedConfig(name, version, ctx); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	if err := s.repo.PurgeConfig(name, version, ctx); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "SERVICE - Success")
+	return nil
+}
+
+func (s TrashService) PurgeGroup(name string, version string, ctx context.Context) error {
+	ctx, span := s.Tracer.Start(ctx, "TrashService.PurgeGroup")
+	defer span.End()
+
+	trashed, err := s.repo.GetTrashedGroups(name, version, ctx)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	if len(trashed) == 0 {
+		err = fmt.Errorf("trashed group %s %s %w", name, version, model.ErrNotFound)
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	if err = s.repo.PurgeGroup(name, version, ctx); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "SERVICE - Success")
+	return nil
+}
+
+// PurgeExpired removes everything trashed longer than the purge delay ago and returns how many entries were removed.
+// A group version is purged once its most recent delete has expired, so it is always restored as a whole.
+func (s TrashService) PurgeExpired(ctx context.Context) (int, error) {
+	ctx, span := s.Tracer.Start(ctx, "TrashService.PurgeExpired")
+	defer span.End()
+
+	trash, err := s.repo.GetTrash(ctx)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return 0, err
+	}
+
+	cutoff := time.Now().UTC().Add(-s.purgeDelay)
+	purged := 0
+	for _, t := range trash.Configurations {
+		if t.DeletedAt.After(cutoff) {
+			continue
+		}
+		if err = s.repo.PurgeConfig(t.Configuration.Name, model.ToString(t.Configuration.Version), ctx); err != nil {
+			span.SetStatus(codes.Error, err.Error())
+			return purged, err
+		}
+		purged++
+	}
+
+	latest := make(map[string]model.TrashedGroup)
+	for _, t := range trash.Groups {
+		key := t.Name + "/" + model.ToString(t.Version)
+		if prev, ok := latest[key]; !ok || t.DeletedAt.After(prev.DeletedAt) {
+			latest[key] = t
+		}
+	}
+	for _, t := range latest {
+		if t.DeletedAt.After(cutoff) {
+			continue
+		}
+		if err = s.repo.PurgeGroup(t.Name, model.ToString(t.Version), ctx); err != nil {
+			span.SetStatus(codes.Error, err.Error())
+			return purged, err
+		}
+		purged++
+	}
+
+	span.SetStatus(codes.Ok, "SERVICE - Success")
+	return purged, nil
+}
+
+// Start purges expired trash in the background until ctx is cancelled.
+func (s TrashService) Start(interval time.Duration, ctx context.Context) {
+	ticker := time.NewTicker(interval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				purged, err := s.PurgeExpired(ctx)
+				if err != nil {
+					log.Println("Trash purge failed:", err)
+					continue
+				}
+				if purged > 0 {
+					log.Printf("Purged %d expired trash entries", purged)
+				}
+			}
+		}
+	}()
+}
@@ -5,8 +5,10 @@ import (
 	"ars_projekat/repositories"
 	"ars_projekat/services"
 	"context"
-
+	"fmt"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -17,51 +19,57 @@ func TestConfigurationGroupService_Add(t *testing.T) {
 		Name:    "testGroup",
 		Version: model.Version{Major: 1, Minor: 0, Patch: 0},
 		Configurations: []model.Configuration{
-			{Name: "config1", Version: model.Version{Major: 1, Minor: 0, Patch: 0}, Labels: map[string]string{"label1": "value1"}},
+			{Name: "config1", Version: model.Version{Major: 1, Minor: 0, Patch: 0}, Labels: map[string]string{"label1": "value1"}, Binding: model.BindingPinned},
 		},
 	}
 
 	mockRepo := new(repositories.MockConfigRepository)
+	mockRepo.On("GetSchemas", mock.Anything).Return([]model.Schema{}, nil)
 
-	// Set up mock expectations for each configuration in the group
-	for _, config := range configGroup.Configurations {
-		mockRepo.On("AddGroup", configGroup.Name, model.ToString(configGroup.Version), model.SortLabels(config.Labels), config, mock.Anything).Return(nil)
-	}
+	// The members, the document and the revision are written in one call
+	mockRepo.On("GetGroupDocument", configGroup.Name, model.ToString(configGroup.Version), mock.Anything).Return((*model.GroupDocument)(nil), model.ErrNotFound)
+	mockRepo.On("SaveGroup", mock.MatchedBy(func(doc *model.GroupDocument) bool {
+		return doc.Id != 0 && doc.Name == configGroup.Name && doc.CreatedBy == "anonymous" && !doc.CreatedAt.IsZero()
+	}), configGroup.Configurations, mock.MatchedBy(func(rev *model.GroupRevision) bool {
+		return rev.Author == "anonymous" && !rev.Timestamp.IsZero()
+	}), mock.Anything).Return(nil).Once()
 
 	service := services.NewConfigurationGroupService(mockRepo, NewTestTracer())
 
 	err := service.Add(configGroup, context.Background())
 	assert.NoError(t, err)
 
-	// Check that AddGroup was called with the expected parameters for each configuration
-	for _, config := range configGroup.Configurations {
-		mockRepo.AssertCalled(t, "AddGroup", configGroup.Name, model.ToString(configGroup.Version), model.SortLabels(config.Labels), config, mock.Anything)
-	}
 	mockRepo.AssertExpectations(t)
 }
 
 func TestConfigurationGroupService_Save(t *testing.T) {
 	mockRepo := new(repositories.MockConfigRepository)
+	mockRepo.On("GetSchemas", mock.Anything).Return([]model.Schema{}, nil)
 	service := services.NewConfigurationGroupService(mockRepo, NewTestTracer())
 
 	configGroup := &model.ConfigurationGroup{
 		Name:    "testGroup",
 		Version: model.Version{Major: 1, Minor: 0, Patch: 0},
 		Configurations: []model.Configuration{
-			{Name: "config1", Version: model.Version{Major: 1, Minor: 0, Patch: 0}, Labels: map[string]string{"label1": "value1"}},
+			{Name: "config1", Version: model.Version{Major: 1, Minor: 0, Patch: 0}, Labels: map[string]string{"label1": "value1"}, Binding: model.BindingPinned},
 		},
 	}
 
-	for _, config := range configGroup.Configurations {
-		mockRepo.On("AddGroup", configGroup.Name, model.ToString(configGroup.Version), model.SortLabels(config.Labels), config, mock.Anything).Return(nil)
-	}
+	createdAt := time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC)
+	mockRepo.On("GetGroupDocument", configGroup.Name, model.ToString(configGroup.Version), mock.Anything).Return(&model.GroupDocument{
+		Name:     configGroup.Name,
+		Id:       42,
+		Version:  configGroup.Version,
+		Metadata: model.Metadata{CreatedAt: createdAt, CreatedBy: "alice"},
+	}, nil)
+	mockRepo.On("SaveGroup", mock.MatchedBy(func(doc *model.GroupDocument) bool {
+		return doc.Id == 42 && doc.CreatedBy == "alice" && doc.CreatedAt.Equal(createdAt) && doc.UpdatedAt.After(createdAt)
+	}), configGroup.Configurations, mock.Anything, mock.Anything).Return(nil).Once()
 
 	err := service.Save(configGroup, context.Background())
 	assert.NoError(t, err)
+	assert.Equal(t, int64(42), configGroup.Id)
 
-	for _, config := range configGroup.Configurations {
-		mockRepo.AssertCalled(t, "AddGroup", configGroup.Name, model.ToString(configGroup.Version), model.SortLabels(config.Labels), config, mock.Anything)
-	}
 	mockRepo.AssertExpectations(t)
 }
 
@@ -73,8 +81,10 @@ func TestConfigurationGroupService_Get(t *testing.T) {
 	version := model.Version{Major: 1, Minor: 0, Patch: 0}
 	labels := "label1"
 	configGroup := &model.ConfigurationGroup{
-		Name:    name,
-		Version: version,
+		Name:     name,
+		Id:       7,
+		Version:  version,
+		Metadata: model.Metadata{Description: "payments services", Owner: "payments"},
 	}
 
 	mockRepo.On("GetGroupByParams", name, model.ToString(version), labels, mock.Anything).Return(configGroup, nil)
@@ -82,10 +92,397 @@ func TestConfigurationGroupService_Get(t *testing.T) {
 	retrievedGroup, err := service.Get(name, version, labels, context.Background())
 	assert.NoError(t, err)
 	assert.Equal(t, configGroup, retrievedGroup)
+	assert.Equal(t, int64(7), retrievedGroup.Id)
+	assert.Equal(t, "payments", retrievedGroup.Owner)
 
 	mockRepo.AssertExpectations(t)
 }
 
+func TestConfigurationGroupService_GetEmpty(t *testing.T) {
+	mockRepo := new(repositories.MockConfigRepository)
+	service := services.NewConfigurationGroupService(mockRepo, NewTestTracer())
+
+	version := model.Version{Major: 1}
+	empty := &model.ConfigurationGroup{Name: "empty", Id: 8, Version: version, Configurations: []model.Configuration{}}
+	mockRepo.On("GetGroupByParams", "empty", "1.0.0", "", mock.Anything).Return(empty, nil)
+
+	group, err := service.Get("empty", version, "", context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(8), group.Id)
+	assert.Empty(t, group.Configurations)
+	mockRepo.AssertNotCalled(t, "GetTrashedGroups", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestGroupDocument_Members(t *testing.T) {
+	doc := model.GroupDocument{Name: "backend", Version: model.Version{Major: 1}}
+	db := model.MemberOf(model.Configuration{Name: "db", Labels: model.Labels{"region": "eu", "env": "prod"}})
+	cache := model.MemberOf(model.Configuration{Name: "cache"})
+
+	doc.AddMember(db)
+	doc.AddMember(cache)
+	doc.AddMember(db)
+	assert.Equal(t, []model.GroupMember{cache, db}, doc.Members)
+	assert.True(t, doc.HasMember(db))
+
+	doc.RemoveMember(db)
+	assert.False(t, doc.HasMember(db))
+	assert.Equal(t, []model.GroupMember{cache}, doc.Members)
+}
+
+func TestConfigurationGroupService_GetEffectiveSources(t *testing.T) {
+	v1 := model.Version{Major: 1}
+	v12 := model.Version{Major: 1, Minor: 2}
+	group := &model.ConfigurationGroup{Name: "backend", Version: v1, Configurations: []model.Configuration{
+		{Name: "db", Labels: model.Labels{"env": "prod"}, Source: &model.ConfigReference{Name: "db", Version: "^1"}, Binding: model.BindingFloating},
+		{Name: "cache", Labels: model.Labels{"env": "prod"}, Source: &model.ConfigReference{Name: "redis", Version: "1.0.0"}, Binding: model.BindingPinned},
+		{Name: "web", Version: v1, Parameters: map[string]string{"port": "80"}, Binding: model.BindingPinned},
+	}}
+
+	mockRepo := new(repositories.MockConfigRepository)
+	mockRepo.On("GetGroupByParams", "backend", "1.0.0", "", mock.Anything).Return(group, nil)
+	mockRepo.On("GetVersions", "db", mock.Anything).Return([]model.Configuration{
+		{Name: "db", Version: v1, Parameters: map[string]string{"host": "old"}},
+		{Name: "db", Version: v12, Parameters: map[string]string{"host": "fixed"}},
+		{Name: "db", Version: model.Version{Major: 2}, Parameters: map[string]string{"host": "next"}},
+	}, nil)
+	mockRepo.On("GetById", "redis", "1.0.0", mock.Anything).Return(&model.Configuration{
+		Name: "redis", Version: v1, Parameters: map[string]string{"ttl": "60"}, Labels: model.Labels{"team": "cache"},
+	}, nil)
+	service := services.NewConfigurationGroupService(mockRepo, NewTestTracer())
+
+	effective, err := service.GetEffective("backend", v1, "", context.Background())
+	assert.NoError(t, err)
+	db, cache, web := effective.Configurations[0], effective.Configurations[1], effective.Configurations[2]
+
+	assert.Equal(t, "fixed", db.Parameters["host"])
+	assert.Equal(t, v12, *db.Source.Resolved)
+	assert.Equal(t, model.BindingFloating, db.Binding)
+
+	assert.Equal(t, "cache", cache.Name)
+	assert.Equal(t, model.Labels{"env": "prod"}, cache.Labels)
+	assert.Equal(t, "60", cache.Parameters["ttl"])
+	assert.Equal(t, model.BindingPinned, cache.Binding)
+
+	assert.Equal(t, "80", web.Parameters["port"])
+	assert.Nil(t, web.Source)
+}
+
+func TestConfigurationGroupService_GetEffectiveSameNamedMembers(t *testing.T) {
+	v1 := model.Version{Major: 1}
+	group := &model.ConfigurationGroup{Name: "backend", Version: v1, Configurations: []model.Configuration{
+		{Name: "db", Labels: model.Labels{"env": "dev"}, Parameters: map[string]string{"host": "dev.db", "url": "pg://${self:host}"}},
+		{Name: "db", Labels: model.Labels{"env": "prod"}, Parameters: map[string]string{"host": "prod.db", "url": "pg://${self:host}"}},
+	}}
+
+	mockRepo := new(repositories.MockConfigRepository)
+	mockRepo.On("GetGroupByParams", "backend", "1.0.0", "", mock.Anything).Return(group, nil)
+	mockRepo.On("GetSchemas", mock.Anything).Return([]model.Schema{}, nil)
+	service := services.NewConfigurationGroupService(mockRepo, NewTestTracer())
+
+	effective, err := service.GetEffective("backend", v1, "", context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "pg://dev.db", effective.Configurations[0].Parameters["url"])
+	assert.Equal(t, "pg://prod.db", effective.Configurations[1].Parameters["url"])
+}
+
+func TestConfigurationGroupService_AddSourceWithParameters(t *testing.T) {
+	mockRepo := new(repositories.MockConfigRepository)
+	service := services.NewConfigurationGroupService(mockRepo, NewTestTracer())
+
+	group := model.ConfigurationGroup{Name: "backend", Version: model.Version{Major: 1}, Configurations: []model.Configuration{
+		{Source: &model.ConfigReference{Name: "db", Version: "^1"}, Parameters: map[string]string{"host": "x"}},
+	}}
+	err := service.Add(group, context.Background())
+	assert.ErrorIs(t, err, model.ErrInvalid)
+	assert.Equal(t, "db", group.Configurations[0].Name)
+	mockRepo.AssertNotCalled(t, "SaveGroup", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestConfigurationGroupService_ReplaceMember(t *testing.T) {
+	v1 := model.Version{Major: 1}
+	prod := model.Configuration{Name: "db", Version: v1, Parameters: map[string]string{"host": "a"}, Labels: model.Labels{"env": "prod"}}
+	dev := model.Configuration{Name: "db", Version: v1, Parameters: map[string]string{"host": "b"}, Labels: model.Labels{"env": "dev"}}
+	group := &model.ConfigurationGroup{Name: "backend", Version: v1, Configurations: []model.Configuration{prod, dev}}
+
+	mockRepo := new(repositories.MockConfigRepository)
+	mockRepo.On("GetGroupByParams", "backend", "1.0.0", "", mock.Anything).Return(group, nil)
+	mockRepo.On("GetSchemas", mock.Anything).Return([]model.Schema{}, nil)
+	mockRepo.On("ReplaceGroupMember", "backend", "1.0.0", prod, mock.MatchedBy(func(c model.Configuration) bool {
+		return c.Name == "db" && c.Parameters["host"] == "c" && c.Labels["env"] == "staging" && c.Binding == model.BindingPinned
+	}), mock.Anything, mock.Anything).Return(nil)
+	service := services.NewConfigurationGroupService(mockRepo, NewTestTracer())
+
+	replacement := model.Configuration{Version: v1, Parameters: map[string]string{"host": "c"}, Labels: model.Labels{"env": "staging"}}
+	member, err := service.ReplaceMember("backend", v1, "db", "env:prod", replacement, context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "db", member.Name)
+
+	_, err = service.ReplaceMember("backend", v1, "db", "", replacement, context.Background())
+	assert.ErrorIs(t, err, model.ErrInvalid)
+
+	replacement.Name = "cache"
+	_, err = service.ReplaceMember("backend", v1, "db", "env:dev", replacement, context.Background())
+	assert.ErrorIs(t, err, model.ErrInvalid)
+
+	mockRepo.AssertNumberOfCalls(t, "ReplaceGroupMember", 1)
+}
+
+func TestConfigurationGroupService_RemoveMember(t *testing.T) {
+	v1 := model.Version{Major: 1}
+	db := model.Configuration{Name: "db", Version: v1, Labels: model.Labels{"env": "prod"}}
+	group := &model.ConfigurationGroup{Name: "backend", Version: v1, Configurations: []model.Configuration{db}}
+
+	mockRepo := new(repositories.MockConfigRepository)
+	mockRepo.On("GetGroupByParams", "backend", "1.0.0", "", mock.Anything).Return(group, nil)
+	mockRepo.On("TrashGroup", mock.MatchedBy(func(trashed *model.TrashedGroup) bool {
+		// Only the member goes to the trash, an empty label filter would trash the whole group.
+		return trashed.Labels == "" && trashed.Member != nil && *trashed.Member == model.MemberOf(db) &&
+			len(trashed.Configurations) == 1 && trashed.Configurations[0].Name == "db" && trashed.DeletedBy == "anonymous"
+	}), mock.Anything, mock.Anything).Return(nil)
+	service := services.NewConfigurationGroupService(mockRepo, NewTestTracer())
+
+	assert.NoError(t, service.RemoveMember("backend", v1, "db", "", context.Background()))
+	assert.ErrorIs(t, service.RemoveMember("backend", v1, "db", "env:dev", context.Background()), model.ErrNotFound)
+	assert.ErrorIs(t, service.RemoveMember("backend", v1, "cache", "", context.Background()), model.ErrNotFound)
+	mockRepo.AssertNumberOfCalls(t, "TrashGroup", 1)
+}
+
+func TestConfigurationGroupService_LabelSubset(t *testing.T) {
+	v1 := model.Version{Major: 1}
+	group := &model.ConfigurationGroup{Name: "backend", Version: v1, Configurations: []model.Configuration{
+		{Name: "db", Labels: model.Labels{"env": "prod", "region": "eu"}},
+		{Name: "cache", Labels: model.Labels{"env": "prod"}},
+		{Name: "web", Labels: model.Labels{"env": "dev", "region": "eu"}},
+	}}
+
+	mockRepo := new(repositories.MockConfigRepository)
+	mockRepo.On("GetGroupRevision", "backend", "1.0.0", int64(1), mock.Anything).Return(&model.GroupRevision{Number: 1, Group: group}, nil)
+	mockRepo.On("GetGroupByParams", "backend", "1.0.0", "region:us", mock.Anything).Return((*model.ConfigurationGroup)(nil), nil)
+	mockRepo.On("GetTrashedGroups", "backend", "1.0.0", mock.Anything).Return([]model.TrashedGroup{{Name: "backend", Version: v1, Configurations: group.Configurations}}, nil)
+	service := services.NewConfigurationGroupService(mockRepo, NewTestTracer())
+
+	rev, err := service.GetRevision("backend", v1, "env:prod", 1, context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, rev.Configurations, 2)
+
+	rev, err = service.GetRevision("backend", v1, "region:eu;env:prod", 1, context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "db", rev.Configurations[0].Name)
+
+	gone, err := service.Get("backend", v1, "region:us", context.Background())
+	assert.NoError(t, err)
+	assert.Nil(t, gone)
+}
+
+func TestConfigurationGroupService_GetEffectiveIncludes(t *testing.T) {
+	v1 := model.Version{Major: 1}
+	base := &model.ConfigurationGroup{Name: "platform-base", Version: v1, Configurations: []model.Configuration{
+		{Name: "log", Parameters: map[string]string{"level": "info"}},
+		{Name: "db", Parameters: map[string]string{"host": "base"}},
+	}}
+	shared := &model.ConfigurationGroup{Name: "shared", Version: v1, Configurations: []model.Configuration{
+		{Name: "log", Parameters: map[string]string{"level": "debug"}},
+	}}
+	service := &model.ConfigurationGroup{Name: "orders", Version: v1,
+		Configurations: []model.Configuration{{Name: "db", Parameters: map[string]string{"host": "orders"}}},
+		Includes:       []model.GroupInclude{{Name: "platform-base", Version: v1}, {Name: "shared", Version: v1}},
+	}
+
+	mockRepo := new(repositories.MockConfigRepository)
+	mockRepo.On("GetGroupByParams", "orders", "1.0.0", "", mock.Anything).Return(service, nil)
+	mockRepo.On("GetGroupByParams", "platform-base", "1.0.0", "", mock.Anything).Return(base, nil)
+	mockRepo.On("GetGroupByParams", "shared", "1.0.0", "", mock.Anything).Return(shared, nil)
+	groupService := services.NewConfigurationGroupService(mockRepo, NewTestTracer())
+
+	effective, err := groupService.GetEffective("orders", v1, "", context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, effective.Configurations, 2)
+	assert.Equal(t, "log", effective.Configurations[0].Name)
+	assert.Equal(t, "debug", effective.Configurations[0].Parameters["level"])
+	assert.Equal(t, "orders", effective.Configurations[1].Parameters["host"])
+
+	tree, err := groupService.GetTree("orders", v1, context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, tree.Includes, 2)
+	assert.Equal(t, "platform-base", tree.Includes[0].Name)
+	assert.Len(t, tree.Configurations, 1)
+}
+
+func TestConfigurationGroupService_GetEffectiveMissingInclude(t *testing.T) {
+	v1 := model.Version{Major: 1}
+	service := &model.ConfigurationGroup{Name: "orders", Version: v1, Includes: []model.GroupInclude{{Name: "platform-base", Version: v1}}}
+
+	mockRepo := new(repositories.MockConfigRepository)
+	mockRepo.On("GetGroupByParams", "orders", "1.0.0", "", mock.Anything).Return(service, nil)
+	mockRepo.On("GetGroupByParams", "platform-base", "1.0.0", "", mock.Anything).Return((*model.ConfigurationGroup)(nil), nil)
+	groupService := services.NewConfigurationGroupService(mockRepo, NewTestTracer())
+
+	_, err := groupService.GetEffective("orders", v1, "", context.Background())
+	assert.ErrorIs(t, err, model.ErrDangling)
+	assert.NotErrorIs(t, err, model.ErrInvalid)
+	assert.Contains(t, err.Error(), "platform-base@1.0.0 included by orders@1.0.0")
+}
+
+func TestConfigurationGroupService_GetTreeMissing(t *testing.T) {
+	mockRepo := new(repositories.MockConfigRepository)
+	mockRepo.On("GetGroupByParams", "orders", "1.0.0", "", mock.Anything).Return((*model.ConfigurationGroup)(nil), nil)
+	mockRepo.On("GetTrashedGroups", "orders", "1.0.0", mock.Anything).Return([]model.TrashedGroup{}, nil)
+	service := services.NewConfigurationGroupService(mockRepo, NewTestTracer())
+
+	tree, err := service.GetTree("orders", model.Version{Major: 1}, context.Background())
+	assert.Nil(t, tree)
+	assert.ErrorIs(t, err, model.ErrNotFound)
+}
+
+func TestConfigurationGroupService_DeleteIncluded(t *testing.T) {
+	v1 := model.Version{Major: 1}
+	base := &model.ConfigurationGroup{Name: "platform-base", Version: v1, Configurations: []model.Configuration{{Name: "log"}}}
+	orders := model.ConfigurationGroup{Name: "orders", Version: v1, Includes: []model.GroupInclude{{Name: "platform-base", Version: v1}}}
+
+	mockRepo := new(repositories.MockConfigRepository)
+	mockRepo.On("GetGroupByParams", "platform-base", "1.0.0", mock.Anything, mock.Anything).Return(base, nil)
+	mockRepo.On("FindIncluders", "platform-base", "1.0.0", mock.Anything).Return([]model.ConfigurationGroup{orders}, nil)
+	mockRepo.On("TrashGroup", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	service := services.NewConfigurationGroupService(mockRepo, NewTestTracer())
+
+	err := service.Delete("platform-base", "1.0.0", "", context.Background())
+	assert.ErrorIs(t, err, model.ErrInUse)
+	assert.Contains(t, err.Error(), "orders@1.0.0")
+	mockRepo.AssertNotCalled(t, "TrashGroup", mock.Anything, mock.Anything, mock.Anything)
+
+	// Deleting members by label leaves the included group in place.
+	assert.NoError(t, service.Delete("platform-base", "1.0.0", "env:prod", context.Background()))
+	mockRepo.AssertNumberOfCalls(t, "TrashGroup", 1)
+}
+
+func TestConfigurationGroupService_AddIncludeCycle(t *testing.T) {
+	v1 := model.Version{Major: 1}
+	b := &model.ConfigurationGroup{Name: "b", Version: v1, Configurations: []model.Configuration{},
+		Includes: []model.GroupInclude{{Name: "a", Version: v1}}}
+
+	mockRepo := new(repositories.MockConfigRepository)
+	mockRepo.On("GetSchemas", mock.Anything).Return([]model.Schema{}, nil)
+	mockRepo.On("GetGroupByParams", "b", "1.0.0", "", mock.Anything).Return(b, nil)
+	mockRepo.On("GetGroupByParams", "missing", "1.0.0", "", mock.Anything).Return((*model.ConfigurationGroup)(nil), nil)
+	service := services.NewConfigurationGroupService(mockRepo, NewTestTracer())
+
+	a := model.ConfigurationGroup{Name: "a", Version: v1, Includes: []model.GroupInclude{{Name: "b", Version: v1}}}
+	err := service.Add(a, context.Background())
+	assert.ErrorIs(t, err, model.ErrInvalid)
+	assert.Contains(t, err.Error(), "a@1.0.0 -> b@1.0.0 -> a@1.0.0")
+
+	a.Includes = []model.GroupInclude{{Name: "missing", Version: v1}}
+	assert.ErrorIs(t, service.Add(a, context.Background()), model.ErrInvalid)
+	mockRepo.AssertNotCalled(t, "SaveGroup", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestConfigurationGroupService_Resolve(t *testing.T) {
+	v1 := model.Version{Major: 1}
+	group := &model.ConfigurationGroup{Name: "orders", Version: v1, Configurations: []model.Configuration{
+		{Name: "app", Version: v1, Labels: model.Labels{"env": "prod", "region": "eu"}, Parameters: map[string]string{"replicas": "6"}},
+		{Name: "app", Version: v1, Labels: model.Labels{"env": "prod"}, Parameters: map[string]string{"replicas": "3", "log": "warn"}},
+		{Name: "app", Version: v1, Parameters: map[string]string{"replicas": "1", "log": "debug", "port": "8080"}},
+		{Name: "app", Version: v1, Labels: model.Labels{"env": "dev"}, Parameters: map[string]string{"port": "9090"}},
+		{Name: "app", Version: v1, Labels: model.Labels{"cluster": "c1"}, Parameters: map[string]string{"replicas": "2"}},
+	}}
+
+	mockRepo := new(repositories.MockConfigRepository)
+	mockRepo.On("GetGroupByParams", "orders", "1.0.0", "", mock.Anything).Return(group, nil)
+	service := services.NewConfigurationGroupService(mockRepo, NewTestTracer())
+
+	clientContext := model.Labels{"env": "prod", "region": "eu", "cluster": "c1"}
+	resolved, err := service.Resolve("orders", v1, model.ResolveRequest{Context: clientContext}, context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, resolved.Layers, 4)
+	assert.Equal(t, "6", resolved.Parameters["replicas"].Value)
+	assert.Equal(t, model.Labels{"env": "prod", "region": "eu"}, resolved.Parameters["replicas"].Source.Labels)
+	assert.Equal(t, "warn", resolved.Parameters["log"].Value)
+	assert.Equal(t, "8080", resolved.Parameters["port"].Value)
+	assert.Empty(t, resolved.Parameters["port"].Source.Labels)
+
+	// cluster outranks env and region when it is listed first
+	resolved, err = service.Resolve("orders", v1, model.ResolveRequest{Context: clientContext, Order: model.ResolveByPrecedence}, context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "2", resolved.Parameters["replicas"].Value)
+
+	_, err = service.Resolve("orders", v1, model.ResolveRequest{Context: clientContext, Precedence: []string{"zone"}}, context.Background())
+	assert.ErrorIs(t, err, model.ErrInvalid)
+}
+
+func TestConfigurationGroupService_ResolveCollisions(t *testing.T) {
+	v1 := model.Version{Major: 1}
+	group := &model.ConfigurationGroup{Name: "orders", Version: v1, Configurations: []model.Configuration{
+		{Name: "app", Version: v1, Parameters: map[string]string{"port": "8080", "log": "info"}},
+		{Name: "app", Version: v1, Labels: model.Labels{"env": "prod"}, Parameters: map[string]string{"log": "warn"}},
+		{Name: "sidecar", Version: v1, Labels: model.Labels{"env": "prod"}, Parameters: map[string]string{"port": "15001"}},
+		{Name: "sidecar", Version: v1, Labels: model.Labels{"team": "payments"}, Parameters: map[string]string{"mode": "strict"}},
+	}}
+
+	mockRepo := new(repositories.MockConfigRepository)
+	mockRepo.On("GetGroupByParams", "orders", "1.0.0", "", mock.Anything).Return(group, nil)
+	service := services.NewConfigurationGroupService(mockRepo, NewTestTracer())
+
+	clientContext := model.Labels{"env": "prod"}
+	resolved, err := service.Resolve("orders", v1, model.ResolveRequest{Context: clientContext}, context.Background())
+	assert.NoError(t, err)
+	// team is not a context key, the member labelled with it never applies
+	assert.Len(t, resolved.Layers, 3)
+	assert.NotContains(t, resolved.Parameters, "mode")
+	// log is only overridden within app, port is set by both members
+	assert.Len(t, resolved.Collisions, 1)
+	assert.Equal(t, "port", resolved.Collisions[0].Parameter)
+	assert.Equal(t, []string{"app", "sidecar"}, []string{resolved.Collisions[0].Sources[0].Member, resolved.Collisions[0].Sources[1].Member})
+	assert.Equal(t, "15001", resolved.Parameters["port"].Value)
+
+	resolved, err = service.Resolve("orders", v1, model.ResolveRequest{Context: clientContext, Member: "app"}, context.Background())
+	assert.NoError(t, err)
+	assert.Empty(t, resolved.Collisions)
+	assert.Equal(t, "8080", resolved.Parameters["port"].Value)
+}
+
+func TestConfigurationGroupService_Export(t *testing.T) {
+	v1 := model.Version{Major: 1}
+	group := &model.ConfigurationGroup{Name: "Orders", Version: v1, Configurations: []model.Configuration{
+		{Name: "app", Version: v1, Parameters: map[string]string{"db.host": "db.local", "db.port": "5432", "motd": "hi $USER", "tags": `["a",1]`},
+			Types: map[string]model.ParameterType{"db.port": model.ParamInt, "tags": model.ParamList}},
+		{Name: "app", Version: v1, Labels: model.Labels{"env": "prod"}, Parameters: map[string]string{"db.host": "db.prod"}},
+	}}
+
+	mockRepo := new(repositories.MockConfigRepository)
+	mockRepo.On("GetGroupByParams", "orders", "1.0.0", "", mock.Anything).Return(group, nil)
+	service := services.NewConfigurationGroupService(mockRepo, NewTestTracer())
+	req := model.ResolveRequest{Context: model.Labels{"env": "prod"}}
+
+	file, err := service.Export("orders", v1, req, model.ExportRequest{Format: model.ExportDotenv}, context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "DB_HOST=db.prod\nDB_PORT=5432\nMOTD=\"hi \\$USER\"\nTAGS=\"[\\\"a\\\",1]\"\n", string(file.Body))
+	assert.Equal(t, "orders.env", file.FileName)
+
+	file, err = service.Export("orders", v1, req, model.ExportRequest{Format: model.ExportTOML, Keys: model.KeysAsIs}, context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "motd = \"hi $USER\"\ntags = [\"a\", 1]\n\n[db]\nhost = \"db.prod\"\nport = 5432\n", string(file.Body))
+
+	file, err = service.Export("orders", v1, req, model.ExportRequest{Format: model.ExportYAML}, context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "db:\n  host: db.prod\n  port: 5432\nmotd: hi $USER\ntags:\n  - a\n  - 1\n", string(file.Body))
+
+	file, err = service.Export("orders", v1, req, model.ExportRequest{Format: model.ExportSecret, Namespace: "shop"}, context.Background())
+	assert.NoError(t, err)
+	assert.Contains(t, string(file.Body), "kind: Secret\nmetadata:\n  name: orders\n  namespace: shop\ntype: Opaque\n")
+	assert.Contains(t, string(file.Body), "db.host: ZGIucHJvZA==\n")
+
+	// dotted keys are not environment variable names, and two keys may not rename to the same one
+	_, err = service.Export("orders", v1, req, model.ExportRequest{Format: model.ExportDotenv, Keys: model.KeysAsIs}, context.Background())
+	assert.ErrorIs(t, err, model.ErrInvalid)
+	group.Configurations[1].Parameters["db_host"] = "x"
+	_, err = service.Export("orders", v1, req, model.ExportRequest{Format: model.ExportProperties, Keys: model.KeysLowerSnake}, context.Background())
+	assert.ErrorIs(t, err, model.ErrInvalid)
+	assert.Contains(t, err.Error(), "db.host and db_host both export as db_host")
+
+	_, err = service.Export("orders", v1, req, model.ExportRequest{Format: "ini"}, context.Background())
+	assert.ErrorIs(t, err, model.ErrInvalid)
+}
+
 func TestConfigurationGroupService_Delete(t *testing.T) {
 	mockRepo := new(repositories.MockConfigRepository)
 	service := services.NewConfigurationGroupService(mockRepo, NewTestTracer())
@@ -94,10 +491,283 @@ func TestConfigurationGroupService_Delete(t *testing.T) {
 	version := "1.0.0"
 	labels := "label1"
 
-	mockRepo.On("DeleteGroupByParams", name, version, labels, mock.Anything).Return(nil)
+	group := &model.ConfigurationGroup{
+		Name:           name,
+		Version:        model.Version{Major: 1, Minor: 0, Patch: 0},
+		Configurations: []model.Configuration{{Name: "config1", Labels: map[string]string{"label1": ""}}},
+	}
+
+	mockRepo.On("GetGroupByParams", name, version, labels, mock.Anything).Return(group, nil)
+	mockRepo.On("TrashGroup", mock.MatchedBy(func(trashed *model.TrashedGroup) bool {
+		return trashed.Labels == labels && len(trashed.Configurations) == 1
+	}), mock.MatchedBy(func(rev *model.GroupRevision) bool {
+		return rev.Author == "anonymous" && !rev.Timestamp.IsZero()
+	}), mock.Anything).Return(nil)
 
 	err := service.Delete(name, version, labels, context.Background())
 	assert.NoError(t, err)
 
 	mockRepo.AssertExpectations(t)
 }
+
+func TestConfigurationGroupService_Clone(t *testing.T) {
+	mockRepo := new(repositories.MockConfigRepository)
+	mockRepo.On("GetSchemas", mock.Anything).Return([]model.Schema{}, nil)
+	mockRepo.On("GetGroupDocument", mock.Anything, mock.Anything, mock.Anything).Return((*model.GroupDocument)(nil), model.ErrNotFound)
+	mockRepo.On("SaveGroup", mock.Anything, mock.MatchedBy(func(configs []model.Configuration) bool {
+		return len(configs) == 2 && configs[0].Labels["env"] == "prod" && configs[1].Labels["env"] == "prod"
+	}), mock.Anything, mock.Anything).Return(nil)
+	service := services.NewConfigurationGroupService(mockRepo, NewTestTracer())
+
+	version := model.Version{Major: 1, Minor: 0, Patch: 0}
+	source := &model.ConfigurationGroup{
+		Name:    "testGroup",
+		Version: version,
+		Configurations: []model.Configuration{
+			{Name: "config1", Version: version, Parameters: map[string]string{"a": "1"}, Labels: map[string]string{"env": "dev"}},
+			{Name: "config2", Version: version, Parameters: map[string]string{"b": "2"}},
+		},
+	}
+	req := model.CloneRequest{
+		Version: model.Version{Major: 2, Minor: 0, Patch: 0},
+		Patch:   &model.Patch{Labels: map[string]string{"env": "prod"}},
+		Members: map[string]model.Patch{"config2": {Parameters: map[string]string{"b": "3"}}},
+	}
+
+	mockRepo.On("GetGroupByParams", source.Name, "1.0.0", "", mock.Anything).Return(source, nil)
+	mockRepo.On("GetGroupByParams", source.Name, "2.0.0", "", mock.Anything).Return((*model.ConfigurationGroup)(nil), nil).Once()
+
+	clone, err := service.Clone(source.Name, version, req, context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, req.Version, clone.Version)
+	assert.Len(t, clone.Configurations, 2)
+	assert.Equal(t, "3", clone.Configurations[1].Parameters["b"])
+	assert.Equal(t, "dev", source.Configurations[0].Labels["env"])
+
+	mockRepo.AssertNumberOfCalls(t, "SaveGroup", 1)
+}
+
+func TestConfigurationGroupService_Diff(t *testing.T) {
+	mockRepo := new(repositories.MockConfigRepository)
+	service := services.NewConfigurationGroupService(mockRepo, NewTestTracer())
+
+	from := model.Version{Major: 1, Minor: 0, Patch: 0}
+	to := model.Version{Major: 1, Minor: 1, Patch: 0}
+	oldGroup := &model.ConfigurationGroup{
+		Name:    "testGroup",
+		Version: from,
+		Configurations: []model.Configuration{
+			{Name: "db", Version: from, Parameters: map[string]string{"port": "5432"}, Labels: map[string]string{"env": "dev"}},
+			{Name: "cache", Version: from, Parameters: map[string]string{"ttl": "60"}},
+		},
+	}
+	newGroup := &model.ConfigurationGroup{
+		Name:    "testGroup",
+		Version: to,
+		Configurations: []model.Configuration{
+			{Name: "db", Version: from, Parameters: map[string]string{"port": "5433"}, Labels: map[string]string{"env": "prod"}},
+			{Name: "queue", Version: from, Parameters: map[string]string{"size": "10"}},
+		},
+	}
+
+	mockRepo.On("GetGroupByParams", "testGroup", "1.0.0", "", mock.Anything).Return(oldGroup, nil)
+	mockRepo.On("GetGroupByParams", "testGroup", "1.1.0", "", mock.Anything).Return(newGroup, nil)
+
+	diff, err := service.Diff("testGroup", from, to, context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, diff.Added, 1)
+	assert.Equal(t, "queue", diff.Added[0].Name)
+	assert.Len(t, diff.Removed, 1)
+	assert.Equal(t, "cache", diff.Removed[0].Name)
+	assert.Len(t, diff.Modified, 1)
+	assert.Equal(t, model.ValueChange{From: "5432", To: "5433"}, diff.Modified[0].Parameters.Changed["port"])
+	assert.Equal(t, model.ValueChange{From: "dev", To: "prod"}, diff.Modified[0].Labels.Changed["env"])
+	assert.Contains(t, diff.Unified(), "-port=5432\n+port=5433\n")
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestConfigurationGroupService_Rollback(t *testing.T) {
+	mockRepo := new(repositories.MockConfigRepository)
+	service := services.NewConfigurationGroupService(mockRepo, NewTestTracer())
+
+	version := model.Version{Major: 1, Minor: 0, Patch: 0}
+	good := &model.ConfigurationGroup{
+		Name:           "testGroup",
+		Version:        version,
+		Configurations: []model.Configuration{{Name: "config1", Parameters: map[string]string{"a": "1"}}},
+		Includes:       []model.GroupInclude{{Name: "platform-base", Version: version}},
+	}
+	bad := &model.ConfigurationGroup{
+		Name:    "testGroup",
+		Version: version,
+		Configurations: []model.Configuration{
+			{Name: "config1", Parameters: map[string]string{"a": "1"}},
+			{Name: "broken", Parameters: map[string]string{"b": "2"}},
+		},
+	}
+
+	mockRepo.On("GetGroupRevision", "testGroup", "1.0.0", int64(3), mock.Anything).Return(&model.GroupRevision{Number: 3, Group: good}, nil)
+	mockRepo.On("GetGroupByParams", "testGroup", "1.0.0", "", mock.Anything).Return(bad, nil).Once()
+
+	diff, err := service.PreviewRollback("testGroup", version, 3, context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, diff.Removed, 1)
+	assert.Equal(t, "broken", diff.Removed[0].Name)
+	mockRepo.AssertNotCalled(t, "ReplaceGroup", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+
+	mockRepo.On("GetGroupByParams", "testGroup", "1.0.0", "", mock.Anything).Return(bad, nil).Once()
+	mockRepo.On("GetGroupByParams", "platform-base", "1.0.0", "", mock.Anything).Return(&model.ConfigurationGroup{Name: "platform-base", Version: version}, nil)
+	// The includes are restored in the same update as the members.
+	mockRepo.On("ReplaceGroup", "testGroup", "1.0.0", good.Configurations, good.Includes, mock.MatchedBy(func(rev *model.GroupRevision) bool {
+		return rev.RestoredFrom == 3
+	}), mock.Anything).Return(nil)
+
+	restored, err := service.Rollback("testGroup", version, 3, context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, good, restored)
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestConfigurationGroupService_RollbackMissingInclude(t *testing.T) {
+	mockRepo := new(repositories.MockConfigRepository)
+	service := services.NewConfigurationGroupService(mockRepo, NewTestTracer())
+
+	version := model.Version{Major: 1, Minor: 0, Patch: 0}
+	target := &model.ConfigurationGroup{Name: "testGroup", Version: version, Includes: []model.GroupInclude{{Name: "gone", Version: version}}}
+	mockRepo.On("GetGroupRevision", "testGroup", "1.0.0", int64(2), mock.Anything).Return(&model.GroupRevision{Number: 2, Group: target}, nil)
+	mockRepo.On("GetGroupByParams", "testGroup", "1.0.0", "", mock.Anything).Return(&model.ConfigurationGroup{Name: "testGroup", Version: version}, nil)
+	mockRepo.On("GetGroupByParams", "gone", "1.0.0", "", mock.Anything).Return((*model.ConfigurationGroup)(nil), nil)
+
+	_, err := service.Rollback("testGroup", version, 2, context.Background())
+	assert.ErrorIs(t, err, model.ErrInvalid)
+	assert.Contains(t, err.Error(), "gone@1.0.0")
+	mockRepo.AssertNotCalled(t, "ReplaceGroup", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestConfigurationGroupService_RollbackToDeletion(t *testing.T) {
+	mockRepo := new(repositories.MockConfigRepository)
+	service := services.NewConfigurationGroupService(mockRepo, NewTestTracer())
+
+	version := model.Version{Major: 1, Minor: 0, Patch: 0}
+	mockRepo.On("GetGroupRevision", "testGroup", "1.0.0", int64(2), mock.Anything).Return(&model.GroupRevision{Number: 2, Deleted: true}, nil)
+
+	_, err := service.Rollback("testGroup", version, 2, context.Background())
+	assert.ErrorIs(t, err, model.ErrInvalid)
+	mockRepo.AssertNotCalled(t, "ReplaceGroup", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestConfigurationGroupService_RollbackLargeGroup(t *testing.T) {
+	mockRepo := new(repositories.MockConfigRepository)
+	service := services.NewConfigurationGroupService(mockRepo, NewTestTracer())
+
+	version := model.Version{Major: 1, Minor: 0, Patch: 0}
+	large := &model.ConfigurationGroup{Name: "testGroup", Version: version}
+	for i := 0; i < 70; i++ {
+		large.Configurations = append(large.Configurations, model.Configuration{Name: fmt.Sprintf("config%d", i), Version: version})
+	}
+	mockRepo.On("GetGroupRevision", "testGroup", "1.0.0", int64(4), mock.Anything).Return(&model.GroupRevision{Number: 4, Group: large}, nil)
+	mockRepo.On("GetGroupByParams", "testGroup", "1.0.0", "", mock.Anything).Return(large, nil)
+	mockRepo.On("ReplaceGroup", "testGroup", "1.0.0", large.Configurations, large.Includes, mock.Anything, mock.Anything).Return(nil)
+
+	group, err := service.Rollback("testGroup", version, 4, context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, group.Configurations, 70)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestLabels_Encode(t *testing.T) {
+	labels := map[string]string{"region": "eu", "env": "prod", "app.kubernetes.io/name": "web", "canary": ""}
+
+	for i := 0; i < 20; i++ {
+		assert.Equal(t, "app.kubernetes.io%2Fname:web;canary:;env:prod;region:eu", model.SortLabels(labels))
+	}
+	assert.Equal(t, labels, model.ParseLabels(model.SortLabels(labels)))
+	assert.Equal(t, labels, model.ParseLabels("region:eu;env:prod;canary:;app.kubernetes.io/name:web"))
+}
+
+func TestConfigurationGroupService_AddInvalidLabels(t *testing.T) {
+	tests := map[string]model.Labels{
+		"separator in value": {"env": "prod;region:eu"},
+		"slash in name":      {"team/a/b": "x"},
+		"uppercase prefix":   {"Example.com/tier": "x"},
+		"long value":         {"env": strings.Repeat("a", 64)},
+		"bad start":          {"-env": "prod"},
+	}
+
+	for name, labels := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockRepo := new(repositories.MockConfigRepository)
+			service := services.NewConfigurationGroupService(mockRepo, NewTestTracer())
+
+			group := model.ConfigurationGroup{
+				Name:           "testGroup",
+				Version:        model.Version{Major: 1, Minor: 0, Patch: 0},
+				Configurations: []model.Configuration{{Name: "config1", Labels: labels}},
+			}
+			err := service.Add(group, context.Background())
+			assert.ErrorIs(t, err, model.ErrInvalid)
+			mockRepo.AssertNotCalled(t, "SaveGroup", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestConfigurationGroupService_List(t *testing.T) {
+	v1 := model.Version{Major: 1}
+	refs := []model.ObjectRef{
+		{Kind: model.KindGroup, Name: "backend", Version: v1},
+		{Kind: model.KindGroup, Name: "empty", Version: v1},
+	}
+	backend := &model.ConfigurationGroup{Name: "backend", Id: 7, Version: v1, Configurations: []model.Configuration{
+		{Name: "db", Version: v1, Labels: model.Labels{"env": "prod"}},
+		{Name: "cache", Version: v1, Labels: model.Labels{"env": "dev"}},
+	}}
+
+	mockRepo := new(repositories.MockConfigRepository)
+	mockRepo.On("ListGroupRefs", "", mock.Anything).Return(refs, nil)
+	mockRepo.On("GetGroupByParams", "backend", "1.0.0", "", mock.Anything).Return(backend, nil)
+	mockRepo.On("GetGroupByParams", "empty", "1.0.0", "", mock.Anything).Return(&model.ConfigurationGroup{
+		Name: "empty", Id: 8, Version: v1, Configurations: []model.Configuration{},
+	}, nil)
+	mockRepo.On("FindByLabel", model.KindGroup, "env", []string{"prod"}, mock.Anything).Return(refs[:1], nil)
+
+	service := services.NewConfigurationGroupService(mockRepo, NewTestTracer())
+
+	page, err := service.List(model.ListOptions{Sort: model.SortByName, Order: model.OrderAsc, Limit: 10}, context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, page.Items, 2)
+	assert.Equal(t, int64(7), page.Items[0].Id)
+	assert.Equal(t, "empty", page.Items[1].Name)
+	assert.Empty(t, page.Items[1].Configurations)
+
+	selector, err := model.ParseSelector("env=prod")
+	assert.NoError(t, err)
+	page, err = service.List(model.ListOptions{Selector: selector, Sort: model.SortByName, Order: model.OrderAsc, Limit: 10}, context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, page.Items, 1)
+	assert.Len(t, page.Items[0].Configurations, 1)
+	assert.Equal(t, "db", page.Items[0].Configurations[0].Name)
+}
+
+func TestConfigurationGroup_Fingerprint(t *testing.T) {
+	v1 := model.Version{Major: 1}
+	db := model.Configuration{Name: "db", Version: v1, Parameters: map[string]string{"port": "5432"}, Labels: model.Labels{"env": "prod"}}
+	cache := model.Configuration{Name: "cache", Version: v1, Parameters: map[string]string{"ttl": "60"}}
+
+	group := model.ConfigurationGroup{Name: "backend", Version: v1, Configurations: []model.Configuration{db, cache}}
+	reordered := model.ConfigurationGroup{Name: "backend", Version: v1, Configurations: []model.Configuration{cache, db}}
+	reordered.Description = "metadata is not content"
+	assert.Equal(t, group.ComputeFingerprint(), reordered.ComputeFingerprint())
+	assert.True(t, strings.HasPrefix(group.ComputeFingerprint(), "sha256:"))
+
+	changed := db
+	changed.Parameters = map[string]string{"port": "5433"}
+	edited := model.ConfigurationGroup{Name: "backend", Version: v1, Configurations: []model.Configuration{changed, cache}}
+	assert.NotEqual(t, group.ComputeFingerprint(), edited.ComputeFingerprint())
+	assert.NotEqual(t, db.ComputeFingerprint(), changed.ComputeFingerprint())
+
+	relabeled := db
+	relabeled.Labels = model.Labels{"env": "dev"}
+	assert.NotEqual(t, db.ComputeFingerprint(), relabeled.ComputeFingerprint())
+}
@@ -5,9 +5,11 @@ import (
 	"ars_projekat/repositories"
 	"ars_projekat/services"
 	"context"
+	"encoding/json"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	"go.opentelemetry.io/otel/trace"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -22,17 +24,114 @@ func TestConfigurationService_Add(t *testing.T) {
 	config := &model.Configuration{Name: "testConfig", Version: model.Version{Major: 1, Minor: 0, Patch: 0}}
 
 	mockRepo := new(repositories.MockConfigRepository)
-	mockRepo.On("Add", config, mock.Anything).Return(config, nil)
+	mockRepo.On("GetSchemas", mock.Anything).Return([]model.Schema{}, nil)
+	mockRepo.On("Add", config, mock.MatchedBy(func(rev *model.ConfigurationRevision) bool {
+		return rev.Configuration == config && rev.Author == "alice" && !rev.Deleted
+	}), mock.Anything).Return(config, nil)
 
 	service := services.NewConfigurationService(mockRepo, NewTestTracer())
 
-	err := service.Add(config, context.Background())
+	err := service.Add(config, services.WithAuthor(context.Background(), "alice"))
 	assert.NoError(t, err)
 	//assert.Equal(t, config, "")
 
 	// Check if the configuration was added (using expectations)
-	mockRepo.AssertCalled(t, "Add", config, mock.Anything)
+	mockRepo.AssertCalled(t, "Add", config, mock.Anything, mock.Anything)
 	mockRepo.AssertExpectations(t)
+
+	assert.NotZero(t, config.Id)
+	assert.Equal(t, "alice", config.CreatedBy)
+	assert.False(t, config.CreatedAt.IsZero())
+
+	rev := mockRepo.Calls[1].Arguments.Get(1).(*model.ConfigurationRevision)
+	assert.Equal(t, "alice", rev.Author)
+	assert.False(t, rev.Deleted)
+	assert.Equal(t, config, rev.Configuration)
+}
+
+func TestConfigurationService_AddInvalidType(t *testing.T) {
+	config := &model.Configuration{
+		Name:       "testConfig",
+		Version:    model.Version{Major: 1, Minor: 0, Patch: 0},
+		Parameters: map[string]string{"port": "eighty"},
+		Types:      map[string]model.ParameterType{"port": model.ParamInt},
+	}
+
+	mockRepo := new(repositories.MockConfigRepository)
+	service := services.NewConfigurationService(mockRepo, NewTestTracer())
+
+	err := service.Add(config, context.Background())
+	assert.ErrorIs(t, err, model.ErrInvalid)
+	mockRepo.AssertNotCalled(t, "Add", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestConfiguration_TypedParameters(t *testing.T) {
+	var config model.Configuration
+	body := `{"name":"db","version":{"major":1,"minor":0,"patch":0},
+		"parameters":{"host":"localhost","port":5432,"ratio":0.5,"debug":true,"timeout":"1m30s","hosts":["a","b"],"pool":{"max":10},"legacy":"42"},
+		"types":{"timeout":"duration","legacy":"int","pool":"json"},"labels":{}}`
+	assert.NoError(t, json.Unmarshal([]byte(body), &config))
+
+	assert.Equal(t, "5432", config.Parameters["port"])
+	assert.Equal(t, "1m30s", config.Parameters["timeout"])
+	assert.Equal(t, model.ParamInt, config.TypeOf("port"))
+	assert.Equal(t, model.ParamFloat, config.TypeOf("ratio"))
+	assert.Equal(t, model.ParamList, config.TypeOf("hosts"))
+	assert.Equal(t, model.ParamJSON, config.TypeOf("pool"))
+	assert.Equal(t, model.ParamString, config.TypeOf("host"))
+
+	data, err := json.Marshal(config)
+	assert.NoError(t, err)
+	var rendered map[string]interface{}
+	assert.NoError(t, json.Unmarshal(data, &rendered))
+	params := rendered["parameters"].(map[string]interface{})
+	assert.Equal(t, float64(5432), params["port"])
+	assert.Equal(t, true, params["debug"])
+	assert.Equal(t, float64(42), params["legacy"])
+	assert.Equal(t, "1m30s", params["timeout"])
+	assert.Equal(t, []interface{}{"a", "b"}, params["hosts"])
+
+	var legacy model.Configuration
+	assert.NoError(t, json.Unmarshal([]byte(`{"name":"old","id":0,"version":{"major":1,"minor":0,"patch":0},"parameters":{"port":"5432"},"labels":null}`), &legacy))
+	assert.Equal(t, model.ParamString, legacy.TypeOf("port"))
+	assert.Empty(t, legacy.Types)
+
+	assert.Error(t, json.Unmarshal([]byte(`{"name":"bad","parameters":{"port":"x"},"types":{"port":"int"}}`), &config))
+}
+
+func TestConfiguration_NestedParameters(t *testing.T) {
+	var config model.Configuration
+	body := `{"name":"svc","parameters":{"db":{"host":"localhost","pool":{"max":10}},"log.level":"info"},"types":{"db.pool.max":"int"}}`
+	assert.NoError(t, json.Unmarshal([]byte(body), &config))
+	assert.Equal(t, map[string]string{"db.host": "localhost", "db.pool.max": "10", "log.level": "info"}, config.Parameters)
+
+	view, err := model.NestConfiguration(config)
+	assert.NoError(t, err)
+	data, err := json.Marshal(view)
+	assert.NoError(t, err)
+	var rendered map[string]interface{}
+	assert.NoError(t, json.Unmarshal(data, &rendered))
+	assert.Equal(t, map[string]interface{}{
+		"db":  map[string]interface{}{"host": "localhost", "pool": map[string]interface{}{"max": float64(10)}},
+		"log": map[string]interface{}{"level": "info"},
+	}, rendered["parameters"])
+
+	err = json.Unmarshal([]byte(`{"name":"svc","parameters":{"db":{"host":"a"},"db.host":"b"}}`), &config)
+	assert.ErrorContains(t, err, "db.host is set more than once")
+	err = json.Unmarshal([]byte(`{"name":"svc","parameters":{"db":{"pool":{"max":1}},"db.pool":"x"}}`), &config)
+	assert.ErrorContains(t, err, "db.pool is both a value and the parent of db.pool.max")
+
+	flat := model.Configuration{Name: "svc", Parameters: map[string]string{"db": "x", "db.host": "y"}}
+	_, err = model.NestConfiguration(flat)
+	assert.ErrorIs(t, err, model.ErrInvalid)
+}
+
+func TestConfiguration_UnmarshalStoredFields(t *testing.T) {
+	// Stored values may carry fields this version does not know, such as ones written by a newer release.
+	var config model.Configuration
+	body := `{"name":"svc","version":{"major":1},"parameters":{"port":8080},"types":{"port":"int"},"retired":true}`
+	assert.NoError(t, json.Unmarshal([]byte(body), &config))
+	assert.Equal(t, map[string]string{"port": "8080"}, config.Parameters)
 }
 
 func TestConfigurationService_Get(t *testing.T) {
@@ -54,10 +153,495 @@ func TestConfigurationService_Delete(t *testing.T) {
 	service := services.NewConfigurationService(mockRepo, NewTestTracer())
 
 	config := &model.Configuration{Name: "testConfig", Version: model.Version{Major: 1, Minor: 0, Patch: 0}}
-	mockRepo.On("Delete", config.Name, model.ToString(config.Version), mock.Anything).Return(nil)
+	mockRepo.On("FindSourceMembers", config.Name, mock.Anything).Return([]model.ConfigurationGroup{}, nil)
+	mockRepo.On("GetVersions", config.Name, mock.Anything).Return([]model.Configuration{*config}, nil)
+	mockRepo.On("TrashConfig", mock.MatchedBy(func(trashed *model.TrashedConfiguration) bool {
+		return trashed.Configuration.Name == config.Name && trashed.DeletedBy == "anonymous" && !trashed.DeletedAt.IsZero()
+	}), mock.MatchedBy(func(rev *model.ConfigurationRevision) bool {
+		return rev.Deleted && rev.Configuration == nil
+	}), mock.Anything).Return(nil)
 
 	err := service.Delete(*config, context.Background())
 	assert.NoError(t, err)
 
 	mockRepo.AssertExpectations(t)
 }
+
+func TestConfigurationService_DeleteReferenced(t *testing.T) {
+	v100 := model.Configuration{Name: "db", Version: model.Version{Major: 1, Minor: 0, Patch: 0}}
+	v110 := model.Configuration{Name: "db", Version: model.Version{Major: 1, Minor: 1, Patch: 0}}
+	v200 := model.Configuration{Name: "db", Version: model.Version{Major: 2, Minor: 0, Patch: 0}}
+	groups := []model.ConfigurationGroup{{Name: "backend", Version: model.Version{Major: 1}, Configurations: []model.Configuration{
+		{Name: "legacy", Source: &model.ConfigReference{Name: "db", Version: "1.0.0"}},
+		{Name: "database", Source: &model.ConfigReference{Name: "db", Version: "^1"}},
+		{Name: "next", Source: &model.ConfigReference{Name: "db", Version: "^2"}},
+	}}}
+
+	tests := map[string]struct {
+		config model.Configuration
+		inUse  bool
+	}{
+		"pinned member":                  {config: v100, inUse: true},
+		"floating member with fallbacks": {config: v110, inUse: false},
+		"floating member's only match":   {config: v200, inUse: true},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockRepo := new(repositories.MockConfigRepository)
+			service := services.NewConfigurationService(mockRepo, NewTestTracer())
+			mockRepo.On("FindSourceMembers", "db", mock.Anything).Return(groups, nil)
+			mockRepo.On("GetVersions", "db", mock.Anything).Return([]model.Configuration{v100, v110, v200}, nil)
+			mockRepo.On("TrashConfig", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+			err := service.Delete(tt.config, context.Background())
+			if tt.inUse {
+				assert.ErrorIs(t, err, model.ErrInUse)
+				assert.Contains(t, err.Error(), "backend@1.0.0")
+				mockRepo.AssertNotCalled(t, "TrashConfig", mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+			assert.NoError(t, err)
+			mockRepo.AssertCalled(t, "TrashConfig", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestConfigurationService_GetTrashed(t *testing.T) {
+	mockRepo := new(repositories.MockConfigRepository)
+	service := services.NewConfigurationService(mockRepo, NewTestTracer())
+
+	trashed := &model.TrashedConfiguration{
+		Configuration: model.Configuration{Name: "testConfig", Version: model.Version{Major: 1, Minor: 0, Patch: 0}},
+		DeletedAt:     time.Now().UTC(),
+		DeletedBy:     "alice",
+	}
+	mockRepo.On("GetById", "testConfig", "1.0.0", mock.Anything).Return((*model.Configuration)(nil), model.ErrNotFound)
+	mockRepo.On("GetTrashedConfig", "testConfig", "1.0.0", mock.Anything).Return(trashed, nil)
+
+	config, err := service.Get("testConfig", "1.0.0", context.Background())
+	assert.Nil(t, config)
+	assert.ErrorIs(t, err, model.ErrGone)
+	assert.Contains(t, err.Error(), "/trash/configs/testConfig/1.0.0/restore")
+}
+
+func TestConfigurationService_GetTrashedEscapesHint(t *testing.T) {
+	mockRepo := new(repositories.MockConfigRepository)
+	service := services.NewConfigurationService(mockRepo, NewTestTracer())
+
+	trashed := &model.TrashedConfiguration{
+		Configuration: model.Configuration{Name: "billing api", Version: model.Version{Major: 1, Minor: 0, Patch: 0}},
+		DeletedAt:     time.Now().UTC(),
+		DeletedBy:     "alice",
+	}
+	mockRepo.On("GetById", "billing api", "1.0.0", mock.Anything).Return((*model.Configuration)(nil), model.ErrNotFound)
+	mockRepo.On("GetTrashedConfig", "billing api", "1.0.0", mock.Anything).Return(trashed, nil)
+
+	_, err := service.Get("billing api", "1.0.0", context.Background())
+	assert.ErrorIs(t, err, model.ErrGone)
+	assert.Contains(t, err.Error(), "/trash/configs/billing%20api/1.0.0/restore")
+}
+
+func TestConfigurationService_Restore(t *testing.T) {
+	mockRepo := new(repositories.MockConfigRepository)
+	service := services.NewConfigurationService(mockRepo, NewTestTracer())
+
+	trashed := &model.TrashedConfiguration{
+		Configuration: model.Configuration{Name: "testConfig", Version: model.Version{Major: 1, Minor: 0, Patch: 0}, Parameters: map[string]string{"a": "1"}},
+		DeletedAt:     time.Now().UTC(),
+	}
+	mockRepo.On("GetTrashedConfig", "testConfig", "1.0.0", mock.Anything).Return(trashed, nil)
+	mockRepo.On("GetById", "testConfig", "1.0.0", mock.Anything).Return((*model.Configuration)(nil), model.ErrNotFound)
+	mockRepo.On("RestoreConfig", mock.MatchedBy(func(c *model.Configuration) bool {
+		return c.Parameters["a"] == "1"
+	}), mock.MatchedBy(func(rev *model.ConfigurationRevision) bool {
+		return !rev.Deleted && rev.Configuration != nil
+	}), mock.Anything).Return(nil)
+
+	config, err := service.Restore("testConfig", "1.0.0", context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "1", config.Parameters["a"])
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestConfigurationService_Clone(t *testing.T) {
+	mockRepo := new(repositories.MockConfigRepository)
+	mockRepo.On("GetSchemas", mock.Anything).Return([]model.Schema{}, nil)
+	service := services.NewConfigurationService(mockRepo, NewTestTracer())
+
+	source := &model.Configuration{
+		Name:       "testConfig",
+		Version:    model.Version{Major: 1, Minor: 0, Patch: 0},
+		Parameters: map[string]string{"db.host": "localhost", "db.port": "5432"},
+		Labels:     map[string]string{"env": "dev"},
+	}
+	req := model.CloneRequest{
+		Version: model.Version{Major: 1, Minor: 1, Patch: 0},
+		Patch: &model.Patch{
+			Parameters:       map[string]string{"db.host": "db.internal"},
+			RemoveParameters: []string{"db.port"},
+			Labels:           map[string]string{"env": "prod"},
+		},
+	}
+	expected := &model.Configuration{
+		Name:       "testConfig",
+		Version:    req.Version,
+		Parameters: map[string]string{"db.host": "db.internal"},
+		Labels:     map[string]string{"env": "prod"},
+	}
+
+	mockRepo.On("GetById", source.Name, "1.0.0", mock.Anything).Return(source, nil)
+	mockRepo.On("GetById", source.Name, "1.1.0", mock.Anything).Return((*model.Configuration)(nil), model.ErrNotFound)
+	mockRepo.On("Add", mock.MatchedBy(func(c *model.Configuration) bool {
+		return c.Id != 0 && assert.ObjectsAreEqual(expected.Parameters, c.Parameters) && assert.ObjectsAreEqual(expected.Labels, c.Labels)
+	}), mock.Anything, mock.Anything).Return(expected, nil)
+
+	clone, err := service.Clone(source.Name, "1.0.0", req, context.Background())
+	assert.NoError(t, err)
+	assert.NotZero(t, clone.Id)
+	clone.Id, clone.Metadata = 0, model.Metadata{}
+	assert.Equal(t, expected, clone)
+	assert.Equal(t, "localhost", source.Parameters["db.host"])
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestConfigurationService_CloneExistingTarget(t *testing.T) {
+	mockRepo := new(repositories.MockConfigRepository)
+	service := services.NewConfigurationService(mockRepo, NewTestTracer())
+
+	config := &model.Configuration{Name: "testConfig", Version: model.Version{Major: 1, Minor: 0, Patch: 0}}
+	mockRepo.On("GetById", config.Name, "1.0.0", mock.Anything).Return(config, nil)
+
+	_, err := service.Clone(config.Name, "1.0.0", model.CloneRequest{Version: config.Version}, context.Background())
+	assert.ErrorIs(t, err, model.ErrAlreadyExists)
+
+	mockRepo.AssertNotCalled(t, "Add", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestConfigurationService_GetAsOf(t *testing.T) {
+	mockRepo := new(repositories.MockConfigRepository)
+	service := services.NewConfigurationService(mockRepo, NewTestTracer())
+
+	written := time.Date(2024, 5, 1, 3, 0, 0, 0, time.UTC)
+	first := &model.Configuration{Name: "testConfig", Parameters: map[string]string{"a": "1"}}
+	second := &model.Configuration{Name: "testConfig", Parameters: map[string]string{"a": "2"}}
+	revisions := []model.ConfigurationRevision{
+		{Number: 1, Timestamp: written, Configuration: first},
+		{Number: 2, Timestamp: written.Add(time.Hour), Configuration: second},
+		{Number: 3, Timestamp: written.Add(2 * time.Hour), Deleted: true},
+	}
+	mockRepo.On("GetConfigRevisions", "testConfig", "1.0.0", mock.Anything).Return(revisions, nil)
+
+	config, err := service.GetAsOf("testConfig", "1.0.0", written.Add(12*time.Minute), context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, first, config)
+
+	config, err = service.GetAsOf("testConfig", "1.0.0", written.Add(time.Hour), context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, second, config)
+
+	_, err = service.GetAsOf("testConfig", "1.0.0", written.Add(3*time.Hour), context.Background())
+	assert.ErrorIs(t, err, model.ErrNotFound)
+
+	_, err = service.GetAsOf("testConfig", "1.0.0", written.Add(-time.Minute), context.Background())
+	assert.ErrorIs(t, err, model.ErrNotFound)
+}
+
+func TestConfigurationService_GetEffective(t *testing.T) {
+	mockRepo := new(repositories.MockConfigRepository)
+	service := services.NewConfigurationService(mockRepo, NewTestTracer())
+
+	child := &model.Configuration{
+		Name:       "orders",
+		Version:    model.Version{Major: 1, Minor: 0, Patch: 0},
+		Parameters: map[string]string{"log.level": "debug", "orders.db": "orders"},
+		Extends:    &model.ConfigReference{Name: "service-base", Version: "^1.1"},
+	}
+	bases := []model.Configuration{
+		{Name: "service-base", Version: model.Version{Major: 1, Minor: 0, Patch: 0}, Parameters: map[string]string{"log.level": "warn"}},
+		{
+			Name:       "service-base",
+			Version:    model.Version{Major: 1, Minor: 2, Patch: 0},
+			Parameters: map[string]string{"log.level": "info", "tracing.rate": "0.1"},
+			Types:      map[string]model.ParameterType{"tracing.rate": model.ParamFloat},
+			Extends:    &model.ConfigReference{Name: "root", Version: "1.0.0"},
+		},
+		{Name: "service-base", Version: model.Version{Major: 2, Minor: 0, Patch: 0}, Parameters: map[string]string{"log.level": "error"}},
+	}
+	root := &model.Configuration{Name: "root", Version: model.Version{Major: 1, Minor: 0, Patch: 0}, Parameters: map[string]string{"region": "eu", "tracing.rate": "1"}}
+
+	mockRepo.On("GetById", "orders", "1.0.0", mock.Anything).Return(child, nil)
+	mockRepo.On("GetVersions", "service-base", mock.Anything).Return(bases, nil)
+	mockRepo.On("GetById", "root", "1.0.0", mock.Anything).Return(root, nil)
+
+	effective, err := service.GetEffective("orders", "1.0.0", context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"log.level":    "debug",
+		"orders.db":    "orders",
+		"tracing.rate": "0.1",
+		"region":       "eu",
+	}, effective.Parameters)
+	assert.Equal(t, model.ParamFloat, effective.TypeOf("tracing.rate"))
+	assert.Equal(t, model.Version{Major: 1, Minor: 2, Patch: 0}, *effective.Extends.Resolved)
+	assert.Nil(t, child.Extends.Resolved)
+}
+
+func TestConfigurationService_AddInheritanceCycle(t *testing.T) {
+	mockRepo := new(repositories.MockConfigRepository)
+	service := services.NewConfigurationService(mockRepo, NewTestTracer())
+
+	config := &model.Configuration{
+		Name:       "base",
+		Version:    model.Version{Major: 2, Minor: 0, Patch: 0},
+		Parameters: map[string]string{"a": "1"},
+		Extends:    &model.ConfigReference{Name: "base", Version: ">=1.0.0"},
+	}
+	mockRepo.On("GetVersions", "base", mock.Anything).Return([]model.Configuration{
+		{Name: "base", Version: model.Version{Major: 1, Minor: 0, Patch: 0}},
+	}, nil)
+
+	err := service.Add(config, context.Background())
+	assert.ErrorIs(t, err, model.ErrInvalid)
+	assert.ErrorContains(t, err, "inheritance cycle base@2.0.0 -> base@2.0.0")
+	mockRepo.AssertNotCalled(t, "Add", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestConfigurationService_GetEffectiveInterpolation(t *testing.T) {
+	mockRepo := new(repositories.MockConfigRepository)
+	service := services.NewConfigurationService(mockRepo, NewTestTracer())
+
+	config := &model.Configuration{
+		Name:    "orders",
+		Version: model.Version{Major: 1, Minor: 0, Patch: 0},
+		Parameters: map[string]string{
+			"db.host":  "${config:database@^2#host}",
+			"db.url":   "postgres://${self:db.host}:${self:db.port}/orders",
+			"db.port":  "${config:database@^2#port}",
+			"template": "$${self:db.host}",
+		},
+		Types: map[string]model.ParameterType{"db.port": model.ParamInt},
+	}
+	database := []model.Configuration{
+		{Name: "database", Version: model.Version{Major: 1, Minor: 0, Patch: 0}, Parameters: map[string]string{"host": "old", "port": "1"}},
+		{Name: "database", Version: model.Version{Major: 2, Minor: 1, Patch: 0}, Parameters: map[string]string{"host": "db.internal", "port": "${self:default.port}", "default.port": "05432"}},
+	}
+
+	mockRepo.On("GetById", "orders", "1.0.0", mock.Anything).Return(config, nil)
+	mockRepo.On("GetVersions", "database", mock.Anything).Return(database, nil)
+	mockRepo.On("GetSchemas", mock.Anything).Return([]model.Schema{}, nil)
+
+	effective, err := service.GetEffective("orders", "1.0.0", context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"db.host":  "db.internal",
+		"db.url":   "postgres://db.internal:5432/orders",
+		"db.port":  "5432",
+		"template": "${self:db.host}",
+	}, effective.Parameters)
+	assert.Equal(t, "${config:database@^2#host}", config.Parameters["db.host"])
+}
+
+func TestConfigurationService_GetEffectiveChecksResolvedValues(t *testing.T) {
+	mockRepo := new(repositories.MockConfigRepository)
+	service := services.NewConfigurationService(mockRepo, NewTestTracer())
+
+	config := &model.Configuration{
+		Name:       "orders",
+		Version:    model.Version{Major: 1, Minor: 0, Patch: 0},
+		Parameters: map[string]string{"db.port": "${self:default.port}", "default.port": "http"},
+	}
+	schema := model.Schema{Name: "orders", ConfigName: "orders", Properties: map[string]model.ParameterSchema{"db.port": {Type: model.ParamInt}}}
+	mockRepo.On("GetById", "orders", "1.0.0", mock.Anything).Return(config, nil)
+	mockRepo.On("GetSchemas", mock.Anything).Return([]model.Schema{schema}, nil)
+
+	_, err := service.GetEffective("orders", "1.0.0", context.Background())
+	var validation *model.ValidationError
+	assert.ErrorAs(t, err, &validation)
+	assert.Equal(t, "db.port", validation.Violations[0].Parameter)
+}
+
+func TestConfigurationService_GetEffectiveInterpolationErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		parameters map[string]string
+		message    string
+	}{
+		{
+			name:       "cycle",
+			parameters: map[string]string{"a": "${self:b}", "b": "x-${self:a}"},
+			message:    "interpolation cycle config:orders@1.0.0#a -> config:orders@1.0.0#b -> config:orders@1.0.0#a",
+		},
+		{
+			name:       "missing key",
+			parameters: map[string]string{"a": "${self:nope}"},
+			message:    "config:orders@1.0.0 has no parameter nope",
+		},
+		{
+			name:       "missing config",
+			parameters: map[string]string{"a": "${config:missing@1.0.0#key}"},
+			message:    "config missing 1.0.0 does not exist",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(repositories.MockConfigRepository)
+			service := services.NewConfigurationService(mockRepo, NewTestTracer())
+
+			config := &model.Configuration{Name: "orders", Version: model.Version{Major: 1, Minor: 0, Patch: 0}, Parameters: tt.parameters}
+			mockRepo.On("GetById", "orders", "1.0.0", mock.Anything).Return(config, nil)
+			mockRepo.On("GetById", "missing", "1.0.0", mock.Anything).Return((*model.Configuration)(nil), model.ErrNotFound)
+
+			_, err := service.GetEffective("orders", "1.0.0", context.Background())
+			assert.ErrorIs(t, err, model.ErrInvalid)
+			assert.ErrorContains(t, err, tt.message)
+		})
+	}
+}
+
+func TestConfigurationService_AddInvalidName(t *testing.T) {
+	mockRepo := new(repositories.MockConfigRepository)
+	service := services.NewConfigurationService(mockRepo, NewTestTracer())
+
+	for _, name := range []string{"", "team//db", "/team/db", "team/db/", "team/../db"} {
+		config := &model.Configuration{Name: name, Version: model.Version{Major: 1, Minor: 0, Patch: 0}}
+		assert.ErrorIs(t, service.Add(config, context.Background()), model.ErrInvalid, name)
+	}
+	mockRepo.AssertNotCalled(t, "Add", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestConfigurationService_GetByObjectId(t *testing.T) {
+	mockRepo := new(repositories.MockConfigRepository)
+	service := services.NewConfigurationService(mockRepo, NewTestTracer())
+
+	config := &model.Configuration{Name: "team/payments/db", Id: 99, Version: model.Version{Major: 1, Minor: 0, Patch: 0}}
+	mockRepo.On("GetObjectRef", int64(99), mock.Anything).Return(&model.ObjectRef{Kind: model.KindConfig, Name: config.Name, Version: config.Version}, nil)
+	mockRepo.On("GetObjectRef", int64(100), mock.Anything).Return(&model.ObjectRef{Kind: model.KindGroup, Name: "platform", Version: config.Version}, nil)
+	mockRepo.On("GetById", config.Name, "1.0.0", mock.Anything).Return(config, nil)
+
+	found, err := service.GetByObjectId(99, context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, config, found)
+
+	_, err = service.GetByObjectId(100, context.Background())
+	assert.ErrorIs(t, err, model.ErrNotFound)
+}
+
+func TestParseSelector(t *testing.T) {
+	selector, err := model.ParseSelector("env=prod, region in (eu, us),tier!=cache,version notin (1,2),canary,!deprecated")
+	assert.NoError(t, err)
+	assert.Equal(t, "env=prod,region in (eu,us),tier!=cache,version notin (1,2),canary,!deprecated", selector.String())
+
+	assert.True(t, selector.Matches(map[string]string{"env": "prod", "region": "eu", "canary": ""}))
+	assert.False(t, selector.Matches(map[string]string{"env": "prod", "region": "asia", "canary": ""}))
+	assert.False(t, selector.Matches(map[string]string{"env": "prod", "region": "eu", "canary": "", "tier": "cache"}))
+	assert.False(t, selector.Matches(map[string]string{"env": "prod", "region": "us", "canary": "", "deprecated": "true"}))
+	assert.False(t, selector.Matches(map[string]string{"env": "prod", "region": "us"}))
+
+	for _, invalid := range []string{"env=prod,", "region in (eu", "region in ((eu))", "env=pr;od", "=prod"} {
+		_, err := model.ParseSelector(invalid)
+		assert.ErrorIs(t, err, model.ErrInvalid, invalid)
+	}
+}
+
+func TestConfigurationService_List(t *testing.T) {
+	v1, v2 := model.Version{Major: 1}, model.Version{Major: 2}
+	refs := []model.ObjectRef{
+		{Kind: model.KindConfig, Name: "team/db", Version: v2},
+		{Kind: model.KindConfig, Name: "team/db", Version: v1},
+		{Kind: model.KindConfig, Name: "team/cache", Version: v1},
+		{Kind: model.KindConfig, Name: "team/payments/api", Version: v1},
+	}
+	mockRepo := new(repositories.MockConfigRepository)
+	mockRepo.On("ListConfigRefs", "team/", mock.Anything).Return(refs, nil)
+	for _, ref := range refs {
+		config := &model.Configuration{Name: ref.Name, Version: ref.Version, Labels: model.Labels{"env": "prod"}}
+		if ref.Name == "team/cache" {
+			config.Labels = model.Labels{"env": "dev"}
+		}
+		mockRepo.On("GetById", ref.Name, model.ToString(ref.Version), mock.Anything).Return(config, nil)
+	}
+
+	service := services.NewConfigurationService(mockRepo, NewTestTracer())
+	opts := model.ListOptions{Prefix: "team/", Delimiter: "/", Sort: model.SortByName, Order: model.OrderAsc, Limit: 2}
+
+	page, err := service.List(opts, context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"team/payments/"}, page.Folders)
+	assert.Len(t, page.Items, 2)
+	assert.Equal(t, "team/cache", page.Items[0].Name)
+	assert.Equal(t, v1, page.Items[1].Version)
+	assert.NotEmpty(t, page.NextCursor)
+
+	opts.Cursor = page.NextCursor
+	page, err = service.List(opts, context.Background())
+	assert.NoError(t, err)
+	assert.Nil(t, page.Folders)
+	assert.Len(t, page.Items, 1)
+	assert.Equal(t, v2, page.Items[0].Version)
+	assert.Empty(t, page.NextCursor)
+
+	// A negative selector cannot use the label index, every entry is read and checked.
+	opts = model.ListOptions{Prefix: "team/", Selector: model.Selector{{Key: "env", Operator: model.SelectNotEquals, Values: []string{"dev"}}}, Sort: model.SortByVersion, Order: model.OrderDesc, Limit: 10}
+	page, err = service.List(opts, context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, page.Items, 3)
+	assert.Equal(t, v2, page.Items[0].Version)
+	assert.Equal(t, "team/payments/api", page.Items[1].Name)
+
+	opts.Sort = model.SortByName
+	opts.Cursor = model.PageCursor{Sort: model.SortByVersion, Order: model.OrderDesc}.Encode()
+	_, err = service.List(opts, context.Background())
+	assert.ErrorIs(t, err, model.ErrInvalid)
+}
+
+func TestConfigurationService_ListByUpdatedAt(t *testing.T) {
+	v1, v2 := model.Version{Major: 1}, model.Version{Major: 2}
+	now := time.Now().UTC()
+	mockRepo := new(repositories.MockConfigRepository)
+	mockRepo.On("ListConfigRefs", "", mock.Anything).Return([]model.ObjectRef{
+		{Kind: model.KindConfig, Name: "db", Version: v1},
+		{Kind: model.KindConfig, Name: "db", Version: v2},
+		{Kind: model.KindConfig, Name: "legacy", Version: v1},
+	}, nil)
+	mockRepo.On("ListIndexedObjects", model.KindConfig, "", mock.Anything).Return([]model.IndexedObject{
+		{ObjectRef: model.ObjectRef{Kind: model.KindConfig, Name: "db", Version: v1}, UpdatedAt: now.Add(-time.Hour)},
+		{ObjectRef: model.ObjectRef{Kind: model.KindConfig, Name: "db", Version: v2}, UpdatedAt: now.Add(-3 * time.Hour)},
+		{ObjectRef: model.ObjectRef{Kind: model.KindConfig, Name: "trashed", Version: v1}, UpdatedAt: now},
+	}, nil)
+	// Configs stored before the ID index held update times are read for theirs.
+	mockRepo.On("GetById", "legacy", "1.0.0", mock.Anything).Return(&model.Configuration{Name: "legacy", Version: v1, Metadata: model.Metadata{UpdatedAt: now.Add(-2 * time.Hour)}}, nil).Once()
+	mockRepo.On("GetById", "db", "1.0.0", mock.Anything).Return(&model.Configuration{Name: "db", Version: v1}, nil)
+	service := services.NewConfigurationService(mockRepo, NewTestTracer())
+
+	opts := model.ListOptions{Sort: model.SortByUpdatedAt, Order: model.OrderDesc, Limit: 2}
+	page, err := service.List(opts, context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, page.Items, 2)
+	assert.Equal(t, v1, page.Items[0].Version)
+	assert.Equal(t, "legacy", page.Items[1].Name)
+	assert.NotEmpty(t, page.NextCursor)
+	mockRepo.AssertNotCalled(t, "GetById", "db", "2.0.0", mock.Anything)
+}
+
+func TestConfigurationService_ListFolderPrefix(t *testing.T) {
+	v1 := model.Version{Major: 1}
+	mockRepo := new(repositories.MockConfigRepository)
+	mockRepo.On("ListConfigRefs", "team/", mock.Anything).Return([]model.ObjectRef{
+		{Kind: model.KindConfig, Name: "team/payments/db", Version: v1},
+		{Kind: model.KindConfig, Name: "team/shared", Version: v1},
+	}, nil)
+	mockRepo.On("GetById", "team/shared", "1.0.0", mock.Anything).Return(&model.Configuration{Name: "team/shared", Version: v1}, nil)
+	service := services.NewConfigurationService(mockRepo, NewTestTracer())
+
+	for _, prefix := range []string{"team", "/team", "team/"} {
+		opts := model.ListOptions{Prefix: prefix, Delimiter: "/", Sort: model.SortByName, Order: model.OrderAsc, Limit: 10}
+		page, err := service.List(opts, context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"team/payments/"}, page.Folders, prefix)
+		assert.Len(t, page.Items, 1)
+		assert.Equal(t, "team/shared", page.Items[0].Name)
+	}
+}
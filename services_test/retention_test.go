@@ -0,0 +1,149 @@
+package services_test
+
+import (
+	"ars_projekat/model"
+	"ars_projekat/repositories"
+	"ars_projekat/services"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newTestRetentionService(repo *repositories.MockConfigRepository) services.RetentionService {
+	tracer := NewTestTracer()
+	return services.NewRetentionService(
+		repo,
+		services.NewConfigurationService(repo, tracer),
+		services.NewConfigurationGroupService(repo, tracer),
+		services.NewMetricsService(),
+		tracer,
+	)
+}
+
+func TestRetentionService_DryRun(t *testing.T) {
+	mockRepo := new(repositories.MockConfigRepository)
+	service := newTestRetentionService(mockRepo)
+
+	policy := model.RetentionPolicy{Name: "svc", Kind: model.RetentionConfigs, NamePrefix: "svc", KeepLast: 2, KeepYoungerThan: "30d", KeepReferenced: true}
+	configs := []model.Configuration{
+		{Name: "svc", Version: model.Version{Major: 1, Minor: 0, Patch: 0}},
+		{Name: "svc", Version: model.Version{Major: 1, Minor: 1, Patch: 0}},
+		{Name: "svc", Version: model.Version{Major: 1, Minor: 2, Patch: 0}},
+		{Name: "svc", Version: model.Version{Major: 2, Minor: 0, Patch: 0}},
+		{Name: "other", Version: model.Version{Major: 1, Minor: 0, Patch: 0}},
+	}
+	groups := []model.ConfigurationGroup{
+		{Name: "group", Version: model.Version{Major: 1}, Configurations: []model.Configuration{configs[1]}},
+	}
+	old := []model.ConfigurationRevision{{Number: 1, Timestamp: time.Now().Add(-100 * 24 * time.Hour)}}
+
+	mockRepo.On("GetRetentionPolicies", mock.Anything).Return([]model.RetentionPolicy{policy}, nil)
+	mockRepo.On("GetAll", mock.Anything).Return(configs, nil)
+	mockRepo.On("GetAllGroups", mock.Anything).Return(groups, nil)
+	mockRepo.On("GetConfigRevisions", "svc", "1.0.0", mock.Anything).Return(old, nil)
+
+	report, err := service.Run(true, context.Background())
+	assert.NoError(t, err)
+	assert.True(t, report.DryRun)
+	assert.Equal(t, 6, report.Checked)
+	assert.Equal(t, []model.PrunedVersion{{Kind: model.RetentionConfigs, Name: "svc", Version: configs[0].Version, Policy: "svc"}}, report.Pruned)
+
+	mockRepo.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "TrashConfig", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestRetentionService_KeepsYoungVersions(t *testing.T) {
+	mockRepo := new(repositories.MockConfigRepository)
+	service := newTestRetentionService(mockRepo)
+
+	policy := model.RetentionPolicy{Name: "all", KeepLast: 1, KeepYoungerThan: "720h"}
+	configs := []model.Configuration{
+		{Name: "svc", Version: model.Version{Major: 1, Minor: 0, Patch: 0}},
+		{Name: "svc", Version: model.Version{Major: 1, Minor: 1, Patch: 0}},
+	}
+	young := []model.ConfigurationRevision{{Number: 1, Timestamp: time.Now().Add(-time.Hour)}}
+
+	mockRepo.On("GetRetentionPolicies", mock.Anything).Return([]model.RetentionPolicy{policy}, nil)
+	mockRepo.On("GetAll", mock.Anything).Return(configs, nil)
+	mockRepo.On("GetAllGroups", mock.Anything).Return([]model.ConfigurationGroup{}, nil)
+	mockRepo.On("GetConfigRevisions", "svc", "1.0.0", mock.Anything).Return(young, nil)
+
+	report, err := service.Run(false, context.Background())
+	assert.NoError(t, err)
+	assert.Empty(t, report.Pruned)
+
+	mockRepo.AssertNotCalled(t, "TrashConfig", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestRetentionService_KeepsSourceReferences(t *testing.T) {
+	configs := []model.Configuration{
+		{Name: "db", Version: model.Version{Major: 1, Minor: 0, Patch: 0}},
+		{Name: "db", Version: model.Version{Major: 1, Minor: 1, Patch: 0}},
+		{Name: "db", Version: model.Version{Major: 1, Minor: 2, Patch: 0}},
+		{Name: "db", Version: model.Version{Major: 2, Minor: 0, Patch: 0}},
+	}
+	// The members are not named after the config they read, the pinned one needs 1.0.0 and the floating one reads 1.2.0.
+	groups := []model.ConfigurationGroup{{Name: "backend", Version: model.Version{Major: 1}, Configurations: []model.Configuration{
+		{Name: "legacy", Source: &model.ConfigReference{Name: "db", Version: "1.0.0"}},
+		{Name: "database", Source: &model.ConfigReference{Name: "db", Version: "^1"}},
+	}}}
+
+	tests := map[string]struct {
+		keepReferenced bool
+		pruned         []model.Configuration
+	}{
+		// The version a member cannot be read without is never pruned.
+		"without keepReferenced": {keepReferenced: false, pruned: []model.Configuration{configs[2], configs[1]}},
+		"with keepReferenced":    {keepReferenced: true, pruned: []model.Configuration{configs[1]}},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockRepo := new(repositories.MockConfigRepository)
+			service := newTestRetentionService(mockRepo)
+
+			policy := model.RetentionPolicy{Name: "db", Kind: model.RetentionConfigs, NamePrefix: "db", KeepLast: 1, KeepReferenced: tt.keepReferenced}
+			mockRepo.On("GetRetentionPolicies", mock.Anything).Return([]model.RetentionPolicy{policy}, nil)
+			mockRepo.On("GetAll", mock.Anything).Return(configs, nil)
+			mockRepo.On("GetAllGroups", mock.Anything).Return(groups, nil)
+
+			report, err := service.Run(true, context.Background())
+			assert.NoError(t, err)
+			var pruned []model.Configuration
+			for _, p := range report.Pruned {
+				pruned = append(pruned, model.Configuration{Name: p.Name, Version: p.Version})
+			}
+			assert.ElementsMatch(t, tt.pruned, pruned)
+		})
+	}
+}
+
+func TestRetentionService_PrunesWithoutRescan(t *testing.T) {
+	mockRepo := new(repositories.MockConfigRepository)
+	service := newTestRetentionService(mockRepo)
+
+	created := time.Now().Add(-100 * 24 * time.Hour)
+	policy := model.RetentionPolicy{Name: "svc", Kind: model.RetentionConfigs, NamePrefix: "svc", KeepLast: 1, KeepYoungerThan: "720h"}
+	configs := []model.Configuration{
+		{Name: "svc", Version: model.Version{Major: 1, Minor: 0, Patch: 0}, Parameters: map[string]string{"port": "8080"}, Metadata: model.Metadata{CreatedAt: created}},
+		{Name: "svc", Version: model.Version{Major: 1, Minor: 1, Patch: 0}, Metadata: model.Metadata{CreatedAt: created}},
+	}
+
+	mockRepo.On("GetRetentionPolicies", mock.Anything).Return([]model.RetentionPolicy{policy}, nil)
+	mockRepo.On("GetAll", mock.Anything).Return(configs, nil)
+	mockRepo.On("GetAllGroups", mock.Anything).Return([]model.ConfigurationGroup{}, nil)
+	mockRepo.On("TrashConfig", mock.MatchedBy(func(trashed *model.TrashedConfiguration) bool {
+		return trashed.DeletedBy == "retention" && trashed.Configuration.Parameters["port"] == "8080"
+	}), mock.Anything, mock.Anything).Return(nil)
+
+	report, err := service.Run(false, context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, report.Pruned, 1)
+
+	mockRepo.AssertExpectations(t)
+	// The age comes from the metadata and the references from the run, neither is looked up again.
+	mockRepo.AssertNotCalled(t, "GetConfigRevisions", mock.Anything, mock.Anything, mock.Anything)
+	mockRepo.AssertNotCalled(t, "FindSourceMembers", mock.Anything, mock.Anything)
+}
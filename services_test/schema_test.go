@@ -0,0 +1,85 @@
+package services_test
+
+import (
+	"ars_projekat/model"
+	"ars_projekat/repositories"
+	"ars_projekat/services"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestSchemaService_RegisterInvalid(t *testing.T) {
+	mockRepo := new(repositories.MockConfigRepository)
+	service := services.NewSchemaService(mockRepo, NewTestTracer())
+
+	schema := &model.Schema{
+		Name:       "db",
+		ConfigName: "db",
+		Properties: map[string]model.ParameterSchema{"db.host": {Pattern: "("}},
+	}
+
+	err := service.Register(schema, context.Background())
+	assert.ErrorIs(t, err, model.ErrInvalid)
+	mockRepo.AssertNotCalled(t, "AddSchema", mock.Anything, mock.Anything)
+}
+
+func TestSchemaService_DeleteInvalidName(t *testing.T) {
+	mockRepo := new(repositories.MockConfigRepository)
+	service := services.NewSchemaService(mockRepo, NewTestTracer())
+
+	err := service.Delete("db/..", context.Background())
+	assert.ErrorIs(t, err, model.ErrInvalid)
+	mockRepo.AssertNotCalled(t, "GetSchemaVersions", mock.Anything, mock.Anything)
+	mockRepo.AssertNotCalled(t, "DeleteSchema", mock.Anything, mock.Anything)
+}
+
+func TestConfigurationService_AddSchemaViolations(t *testing.T) {
+	minPort, maxPort := 1.0, 65535.0
+	schemas := []model.Schema{
+		{
+			Name:       "db",
+			Version:    2,
+			ConfigName: "db",
+			Required:   []string{"db.host", "db.port"},
+			Properties: map[string]model.ParameterSchema{
+				"db.port": {Type: model.ParamInt, Minimum: &minPort, Maximum: &maxPort},
+				"db.mode": {Enum: []string{"primary", "replica"}},
+				"db.user": {Pattern: "^[a-z]+$"},
+			},
+		},
+		{Name: "other", Version: 1, ConfigName: "cache", Required: []string{"cache.size"}},
+	}
+	config := &model.Configuration{
+		Name:       "db",
+		Version:    model.Version{Major: 1, Minor: 0, Patch: 0},
+		Parameters: map[string]string{"db.port": "70000", "db.mode": "standby", "db.user": "Admin"},
+	}
+
+	mockRepo := new(repositories.MockConfigRepository)
+	mockRepo.On("GetSchemas", mock.Anything).Return(schemas, nil)
+	service := services.NewConfigurationService(mockRepo, NewTestTracer())
+
+	err := service.Add(config, context.Background())
+	assert.ErrorIs(t, err, model.ErrInvalid)
+
+	var validation *model.ValidationError
+	assert.True(t, errors.As(err, &validation))
+
+	rules := make(map[string]string)
+	for _, v := range validation.Violations {
+		assert.Equal(t, "db", v.Schema)
+		assert.Equal(t, int64(2), v.Version)
+		rules[v.Parameter] = v.Rule
+	}
+	assert.Equal(t, map[string]string{
+		"db.host": "required",
+		"db.port": "maximum",
+		"db.mode": "enum",
+		"db.user": "pattern",
+	}, rules)
+	mockRepo.AssertNotCalled(t, "Add", mock.Anything, mock.Anything, mock.Anything)
+}
@@ -0,0 +1,82 @@
+package services_test
+
+import (
+	"ars_projekat/model"
+	"ars_projekat/repositories"
+	"ars_projekat/services"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestSearchService_Search(t *testing.T) {
+	v1 := model.Version{Major: 1}
+	configs := []model.Configuration{
+		{Name: "db", Version: v1, Parameters: map[string]string{"port": "5432", "host": "db.internal"}, Labels: model.Labels{"env": "prod"}},
+		{Name: "cache", Version: v1, Parameters: map[string]string{"db_port": "6379"}, Metadata: model.Metadata{Owner: "platform"}},
+	}
+	groups := []model.ConfigurationGroup{
+		{Name: "backend", Version: v1, Configurations: []model.Configuration{configs[0]}},
+	}
+
+	mockRepo := new(repositories.MockConfigRepository)
+	mockRepo.On("WatchConfigs", uint64(0), time.Duration(0), mock.Anything).Return(&model.ConfigChanges{
+		Changed: configs,
+		Present: []model.ObjectRef{{Kind: model.KindConfig, Name: "db", Version: v1}, {Kind: model.KindConfig, Name: "cache", Version: v1}},
+	}, uint64(7), nil)
+	mockRepo.On("WatchGroups", uint64(0), time.Duration(0), mock.Anything).Return(&model.GroupChanges{
+		Changed: groups,
+		Present: []model.ObjectRef{{Kind: model.KindGroup, Name: "backend", Version: v1}},
+	}, uint64(9), nil)
+
+	service := services.NewSearchService(mockRepo, NewTestTracer())
+	assert.NoError(t, service.Refresh(context.Background()))
+
+	result, err := service.Search(model.SearchQuery{Text: "DB", Mode: model.SearchSubstring, Limit: 10}, context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 3, result.Total)
+	// The config named db outranks the group holding it and the config with a db_port key.
+	assert.Equal(t, model.KindConfig, result.Hits[0].Kind)
+	assert.Equal(t, "db", result.Hits[0].Name)
+	assert.Equal(t, model.FieldName, result.Hits[0].Matches[0].Field)
+	assert.Equal(t, "<em>db</em>", result.Hits[0].Matches[0].Highlight)
+	assert.Equal(t, "backend", result.Hits[1].Name)
+	assert.Equal(t, "db", result.Hits[1].Matches[0].Member)
+	assert.Equal(t, "cache", result.Hits[2].Name)
+	assert.Equal(t, "<em>db</em>_port", result.Hits[2].Matches[0].Highlight)
+
+	result, err = service.Search(model.SearchQuery{Text: "env=prod", Mode: model.SearchExact, Kind: model.KindConfig, Limit: 10}, context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.Total)
+	assert.Equal(t, model.FieldLabel, result.Hits[0].Matches[0].Field)
+
+	result, err = service.Search(model.SearchQuery{Text: "plat", Mode: model.SearchPrefix, Fields: []string{model.FieldMetadata}, Limit: 10}, context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.Total)
+	assert.Equal(t, "cache", result.Hits[0].Name)
+
+	_, err = service.Search(model.SearchQuery{Text: "db", Mode: "fuzzy", Limit: 10}, context.Background())
+	assert.ErrorIs(t, err, model.ErrInvalid)
+}
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		value string
+		text  string
+		want  string
+	}{
+		{value: "Database Host", text: "host", want: "Database <em>Host</em>"},
+		{value: "Ⱥa", text: "a", want: "Ⱥ<em>a</em>"},
+		{value: "ⱥⱥ", text: "Ⱥ", want: "<em>ⱥ</em>ⱥ"},
+		{value: "KELVIN", text: "K", want: "<em>K</em>ELVIN"},
+		{value: "db", text: "cache", want: "db"},
+		{value: "db", text: "", want: "db"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, model.Highlight(tt.value, tt.text), tt.value+"/"+tt.text)
+	}
+}